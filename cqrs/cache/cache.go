@@ -0,0 +1,137 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"runtime/debug"
+	"time"
+
+	"github.com/go-leo/design-pattern/cqrs"
+	"github.com/go-leo/design-pattern/decorator"
+	"github.com/go-leo/design-pattern/event"
+)
+
+// KeyFunc derives the cache key from the arguments of query.
+type KeyFunc func(ctx context.Context, args any) (string, error)
+
+// DefaultKey is the default KeyFunc, the key is the type name and the JSON encoding of args,
+// e.g. `github.com/foo/user.findUserQuery:{"ID":1}`, the type name is event.TypeName, so it is unique across packages.
+func DefaultKey(_ context.Context, args any) (string, error) {
+	data, err := json.Marshal(args)
+	if err != nil {
+		return "", err
+	}
+	return event.TypeName(reflect.TypeOf(args)) + ":" + string(data), nil
+}
+
+type option struct {
+	TTL time.Duration
+	Key KeyFunc
+}
+
+func newOption(opts ...Option) *option {
+	o := &option{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.Key == nil {
+		o.Key = DefaultKey
+	}
+	return o
+}
+
+type Option func(*option)
+
+// TTL sets the time to live of the cached results, default is no expiration.
+func TTL(ttl time.Duration) Option {
+	return func(o *option) {
+		o.TTL = ttl
+	}
+}
+
+// Key sets the KeyFunc, default is DefaultKey.
+// The Invalidator of the same cached query must use the same KeyFunc.
+func Key(key KeyFunc) Option {
+	return func(o *option) {
+		o.Key = key
+	}
+}
+
+// Cache is a cache of query results in a Store. The cached queries and the Invalidators of a Cache coordinate
+// their in-flight loads, so a Cache must be shared by the queries and the Invalidators that use the same keys.
+type Cache struct {
+	store   Store
+	flights *flights
+}
+
+// New returns a Cache that stores the results in store.
+func New(store Store) *Cache {
+	return &Cache{store: store, flights: newFlights()}
+}
+
+// Query returns a Decorator that caches the results of QueryHandler in cache.
+// The concurrent identical queries that miss the cache share one call of QueryHandler, the call is not canceled
+// by the cancellation of the queries, and a query that is canceled returns without waiting for it.
+// A result loaded while an Invalidator of the same cache deletes its key is not cached, as it may be stale.
+// Errors are not cached, and the cache is best effort, a failure of store is treated as a miss.
+// A shared result that is not a Result, e.g. the key is used by a query of another Result, returns cqrs.ErrResultType.
+func Query[Args any, Result any](cache *Cache, opts ...Option) decorator.Decorator[cqrs.QueryHandler[Args, Result]] {
+	o := newOption(opts...)
+	return decorator.Func[cqrs.QueryHandler[Args, Result]](func(handler cqrs.QueryHandler[Args, Result]) cqrs.QueryHandler[Args, Result] {
+		return &cachedQuery[Args, Result]{handler: handler, store: cache.store, options: o, flights: cache.flights}
+	})
+}
+
+type cachedQuery[Args any, Result any] struct {
+	handler cqrs.QueryHandler[Args, Result]
+	store   Store
+	options *option
+	flights *flights
+}
+
+func (q *cachedQuery[Args, Result]) Handle(ctx context.Context, args Args) (Result, error) {
+	key, err := q.options.Key(ctx, args)
+	if err != nil {
+		return q.handler.Handle(ctx, args)
+	}
+	if value, ok, err := q.store.Get(ctx, key); err == nil && ok {
+		if res, ok := value.(Result); ok {
+			return res, nil
+		}
+	}
+	flight := q.flights.group.DoChan(key, func() (any, error) {
+		return q.load(context.WithoutCancel(ctx), key, args)
+	})
+	select {
+	case <-ctx.Done():
+		return *(new(Result)), ctx.Err()
+	case r := <-flight:
+		if r.Err != nil {
+			return *(new(Result)), r.Err
+		}
+		res, ok := r.Val.(Result)
+		if !ok {
+			return res, fmt.Errorf("%w: %T", cqrs.ErrResultType, r.Val)
+		}
+		return res, nil
+	}
+}
+
+// load calls the handler, and caches the result if key was not invalidated during the call.
+// A panic of the handler is returned as a cqrs.PanicError, since it can't be recovered by the callers of DoChan.
+func (q *cachedQuery[Args, Result]) load(ctx context.Context, key string, args Args) (res Result, err error) {
+	generation := q.flights.begin(key)
+	defer func() {
+		if p := recover(); p != nil {
+			err = &cqrs.PanicError{Value: p, Stack: debug.Stack()}
+		}
+		q.flights.end(key, generation, func() {
+			if err == nil {
+				_ = q.store.Set(ctx, key, res, q.options.TTL)
+			}
+		})
+	}()
+	return q.handler.Handle(ctx, args)
+}
@@ -0,0 +1,221 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-leo/design-pattern/cqrs"
+	"github.com/go-leo/design-pattern/event"
+	"github.com/stretchr/testify/assert"
+)
+
+type findUserQuery struct {
+	ID int
+}
+
+type user struct {
+	ID   int
+	Name string
+}
+
+type userRenamed struct {
+	ID int
+}
+
+func TestQuery(t *testing.T) {
+	var calls atomic.Int32
+	names := map[int]string{1: "jax"}
+	release := make(chan struct{})
+	handler := cqrs.QueryHandlerFunc[findUserQuery, *user](func(ctx context.Context, q findUserQuery) (*user, error) {
+		calls.Add(1)
+		<-release
+		name, ok := names[q.ID]
+		if !ok {
+			return nil, cqrs.NotFound("user %d", q.ID)
+		}
+		return &user{ID: q.ID, Name: name}, nil
+	})
+	cache := New(NewLRU(10))
+	bus := cqrs.NewBus()
+	assert.NoError(t, cqrs.RegisterQuery[findUserQuery, *user](bus, Query[findUserQuery, *user](cache, TTL(time.Minute)).Decorate(handler)))
+	ctx := context.Background()
+
+	// concurrent identical queries share one call
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			u, err := cqrs.Query[findUserQuery, *user](ctx, bus, findUserQuery{ID: 1})
+			assert.NoError(t, err)
+			assert.Equal(t, "jax", u.Name)
+		}()
+	}
+	assert.Eventually(t, func() bool { return calls.Load() == 1 }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), calls.Load())
+
+	// hit
+	u, err := cqrs.Query[findUserQuery, *user](ctx, bus, findUserQuery{ID: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, "jax", u.Name)
+	assert.Equal(t, int32(1), calls.Load())
+
+	// errors are not cached
+	_, err = cqrs.Query[findUserQuery, *user](ctx, bus, findUserQuery{ID: 2})
+	assert.ErrorIs(t, err, cqrs.ErrNotFound)
+	_, err = cqrs.Query[findUserQuery, *user](ctx, bus, findUserQuery{ID: 2})
+	assert.ErrorIs(t, err, cqrs.ErrNotFound)
+	assert.Equal(t, int32(3), calls.Load())
+
+	// invalidated by event
+	eventBus := event.NewBus()
+	assert.NoError(t, eventBus.On(event.NewEvent(userRenamed{}, nil), NewInvalidator(cache, func(e event.Event) []any {
+		return []any{findUserQuery{ID: e.Body().(userRenamed).ID}}
+	})))
+	names[1] = "leo"
+	assert.NoError(t, eventBus.Emit(event.NewEvent(userRenamed{ID: 1}, nil)))
+	u, err = cqrs.Query[findUserQuery, *user](ctx, bus, findUserQuery{ID: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, "leo", u.Name)
+	assert.Equal(t, int32(4), calls.Load())
+}
+
+func TestQuery_Flight(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	handler := cqrs.QueryHandlerFunc[findUserQuery, *user](func(ctx context.Context, q findUserQuery) (*user, error) {
+		n := calls.Add(1)
+		<-release
+		if q.ID < 0 {
+			panic("negative id")
+		}
+		// the call is not canceled by the first query
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return &user{ID: q.ID, Name: fmt.Sprint(n)}, nil
+	})
+	cache := New(NewLRU(10))
+	query := Query[findUserQuery, *user](cache).Decorate(handler)
+	invalidator := NewInvalidator(cache, func(e event.Event) []any {
+		return []any{findUserQuery{ID: e.Body().(userRenamed).ID}}
+	})
+
+	// the first query is canceled, the waiter gets the result
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error)
+	go func() {
+		_, err := query.Handle(ctx, findUserQuery{ID: 1})
+		first <- err
+	}()
+	assert.Eventually(t, func() bool { return calls.Load() == 1 }, time.Second, time.Millisecond)
+	waiter := make(chan *user)
+	go func() {
+		u, err := query.Handle(context.Background(), findUserQuery{ID: 1})
+		assert.NoError(t, err)
+		waiter <- u
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	assert.ErrorIs(t, <-first, context.Canceled)
+
+	// invalidated during the call, the stale result is not cached, and the next query loads again
+	assert.NoError(t, invalidator.Handle(event.NewEvent(userRenamed{ID: 1}, nil)))
+	second := make(chan *user)
+	go func() {
+		u, err := query.Handle(context.Background(), findUserQuery{ID: 1})
+		assert.NoError(t, err)
+		second <- u
+	}()
+	assert.Eventually(t, func() bool { return calls.Load() == 2 }, time.Second, time.Millisecond)
+	release <- struct{}{}
+	assert.Equal(t, "1", (<-waiter).Name)
+	release <- struct{}{}
+	assert.Equal(t, "2", (<-second).Name)
+	u, err := query.Handle(context.Background(), findUserQuery{ID: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, "2", u.Name)
+	assert.Equal(t, int32(2), calls.Load())
+
+	// panics are returned as errors
+	close(release)
+	_, err = query.Handle(context.Background(), findUserQuery{ID: -1})
+	var panicErr *cqrs.PanicError
+	assert.ErrorAs(t, err, &panicErr)
+}
+
+func TestQuery_Key(t *testing.T) {
+	var calls int
+	errKey := errors.New("no key")
+	key := func(ctx context.Context, args any) (string, error) {
+		if args.(findUserQuery).ID < 0 {
+			return "", errKey
+		}
+		return "user", nil
+	}
+	handler := Query[findUserQuery, int](New(NewLRU(10)), Key(key)).Decorate(cqrs.QueryHandlerFunc[findUserQuery, int](func(ctx context.Context, q findUserQuery) (int, error) {
+		calls++
+		return q.ID, nil
+	}))
+	ctx := context.Background()
+
+	res, _ := handler.Handle(ctx, findUserQuery{ID: 1})
+	assert.Equal(t, 1, res)
+	// same key
+	res, _ = handler.Handle(ctx, findUserQuery{ID: 2})
+	assert.Equal(t, 1, res)
+	// not cached if the key can not be derived
+	res, _ = handler.Handle(ctx, findUserQuery{ID: -1})
+	assert.Equal(t, -1, res)
+	res, _ = handler.Handle(ctx, findUserQuery{ID: -1})
+	assert.Equal(t, -1, res)
+	assert.Equal(t, 3, calls)
+
+	key1, err := DefaultKey(ctx, findUserQuery{ID: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, `github.com/go-leo/design-pattern/cqrs/cache.findUserQuery:{"ID":1}`, key1)
+}
+
+func TestQuery_ResultType(t *testing.T) {
+	cache := New(NewLRU(10))
+	key := Key(func(ctx context.Context, args any) (string, error) {
+		return "user", nil
+	})
+	release := make(chan struct{})
+	var calls atomic.Int32
+	id := Query[findUserQuery, int](cache, key).Decorate(cqrs.QueryHandlerFunc[findUserQuery, int](func(ctx context.Context, q findUserQuery) (int, error) {
+		calls.Add(1)
+		<-release
+		return q.ID, nil
+	}))
+	name := Query[findUserQuery, string](cache, key).Decorate(cqrs.QueryHandlerFunc[findUserQuery, string](func(ctx context.Context, q findUserQuery) (string, error) {
+		return "jax", nil
+	}))
+	ctx := context.Background()
+
+	done := make(chan int)
+	go func() {
+		res, err := id.Handle(ctx, findUserQuery{ID: 1})
+		assert.NoError(t, err)
+		done <- res
+	}()
+	assert.Eventually(t, func() bool { return calls.Load() == 1 }, time.Second, time.Millisecond)
+	// shares the flight of the int query
+	mismatch := make(chan error)
+	go func() {
+		_, err := name.Handle(ctx, findUserQuery{ID: 1})
+		mismatch <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	assert.Equal(t, 1, <-done)
+	assert.ErrorIs(t, <-mismatch, cqrs.ErrResultType)
+}
@@ -0,0 +1,66 @@
+package cache
+
+import (
+	"sync"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// flights coordinates the loads of the cached queries with the Invalidators of the same Cache.
+// An invalidation of a key bumps its generation, so that the in-flight load of the key, which may have read
+// the stale data, doesn't set the result, and forgets the flight, so that the next query loads again.
+type flights struct {
+	group singleflight.Group
+	mu    sync.Mutex
+	loads map[string]*load
+}
+
+// load is the state of the in-flight loads of a key.
+type load struct {
+	generation uint64
+	refs       int
+}
+
+func newFlights() *flights {
+	return &flights{loads: make(map[string]*load)}
+}
+
+// begin registers a load of key, and returns the generation of key.
+func (f *flights) begin(key string) uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	l, ok := f.loads[key]
+	if !ok {
+		l = &load{}
+		f.loads[key] = l
+	}
+	l.refs++
+	return l.generation
+}
+
+// end unregisters a load of key, and calls set if key was not invalidated since the generation.
+// set is called with the lock held, so that an invalidation happens either before it and skips it, or after it
+// and deletes its result.
+func (f *flights) end(key string, generation uint64, set func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	l := f.loads[key]
+	if l.generation == generation {
+		set()
+	}
+	if l.refs--; l.refs == 0 {
+		delete(f.loads, key)
+	}
+}
+
+// invalidate bumps the generations of the in-flight keys, and forgets their flights.
+func (f *flights) invalidate(keys ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, key := range keys {
+		if l, ok := f.loads[key]; ok {
+			l.generation++
+		}
+		f.group.Forget(key)
+	}
+}
@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"errors"
+
+	"github.com/go-leo/design-pattern/event"
+)
+
+var _ event.Listener = (*Invalidator)(nil)
+
+// Invalidator is an event.Listener that deletes the cached results of the queries affected by the event.
+type Invalidator struct {
+	store   Store
+	queries func(e event.Event) []any
+	options *option
+	flights *flights
+}
+
+// NewInvalidator returns an Invalidator, queries returns the arguments of the queries whose results
+// are changed by the event, their keys are derived by the KeyFunc set by opts.
+//
+//	c := cache.New(cache.NewLRU(1000))
+//	bus.On(event.NewEvent(userRenamed{}, nil), cache.NewInvalidator(c, func(e event.Event) []any {
+//		return []any{findUserQuery{ID: e.Body().(userRenamed).ID}}
+//	}))
+func NewInvalidator(cache *Cache, queries func(e event.Event) []any, opts ...Option) *Invalidator {
+	return &Invalidator{store: cache.store, queries: queries, options: newOption(opts...), flights: cache.flights}
+}
+
+func (inv *Invalidator) Handle(e event.Event) error {
+	ctx := e.Context()
+	queries := inv.queries(e)
+	keys := make([]string, 0, len(queries))
+	var errs []error
+	for _, args := range queries {
+		key, err := inv.options.Key(ctx, args)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) > 0 {
+		inv.flights.invalidate(keys...)
+		errs = append(errs, inv.store.Delete(ctx, keys...))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,41 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	c := NewLRU(2)
+	c.now = func() time.Time { return now }
+
+	assert.NoError(t, c.Set(ctx, "a", 1, 0))
+	assert.NoError(t, c.Set(ctx, "b", 2, time.Minute))
+	v, ok, err := c.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+
+	// b is the least recently used
+	assert.NoError(t, c.Set(ctx, "c", 3, 0))
+	_, ok, _ = c.Get(ctx, "b")
+	assert.False(t, ok)
+	assert.Equal(t, 2, c.Len())
+
+	// expired
+	assert.NoError(t, c.Set(ctx, "c", 4, time.Minute))
+	now = now.Add(time.Minute)
+	_, ok, _ = c.Get(ctx, "c")
+	assert.False(t, ok)
+	v, ok, _ = c.Get(ctx, "a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+
+	assert.NoError(t, c.Delete(ctx, "a", "unknown"))
+	assert.Equal(t, 0, c.Len())
+}
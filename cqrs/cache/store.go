@@ -0,0 +1,19 @@
+// Package cache caches the results of cqrs.QueryHandler, and invalidates them by the events of event.Bus.
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Store stores the cached results.
+type Store interface {
+	// Get returns the value of key, ok is false if the key does not exist or is expired.
+	Get(ctx context.Context, key string) (value any, ok bool, err error)
+
+	// Set stores the value of key, it expires after ttl, ttl <= 0 means no expiration.
+	Set(ctx context.Context, key string, value any, ttl time.Duration) error
+
+	// Delete deletes the keys.
+	Delete(ctx context.Context, keys ...string) error
+}
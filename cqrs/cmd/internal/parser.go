@@ -86,6 +86,15 @@ func NewFileFromComment(mainPkg string, endpoint string, queryDir, commandDir st
 	return nil
 }
 
+// NewStreamFileFromComment returns the File of a server-streaming method, only a query can be streaming.
+func NewStreamFileFromComment(mainPkg string, endpoint string, queryDir string, comments []string) *File {
+	file := NewFileFromComment(mainPkg, endpoint, queryDir, "", comments)
+	if file == nil || file.Type != "query" {
+		return nil
+	}
+	return NewStreamQueryFile(mainPkg, endpoint, queryDir)
+}
+
 func NewQueryFile(mainPkg string, endpoint string, queryDir string) *File {
 	r := &File{
 		MainPkg:       mainPkg,
@@ -98,6 +107,12 @@ func NewQueryFile(mainPkg string, endpoint string, queryDir string) *File {
 	return r
 }
 
+func NewStreamQueryFile(mainPkg string, endpoint string, queryDir string) *File {
+	r := NewQueryFile(mainPkg, endpoint, queryDir)
+	r.Type = "stream_query"
+	return r
+}
+
 func NewCommandFile(mainPkg string, endpoint string, commandDir string) *File {
 	r := &File{
 		MainPkg:       mainPkg,
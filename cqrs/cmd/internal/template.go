@@ -13,6 +13,9 @@ var commandContent string
 //go:embed query.go.template
 var queryContent string
 
+//go:embed stream_query.go.template
+var streamQueryContent string
+
 type File struct {
 	MainPkg       string
 	Type          string
@@ -27,6 +30,8 @@ func (v File) Gen() error {
 		return v.GenCommand()
 	} else if v.Type == "query" {
 		return v.GenQuery()
+	} else if v.Type == "stream_query" {
+		return v.GenStreamQuery()
 	}
 	return errors.New("unknown endpoint type")
 }
@@ -68,3 +73,22 @@ func (v File) GenCommand() error {
 	}
 	return nil
 }
+
+func (v File) GenStreamQuery() error {
+	tmpl, err := template.New("stream_query").Parse(streamQueryContent)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stat(v.AbsFilename)
+	if os.IsNotExist(err) {
+		file, err := os.Create(v.AbsFilename)
+		if err != nil {
+			return err
+		}
+		return tmpl.Execute(file, &v)
+	}
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -2,6 +2,8 @@ package internal
 
 import (
 	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -24,3 +26,18 @@ func TestQuery(t *testing.T) {
 	}
 	assert.NoError(t, v.GenQuery())
 }
+
+func TestStreamQuery(t *testing.T) {
+	dir := t.TempDir()
+	comments := []string{"// @CQRS @Query"}
+	v := NewStreamFileFromComment("github.com/go-leo/design-pattern/cqrs", "ListUsers", dir, comments)
+	assert.NotNil(t, v)
+	assert.NoError(t, v.Gen())
+	content, err := os.ReadFile(filepath.Join(dir, "list_users.go"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "type ListUsers cqrs.StreamQueryHandler[*ListUsersQuery, *ListUsersResult]")
+	assert.Contains(t, string(content), "send func(*ListUsersResult) error) error")
+
+	// a command can not be streaming
+	assert.Nil(t, NewStreamFileFromComment("github.com/go-leo/design-pattern/cqrs", "Import", dir, []string{"// @CQRS @Command"}))
+}
@@ -80,8 +80,16 @@ func getFileInfo(mainPkg string, gen *protogen.Plugin, file *protogen.File, serv
 				continue
 			}
 			files = append(files, file)
+		} else if method.Desc.IsStreamingServer() && !method.Desc.IsStreamingClient() {
+			// Server-streaming RPC method
+			endpoint := method.GoName
+			file := internal.NewStreamFileFromComment(mainPkg, endpoint, queryAbs, splitComment(method.Comments.Leading.String()))
+			if file == nil {
+				continue
+			}
+			files = append(files, file)
 		} else {
-			// Streaming RPC method
+			// Client-streaming and bidirectional-streaming RPC method
 			continue
 		}
 	}
@@ -4,14 +4,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"github.com/go-leo/design-pattern/event"
+	"github.com/go-leo/design-pattern/instrument"
+	"github.com/go-leo/design-pattern/internal/lifecycle"
+	"github.com/go-leo/design-pattern/workerpool"
 	"github.com/go-leo/gox/contextx"
 	"github.com/go-leo/gox/errorx"
-	"github.com/go-leo/gox/syncx"
-	"github.com/go-leo/gox/syncx/gopher"
-	"github.com/go-leo/gox/syncx/gopher/sample"
 	"reflect"
+	"slices"
+	"sort"
 	"sync"
-	"sync/atomic"
 )
 
 var (
@@ -27,124 +29,355 @@ var (
 	// ErrArgsNil arguments is nil
 	ErrArgsNil = errors.New("cqrs: arguments is nil")
 
+	// ErrArgsInterface arguments type of handler is an interface, the handler can't be found by Exec and Query,
+	// since they look up the handler by the dynamic type of arguments
+	ErrArgsInterface = errors.New("cqrs: arguments type is an interface")
+
 	// ErrBusClosed bus was closed
 	ErrBusClosed = errors.New("cqrs: bus was closed")
 
 	// ErrUnimplemented handler is not implement CommandHandler or QueryHandler
 	ErrUnimplemented = errors.New("cqrs: handler is not implement CommandHandler or QueryHandler")
+
+	// ErrResultType result of handler is not the expected type
+	ErrResultType = errors.New("cqrs: result type mismatch")
+
+	// ErrPublish command succeeded, but the events raised by it failed to publish
+	ErrPublish = errors.New("cqrs: failed to publish events")
+
+	// ErrDrainTimeout in-flight commands and queries were not drained in the drain timeout
+	ErrDrainTimeout = lifecycle.ErrDrainTimeout
 )
 
+// AbandonedError is returned by Close when the in-flight commands and queries were not drained
+// before the context of Close is done or the drain timeout elapses.
+// The contexts of the abandoned commands and queries are canceled.
+type AbandonedError = lifecycle.AbandonedError
+
 var _ Bus = (*defaultBus)(nil)
 
 type defaultBus struct {
-	handlers   *sync.Map
-	wg         *sync.WaitGroup
-	inShutdown *atomic.Bool // true when bus is in shutdown
-	options    *option
+	handlers *sync.Map
+	tracker  *lifecycle.Tracker // tracks in-flight commands and queries
+	options  *option
 }
 
 func (b *defaultBus) RegisterCommand(handler any) error {
 	if err := b.checkHandler(handler); err != nil {
 		return err
 	}
-	handlerRef, err := newReflectedHandler(handler, "command")
+	handlerRef, err := newReflectedHandler(handler, CommandKind)
 	if err != nil {
 		return err
 	}
-	if _, loaded := b.handlers.LoadOrStore(handlerRef.InType(), handlerRef); loaded {
-		return ErrRegistered
-	}
-	return nil
+	return b.register(handlerRef)
 }
 
 func (b *defaultBus) RegisterQuery(handler any) error {
 	if err := b.checkHandler(handler); err != nil {
 		return err
 	}
-	handlerRef, err := newReflectedHandler(handler, "query")
+	handlerRef, err := newReflectedHandler(handler, QueryKind)
 	if err != nil {
 		return err
 	}
-	if _, loaded := b.handlers.LoadOrStore(handlerRef.InType(), handlerRef); loaded {
-		return ErrRegistered
+	return b.register(handlerRef)
+}
+
+func (b *defaultBus) ReplaceCommand(handler any) error {
+	if err := b.checkHandler(handler); err != nil {
+		return err
 	}
-	return nil
+	handlerRef, err := newReflectedHandler(handler, CommandKind)
+	if err != nil {
+		return err
+	}
+	return b.replace(handlerRef)
+}
+
+func (b *defaultBus) ReplaceQuery(handler any) error {
+	if err := b.checkHandler(handler); err != nil {
+		return err
+	}
+	handlerRef, err := newReflectedHandler(handler, QueryKind)
+	if err != nil {
+		return err
+	}
+	return b.replace(handlerRef)
+}
+
+func (b *defaultBus) UnregisterCommand(args any) error {
+	if err := b.checkArgs(args); err != nil {
+		return err
+	}
+	if b.shuttingDown() {
+		return ErrBusClosed
+	}
+	return b.unregister(reflect.TypeOf(args), CommandKind)
+}
+
+func (b *defaultBus) UnregisterQuery(args any) error {
+	if err := b.checkArgs(args); err != nil {
+		return err
+	}
+	if b.shuttingDown() {
+		return ErrBusClosed
+	}
+	return b.unregister(reflect.TypeOf(args), QueryKind, StreamQueryKind)
+}
+
+func (b *defaultBus) Handlers() []HandlerInfo {
+	var infos []HandlerInfo
+	b.handlers.Range(func(_, value any) bool {
+		h := value.(handler)
+		infos = append(infos, HandlerInfo{Kind: h.Kind(), ArgsType: h.InType(), HandlerType: h.HandlerType()})
+		return true
+	})
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ArgsType.String() < infos[j].ArgsType.String()
+	})
+	return infos
 }
 
 func (b *defaultBus) Exec(ctx context.Context, args any) error {
 	if err := b.checkArgs(args); err != nil {
 		return err
 	}
-	info, err := b.loadHandler(args)
+	info, err := b.loadHandler(reflect.TypeOf(args), CommandKind)
+	if err != nil {
+		return err
+	}
+	ctx, done, err := b.enter(ctx)
 	if err != nil {
 		return err
 	}
-	return info.Exec(ctx, args)
+	defer done()
+	_, err = b.exec(ctx, info, args)
+	return err
 }
 
 func (b *defaultBus) Query(ctx context.Context, args any) (any, error) {
 	if err := b.checkArgs(args); err != nil {
 		return nil, err
 	}
-	info, err := b.loadHandler(args)
+	info, err := b.loadHandler(reflect.TypeOf(args), QueryKind)
+	if err != nil {
+		return nil, err
+	}
+	ctx, done, err := b.enter(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return info.Query(ctx, args)
+	defer done()
+	return b.query(ctx, info, args)
 }
 
-func (b *defaultBus) AsyncExec(ctx context.Context, args any) (Future, error) {
+func (b *defaultBus) QueryStream(ctx context.Context, args any) (Stream[any], error) {
 	if err := b.checkArgs(args); err != nil {
 		return nil, err
 	}
-	info, err := b.loadHandler(args)
+	info, err := b.loadHandler(reflect.TypeOf(args), StreamQueryKind)
 	if err != nil {
 		return nil, err
 	}
-	f := newDefaultFuture()
-	b.wg.Add(1)
-	if err := b.options.Pool.Go(func() {
-		defer b.wg.Done()
-		f.OnExec(info.Exec(ctx, args))
-	}); err != nil {
-		return nil, err
-	}
-	return f, nil
+	return b.stream(ctx, info, args)
 }
 
-func (b *defaultBus) AsyncQuery(ctx context.Context, args any) (Future, error) {
+func (b *defaultBus) AsyncExec(ctx context.Context, args any) (Future[any], error) {
 	if err := b.checkArgs(args); err != nil {
 		return nil, err
 	}
-	info, err := b.loadHandler(args)
+	info, err := b.loadHandler(reflect.TypeOf(args), CommandKind)
 	if err != nil {
 		return nil, err
 	}
-	f := newDefaultFuture()
-	b.wg.Add(1)
-	if err := b.options.Pool.Go(func() {
-		defer b.wg.Done()
-		f.OnQuery(info.Query(ctx, args))
-	}); err != nil {
+	return async(ctx, b, func(ctx context.Context) (any, error) {
+		return b.exec(ctx, info, args)
+	})
+}
+
+func (b *defaultBus) AsyncQuery(ctx context.Context, args any) (Future[any], error) {
+	if err := b.checkArgs(args); err != nil {
+		return nil, err
+	}
+	info, err := b.loadHandler(reflect.TypeOf(args), QueryKind)
+	if err != nil {
 		return nil, err
 	}
-	return f, nil
+	return async(ctx, b, func(ctx context.Context) (any, error) {
+		return b.query(ctx, info, args)
+	})
 }
 
+// Close rejects new commands and queries, and waits for the in-flight ones to finish.
+// If ctx is done or the drain timeout elapses first, the contexts of the in-flight ones are canceled,
+// and an AbandonedError that reports how many were abandoned is returned.
 func (b *defaultBus) Close(ctx context.Context) error {
-	if b.inShutdown.CompareAndSwap(false, true) {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-syncx.WaitNotify(b.wg):
-			return nil
+	err := b.tracker.Close(ctx, b.options.DrainTimeout)
+	if errors.Is(err, lifecycle.ErrClosed) {
+		return ErrBusClosed
+	}
+	return err
+}
+
+// enter tracks a command or query until done is called, it returns ErrBusClosed if the bus was closed.
+// The commands and queries executed by an in-flight handler are not rejected while the bus is draining.
+func (b *defaultBus) enter(ctx context.Context) (context.Context, func(), error) {
+	ctx, done, err := b.tracker.Enter(ctx)
+	if err != nil {
+		return ctx, nil, ErrBusClosed
+	}
+	return ctx, done, nil
+}
+
+// exec calls the CommandHandler through the command interceptors,
+// and publishes the events raised by the CommandHandler after the command succeeds.
+// A panic of the CommandHandler or interceptors is returned as a PanicError.
+// If the bus has a UnitOfWork, the CommandHandler is called in a transaction, and the events
+// raised by the commands executed in the transaction are published after the outermost one commits,
+// unless they are written to the Outbox of UnitOfWork and delivered by its relay.
+func (b *defaultBus) exec(ctx context.Context, h handler, args any) (_ any, err error) {
+	ctx, end := b.observe(ctx, instrument.Command, h)
+	defer func() { end(err) }()
+	defer recoverPanic(&err)
+	// the events are emitted with the context of command, but not with the pending events of its transaction.
+	emitCtx := ctx
+	var outer *pendingEvents
+	if b.options.UnitOfWork != nil {
+		var nested bool
+		if outer, nested = ctx.Value(pendingEventsKey{}).(*pendingEvents); !nested {
+			outer = nil
+			ctx = context.WithValue(ctx, pendingEventsKey{}, &pendingEvents{})
+		}
+	}
+	var events []event.Event
+	invoker := func(ctx context.Context, args any) (any, error) {
+		res, raised, err := b.handle(ctx, h, args)
+		if err != nil {
+			return nil, err
 		}
+		events = raised
+		return res, nil
+	}
+	var res any
+	if interceptor := b.options.commandInterceptor(h.InType()); interceptor == nil {
+		res, err = invoker(ctx, args)
+	} else {
+		res, err = interceptor(ctx, args, invoker)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if outer != nil {
+		outer.Append(events)
+		return res, nil
+	}
+	if b.relayed() {
+		return res, nil
+	}
+	if pending, ok := ctx.Value(pendingEventsKey{}).(*pendingEvents); ok {
+		events = append(pending.Load(), events...)
+	}
+	return res, b.publish(emitCtx, events)
+}
+
+// relayed reports whether the events were written to the Outbox of UnitOfWork, and are delivered by its relay.
+func (b *defaultBus) relayed() bool {
+	if !b.options.OutboxRelayed || b.options.UnitOfWork == nil {
+		return false
+	}
+	_, ok := outboxOf(b.options.UnitOfWork)
+	return ok
+}
+
+// handle calls the CommandHandler, in a transaction if the bus has a UnitOfWork.
+func (b *defaultBus) handle(ctx context.Context, h handler, args any) (any, []event.Event, error) {
+	if b.options.UnitOfWork == nil {
+		return h.Exec(ctx, args)
+	}
+	return transact(ctx, b.options.UnitOfWork, h, args)
+}
+
+// publish emits the events to the event bus with the context of command, so the listeners carry its trace,
+// and are not called after it is done.
+func (b *defaultBus) publish(ctx context.Context, events []event.Event) error {
+	if b.options.EventBus == nil || len(events) == 0 {
+		return nil
+	}
+	errs := make([]error, 0, len(events))
+	for _, e := range events {
+		errs = append(errs, b.options.EventBus.EmitContext(ctx, e))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("%w: %w", ErrPublish, err)
+	}
+	return nil
+}
+
+// query calls the QueryHandler through the query interceptors.
+// A panic of the QueryHandler or interceptors is returned as a PanicError.
+func (b *defaultBus) query(ctx context.Context, h handler, args any) (_ any, err error) {
+	ctx, end := b.observe(ctx, instrument.Query, h)
+	defer func() { end(err) }()
+	defer recoverPanic(&err)
+	interceptor := b.options.queryInterceptor(h.InType())
+	if interceptor == nil {
+		return h.Query(ctx, args)
+	}
+	return interceptor(ctx, args, h.Query)
+}
+
+// stream runs the StreamQueryHandler in the pool of bus, the results are passed to the returned Stream.
+// The streaming query is in flight until the StreamQueryHandler returns.
+func (b *defaultBus) stream(ctx context.Context, h handler, args any) (Stream[any], error) {
+	ctx, done, err := b.enter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p := newPipe[any](ctx)
+	err = workerpool.Submit(b.options.Pool, func() {
+		defer done()
+		p.end(b.queryStream(p.ctx, h, args, p.send))
+	}, func(err error) {
+		done()
+		p.end(err)
+	})
+	if err != nil {
+		done()
+		p.end(err)
+		return nil, err
+	}
+	return p, nil
+}
+
+// queryStream calls the StreamQueryHandler through the query interceptors, the resp of interceptors is nil.
+// A panic of the StreamQueryHandler or interceptors is returned as a PanicError.
+func (b *defaultBus) queryStream(ctx context.Context, h handler, args any, send func(any) error) (err error) {
+	ctx, end := b.observe(ctx, instrument.StreamQuery, h)
+	defer func() { end(err) }()
+	defer recoverPanic(&err)
+	invoker := func(ctx context.Context, args any) (any, error) {
+		return nil, h.Stream(ctx, args, send)
+	}
+	interceptor := b.options.queryInterceptor(h.InType())
+	if interceptor == nil {
+		_, err = invoker(ctx, args)
+		return err
+	}
+	_, err = interceptor(ctx, args, invoker)
+	return err
+}
+
+// observe starts the operation of handler by the Hook of bus, end must be called with the error of operation.
+func (b *defaultBus) observe(ctx context.Context, kind instrument.Kind, h handler) (context.Context, func(err error)) {
+	if b.options.Hook == nil {
+		return ctx, func(error) {}
 	}
-	return ErrBusClosed
+	return b.options.Hook.Start(ctx, instrument.Operation{Kind: kind, Message: h.InType().String()})
 }
 
 func (b *defaultBus) shuttingDown() bool {
-	return b.inShutdown.Load()
+	return b.tracker.Closed()
 }
 
 func (b *defaultBus) checkHandler(handler any) error {
@@ -161,71 +394,136 @@ func (b *defaultBus) checkArgs(args any) error {
 	if args == nil {
 		return ErrArgsNil
 	}
-	if b.shuttingDown() {
-		return ErrBusClosed
-	}
 	return nil
 }
 
-func (b *defaultBus) loadHandler(args any) (*reflectedHandler, error) {
-	value, ok := b.handlers.Load(reflect.TypeOf(args))
-	if !ok {
-		return nil, ErrUnregistered
+// register stores h, it returns ErrRegistered if a handler of the same arguments type was registered.
+func (b *defaultBus) register(h handler) error {
+	if err := checkInType(h.InType()); err != nil {
+		return err
+	}
+	if _, loaded := b.handlers.LoadOrStore(h.InType(), h); loaded {
+		return ErrRegistered
 	}
-	info := value.(*reflectedHandler)
-	return info, nil
+	return nil
 }
 
-type option struct {
-	Pool gopher.Gopher
+// replace stores h, or swaps the registered handler of the same kind with h.
+func (b *defaultBus) replace(h handler) error {
+	if err := checkInType(h.InType()); err != nil {
+		return err
+	}
+	for {
+		value, loaded := b.handlers.LoadOrStore(h.InType(), h)
+		if !loaded {
+			return nil
+		}
+		if value.(handler).Kind() != h.Kind() {
+			return ErrRegistered
+		}
+		if b.handlers.CompareAndSwap(h.InType(), value, h) {
+			return nil
+		}
+	}
 }
 
-func newOption(opts ...Option) *option {
-	o := &option{}
-	for _, opt := range opts {
-		opt(o)
+// checkInType returns ErrArgsInterface if the arguments type of handler is an interface.
+func checkInType(inType reflect.Type) error {
+	if inType.Kind() == reflect.Interface {
+		return fmt.Errorf("%w: %s", ErrArgsInterface, inType)
 	}
-	if o.Pool == nil {
-		o.Pool = sample.Gopher{}
+	return nil
+}
+
+// unregister deletes the registered handler of the kinds.
+func (b *defaultBus) unregister(inType reflect.Type, kinds ...Kind) error {
+	for {
+		value, ok := b.handlers.Load(inType)
+		if !ok || !slices.Contains(kinds, value.(handler).Kind()) {
+			return ErrUnregistered
+		}
+		if b.handlers.CompareAndDelete(inType, value) {
+			return nil
+		}
 	}
-	return o
 }
 
-type Option func(*option)
+func (b *defaultBus) loadHandler(inType reflect.Type, kind Kind) (handler, error) {
+	value, ok := b.handlers.Load(inType)
+	if !ok {
+		return nil, ErrUnregistered
+	}
+	h := value.(handler)
+	if h.Kind() != kind {
+		return nil, ErrUnregistered
+	}
+	return h, nil
+}
 
-func Pool(pool gopher.Gopher) Option {
-	return func(o *option) {
-		o.Pool = pool
+// async runs fn in the pool of bus, and tracks it until fn returns.
+func async[T any](ctx context.Context, b *defaultBus, fn func(ctx context.Context) (T, error)) (Future[T], error) {
+	ctx, done, err := b.enter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	f, err := goFuture(ctx, b.options.Pool, func(ctx context.Context) (T, error) {
+		defer done()
+		return fn(ctx)
+	}, done)
+	if err != nil {
+		done()
+		return nil, err
 	}
+	return f, nil
 }
 
 func NewBus(opts ...Option) Bus {
 	return &defaultBus{
-		handlers:   &sync.Map{},
-		wg:         &sync.WaitGroup{},
-		inShutdown: &atomic.Bool{},
-		options:    newOption(opts...),
+		handlers: &sync.Map{},
+		tracker:  lifecycle.NewTracker(),
+		options:  newOption(opts...),
 	}
 }
 
+// handler is the handler registered in defaultBus.
+type handler interface {
+	Exec(ctx context.Context, args any) (any, []event.Event, error)
+	Query(ctx context.Context, args any) (any, error)
+	Stream(ctx context.Context, args any, send func(any) error) error
+	InType() reflect.Type
+	Kind() Kind
+	HandlerType() reflect.Type
+}
+
+var eventsType = reflect.TypeOf([]event.Event(nil))
+
 type reflectedHandler struct {
 	value  reflect.Value
 	method reflect.Method
 	inType reflect.Type
+	kind   Kind
 }
 
-func (handler *reflectedHandler) Exec(ctx context.Context, args any) error {
+func (handler *reflectedHandler) Exec(ctx context.Context, args any) (any, []event.Event, error) {
 	resultValues := handler.method.Func.Call(
 		[]reflect.Value{
 			handler.value,
 			reflect.ValueOf(ctx),
 			reflect.ValueOf(args),
 		})
-	err := resultValues[0].Interface()
+	if len(resultValues) == 1 {
+		err := resultValues[0].Interface()
+		if err != nil {
+			return nil, nil, err.(error)
+		}
+		return nil, nil, nil
+	}
+	err := resultValues[2].Interface()
 	if err != nil {
-		return err.(error)
+		return nil, nil, err.(error)
 	}
-	return nil
+	events, _ := resultValues[1].Interface().([]event.Event)
+	return resultValues[0].Interface(), events, nil
 }
 
 func (handler *reflectedHandler) Query(ctx context.Context, args any) (any, error) {
@@ -242,41 +540,102 @@ func (handler *reflectedHandler) Query(ctx context.Context, args any) (any, erro
 	return resultValues[0].Interface(), nil
 }
 
+func (handler *reflectedHandler) Stream(ctx context.Context, args any, send func(any) error) error {
+	if handler.kind != StreamQueryKind {
+		res, err := handler.Query(ctx, args)
+		if err != nil {
+			return err
+		}
+		return send(res)
+	}
+	sendFunc := reflect.MakeFunc(handler.method.Type.In(3), func(in []reflect.Value) []reflect.Value {
+		err := send(in[0].Interface())
+		return []reflect.Value{reflect.ValueOf(&err).Elem()}
+	})
+	resultValues := handler.method.Func.Call(
+		[]reflect.Value{
+			handler.value,
+			reflect.ValueOf(ctx),
+			reflect.ValueOf(args),
+			sendFunc,
+		})
+	err := resultValues[0].Interface()
+	if err != nil {
+		return err.(error)
+	}
+	return nil
+}
+
 func (handler *reflectedHandler) InType() reflect.Type {
 	return handler.inType
 }
 
-func newReflectedHandler(handler any, kind string) (*reflectedHandler, error) {
+func (handler *reflectedHandler) Kind() Kind {
+	return handler.kind
+}
+
+func (handler *reflectedHandler) HandlerType() reflect.Type {
+	return handler.value.Type()
+}
+
+func newReflectedHandler(handler any, kind Kind) (*reflectedHandler, error) {
 	handlerVal := reflect.ValueOf(handler)
 	method, ok := handlerVal.Type().MethodByName("Handle")
 	if !ok {
 		return nil, ErrUnimplemented
 	}
 	switch kind {
-	case "command":
+	case CommandKind:
 		if method.Type.NumIn() != 3 {
 			return nil, ErrUnimplemented
 		}
 		if !method.Type.In(1).Implements(contextx.ContextType) {
 			return nil, ErrUnimplemented
 		}
-		if method.Type.NumOut() != 1 {
+		switch method.Type.NumOut() {
+		case 1:
+			// CommandHandler
+			if !method.Type.Out(0).Implements(errorx.ErrorType) {
+				return nil, ErrUnimplemented
+			}
+		case 3:
+			// CommandResultHandler
+			if method.Type.Out(1) != eventsType {
+				return nil, ErrUnimplemented
+			}
+			if !method.Type.Out(2).Implements(errorx.ErrorType) {
+				return nil, ErrUnimplemented
+			}
+		default:
 			return nil, ErrUnimplemented
 		}
-		if !method.Type.Out(0).Implements(errorx.ErrorType) {
-			return nil, ErrUnimplemented
-		}
-	case "query":
-		if method.Type.NumIn() != 3 {
+	case QueryKind:
+		if method.Type.NumIn() < 3 {
 			return nil, ErrUnimplemented
 		}
 		if !method.Type.In(1).Implements(contextx.ContextType) {
 			return nil, ErrUnimplemented
 		}
-		if method.Type.NumOut() != 2 {
-			return nil, ErrUnimplemented
-		}
-		if !method.Type.Out(1).Implements(errorx.ErrorType) {
+		switch method.Type.NumIn() {
+		case 3:
+			// QueryHandler
+			if method.Type.NumOut() != 2 {
+				return nil, ErrUnimplemented
+			}
+			if !method.Type.Out(1).Implements(errorx.ErrorType) {
+				return nil, ErrUnimplemented
+			}
+		case 4:
+			// StreamQueryHandler
+			send := method.Type.In(3)
+			if send.Kind() != reflect.Func || send.NumIn() != 1 || send.NumOut() != 1 || send.Out(0) != errorx.ErrorType {
+				return nil, ErrUnimplemented
+			}
+			if method.Type.NumOut() != 1 || !method.Type.Out(0).Implements(errorx.ErrorType) {
+				return nil, ErrUnimplemented
+			}
+			kind = StreamQueryKind
+		default:
 			return nil, ErrUnimplemented
 		}
 	default:
@@ -287,40 +646,6 @@ func newReflectedHandler(handler any, kind string) (*reflectedHandler, error) {
 		value:  handlerVal,
 		method: method,
 		inType: inType,
+		kind:   kind,
 	}, nil
 }
-
-type defaultFuture struct {
-	resC chan any
-	errC chan error
-}
-
-func (f *defaultFuture) Get(ctx context.Context) (any, error) {
-	select {
-	case <-ctx.Done():
-		return nil, ctx.Err()
-	case res := <-f.resC:
-		return res, nil
-	case err := <-f.errC:
-		return nil, err
-	}
-}
-
-func (f *defaultFuture) OnExec(err error) {
-	f.errC <- err
-}
-
-func (f *defaultFuture) OnQuery(res any, err error) {
-	if err != nil {
-		f.errC <- err
-		return
-	}
-	f.resC <- res
-}
-
-func newDefaultFuture() *defaultFuture {
-	return &defaultFuture{
-		resC: make(chan any, 1),
-		errC: make(chan error, 1),
-	}
-}
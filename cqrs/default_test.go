@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"reflect"
 	"testing"
 	"time"
 
@@ -95,7 +96,7 @@ func TestBus(t *testing.T) {
 	bus := NewBus()
 	var err error
 	var res any
-	var future Future
+	var future Future[any]
 
 	// command
 	err = bus.RegisterCommand(&study{})
@@ -184,3 +185,115 @@ func TestBus(t *testing.T) {
 	res, err = future.Get(context.Background())
 	assert.ErrorIs(t, err, errNotFoundTeacher)
 }
+
+func TestBus_Manage(t *testing.T) {
+	bus := NewBus()
+	var err error
+
+	err = bus.RegisterCommand(&study{})
+	assert.NoError(t, err)
+	err = RegisterQuery[countQuery, int](bus, QueryHandlerFunc[countQuery, int](func(ctx context.Context, q countQuery) (int, error) {
+		return q.N, nil
+	}))
+	assert.NoError(t, err)
+
+	infos := bus.Handlers()
+	assert.Len(t, infos, 2)
+	assert.Equal(t, HandlerInfo{Kind: CommandKind, ArgsType: reflect.TypeOf(&studyCmd{}), HandlerType: reflect.TypeOf(&study{})}, infos[0])
+	assert.Equal(t, HandlerInfo{Kind: QueryKind, ArgsType: reflect.TypeOf(countQuery{}), HandlerType: reflect.TypeOf(QueryHandlerFunc[countQuery, int](nil))}, infos[1])
+	assert.Equal(t, "command", infos[0].Kind.String())
+
+	// kind mismatch
+	err = bus.Exec(context.Background(), countQuery{})
+	assert.ErrorIs(t, err, ErrUnregistered)
+	err = bus.UnregisterQuery(&studyCmd{})
+	assert.ErrorIs(t, err, ErrUnregistered)
+	err = bus.ReplaceQuery(QueryHandlerFunc[*studyCmd, int](nil))
+	assert.ErrorIs(t, err, ErrRegistered)
+
+	// replace
+	var replaced bool
+	err = Replace[*studyCmd](bus, CommandHandlerFunc[*studyCmd](func(ctx context.Context, args *studyCmd) error {
+		replaced = true
+		return nil
+	}))
+	assert.NoError(t, err)
+	err = bus.Exec(context.Background(), &studyCmd{})
+	assert.NoError(t, err)
+	assert.True(t, replaced)
+
+	err = bus.ReplaceCommand(&exam{})
+	assert.NoError(t, err)
+	err = bus.Exec(context.Background(), examCmd{})
+	assert.ErrorIs(t, err, errNotPassed)
+
+	// unregister
+	err = bus.UnregisterCommand(&studyCmd{})
+	assert.NoError(t, err)
+	err = bus.UnregisterCommand(&studyCmd{})
+	assert.ErrorIs(t, err, ErrUnregistered)
+	err = bus.Exec(context.Background(), &studyCmd{})
+	assert.ErrorIs(t, err, ErrUnregistered)
+	err = bus.UnregisterQuery(countQuery{})
+	assert.NoError(t, err)
+	assert.Len(t, bus.Handlers(), 1)
+
+	err = bus.RegisterCommand(&study{})
+	assert.NoError(t, err)
+}
+
+type sleepCmd struct {
+	Duration time.Duration
+}
+
+func TestBus_Close(t *testing.T) {
+	bus := NewBus(DrainTimeout(50 * time.Millisecond))
+	started := make(chan struct{}, 4)
+	err := Register[sleepCmd](bus, CommandHandlerFunc[sleepCmd](func(ctx context.Context, cmd sleepCmd) error {
+		if cmd.Duration == 0 {
+			return nil
+		}
+		started <- struct{}{}
+		select {
+		case <-time.After(cmd.Duration):
+			// nested commands of in-flight ones are not rejected while draining
+			return Exec(ctx, bus, sleepCmd{})
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}))
+	assert.NoError(t, err)
+
+	// drained
+	errC := make(chan error, 1)
+	go func() { errC <- bus.Exec(context.Background(), sleepCmd{Duration: 20 * time.Millisecond}) }()
+	<-started
+	assert.NoError(t, bus.Close(context.Background()))
+	assert.NoError(t, <-errC)
+	assert.ErrorIs(t, bus.Exec(context.Background(), sleepCmd{}), ErrBusClosed)
+	_, err = bus.AsyncExec(context.Background(), sleepCmd{})
+	assert.ErrorIs(t, err, ErrBusClosed)
+	assert.ErrorIs(t, bus.Close(context.Background()), ErrBusClosed)
+
+	// abandoned
+	bus = NewBus(DrainTimeout(20 * time.Millisecond))
+	err = Register[sleepCmd](bus, CommandHandlerFunc[sleepCmd](func(ctx context.Context, cmd sleepCmd) error {
+		started <- struct{}{}
+		<-ctx.Done()
+		return ctx.Err()
+	}))
+	assert.NoError(t, err)
+	go func() { errC <- bus.Exec(context.Background(), sleepCmd{}) }()
+	future, err := bus.AsyncExec(context.Background(), sleepCmd{})
+	assert.NoError(t, err)
+	<-started
+	<-started
+	err = bus.Close(context.Background())
+	var abandonedErr *AbandonedError
+	assert.ErrorAs(t, err, &abandonedErr)
+	assert.Equal(t, 2, abandonedErr.Abandoned)
+	assert.ErrorIs(t, err, ErrDrainTimeout)
+	assert.ErrorIs(t, <-errC, context.Canceled)
+	_, err = future.Get(context.Background())
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,158 @@
+package cqrs
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+)
+
+// ErrorCode classifies the errors returned by handlers,
+// so that middleware and transports can map them to their own codes.
+type ErrorCode int
+
+const (
+	// CodeUnknown is the code of unclassified errors.
+	CodeUnknown ErrorCode = iota
+	// CodeNotFound means the requested entity was not found.
+	CodeNotFound
+	// CodeConflict means the command conflicts with the current state, e.g. a concurrent modification.
+	CodeConflict
+	// CodeValidation means the arguments are invalid.
+	CodeValidation
+	// CodeUnauthorized means the caller is not allowed to execute the command or query.
+	CodeUnauthorized
+	// CodeTransient means the failure is temporary, and the command or query can be retried.
+	CodeTransient
+)
+
+func (c ErrorCode) String() string {
+	switch c {
+	case CodeNotFound:
+		return "not found"
+	case CodeConflict:
+		return "conflict"
+	case CodeValidation:
+		return "validation"
+	case CodeUnauthorized:
+		return "unauthorized"
+	case CodeTransient:
+		return "transient"
+	default:
+		return "unknown"
+	}
+}
+
+var (
+	// ErrNotFound matches every Error with CodeNotFound by errors.Is
+	ErrNotFound = &Error{Code: CodeNotFound}
+
+	// ErrConflict matches every Error with CodeConflict by errors.Is
+	ErrConflict = &Error{Code: CodeConflict}
+
+	// ErrValidation matches every Error with CodeValidation by errors.Is
+	ErrValidation = &Error{Code: CodeValidation}
+
+	// ErrUnauthorized matches every Error with CodeUnauthorized by errors.Is
+	ErrUnauthorized = &Error{Code: CodeUnauthorized}
+
+	// ErrTransient matches every Error with CodeTransient by errors.Is
+	ErrTransient = &Error{Code: CodeTransient}
+)
+
+// Error is a classified error.
+type Error struct {
+	// Code classifies the error.
+	Code ErrorCode
+	// Message describes the error.
+	Message string
+	// Err is the cause of the error.
+	Err error
+}
+
+func (e *Error) Error() string {
+	msg := "cqrs: " + e.Code.String()
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is the sentinel error of the same code, e.g. ErrNotFound.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+	return t.Message == "" && t.Err == nil && t.Code == e.Code
+}
+
+// NewError returns an Error with the code, message and cause.
+func NewError(code ErrorCode, message string, cause error) error {
+	return &Error{Code: code, Message: message, Err: cause}
+}
+
+// NotFound returns an Error with CodeNotFound.
+func NotFound(format string, args ...any) error {
+	return NewError(CodeNotFound, fmt.Sprintf(format, args...), nil)
+}
+
+// Conflict returns an Error with CodeConflict.
+func Conflict(format string, args ...any) error {
+	return NewError(CodeConflict, fmt.Sprintf(format, args...), nil)
+}
+
+// Validation returns an Error with CodeValidation.
+func Validation(format string, args ...any) error {
+	return NewError(CodeValidation, fmt.Sprintf(format, args...), nil)
+}
+
+// Unauthorized returns an Error with CodeUnauthorized.
+func Unauthorized(format string, args ...any) error {
+	return NewError(CodeUnauthorized, fmt.Sprintf(format, args...), nil)
+}
+
+// Transient returns an Error with CodeTransient that wraps the cause.
+func Transient(cause error) error {
+	return NewError(CodeTransient, "", cause)
+}
+
+// CodeOf returns the code of the first Error in the chain of err, CodeUnknown if there is none.
+func CodeOf(err error) ErrorCode {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return CodeUnknown
+}
+
+// PanicError is returned when a handler panics, it carries the panic value and the stack trace.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value any
+	// Stack is the stack trace of the goroutine that panicked.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("cqrs: handler panic: %v", e.Value)
+}
+
+// Unwrap returns the panic value if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// recoverPanic recovers the panic, and sets it to err as a PanicError, it must be called by defer.
+func recoverPanic(err *error) {
+	if p := recover(); p != nil {
+		*err = &PanicError{Value: p, Stack: debug.Stack()}
+	}
+}
@@ -0,0 +1,59 @@
+package cqrs
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestError(t *testing.T) {
+	err := NotFound("user %d", 1)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NotErrorIs(t, err, ErrConflict)
+	assert.Equal(t, CodeNotFound, CodeOf(err))
+	assert.Equal(t, "cqrs: not found: user 1", err.Error())
+
+	cause := errors.New("connection reset")
+	err = Transient(cause)
+	assert.ErrorIs(t, err, ErrTransient)
+	assert.ErrorIs(t, err, cause)
+	assert.Equal(t, CodeTransient, CodeOf(errors.Join(errors.New("wrapped"), err)))
+
+	assert.Equal(t, CodeUnknown, CodeOf(cause))
+	assert.ErrorIs(t, Conflict("version"), ErrConflict)
+	assert.ErrorIs(t, Validation("name"), ErrValidation)
+	assert.ErrorIs(t, Unauthorized("admin"), ErrUnauthorized)
+}
+
+func TestPanic(t *testing.T) {
+	bus := NewBus()
+	assert.NoError(t, Register[*renameCmd](bus, CommandHandlerFunc[*renameCmd](func(ctx context.Context, cmd *renameCmd) error {
+		panic(cmd.Name)
+	})))
+	assert.NoError(t, RegisterQuery[countQuery, int](bus, QueryHandlerFunc[countQuery, int](func(ctx context.Context, q countQuery) (int, error) {
+		return 10 / q.N, nil
+	})))
+
+	var panicErr *PanicError
+	err := bus.Exec(context.Background(), &renameCmd{Name: "boom"})
+	assert.ErrorAs(t, err, &panicErr)
+	assert.Equal(t, "boom", panicErr.Value)
+	assert.NotEmpty(t, panicErr.Stack)
+
+	future, err := bus.AsyncExec(context.Background(), &renameCmd{Name: "boom"})
+	assert.NoError(t, err)
+	_, err = future.Get(context.Background())
+	assert.ErrorAs(t, err, &panicErr)
+
+	_, err = Query[countQuery, int](context.Background(), bus, countQuery{})
+	assert.ErrorAs(t, err, &panicErr)
+
+	f, err := AsyncQuery[countQuery, int](context.Background(), bus, countQuery{})
+	assert.NoError(t, err)
+	_, err = f.Get(context.Background())
+	assert.ErrorAs(t, err, &panicErr)
+	var runtimeErr interface{ RuntimeError() }
+	assert.ErrorAs(t, err, &runtimeErr)
+}
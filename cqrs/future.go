@@ -0,0 +1,214 @@
+package cqrs
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/go-leo/design-pattern/workerpool"
+	"github.com/go-leo/gox/syncx/gopher"
+)
+
+// Future represents the result of an asynchronous computation.
+type Future[T any] interface {
+	// Get waits for computation completion, and to retrieve the result of the computation.
+	// Get can be called many times, and always returns the same result.
+	Get(ctx context.Context) (T, error)
+
+	// TryGet retrieves the result of the computation without waiting,
+	// done is false if the computation is not completed yet.
+	TryGet() (res T, done bool, err error)
+
+	// Done returns a channel that's closed when the computation is completed.
+	Done() <-chan struct{}
+
+	// Cancel cancels the context of the computation, and completes the Future with context.Canceled
+	// if it is not completed yet.
+	Cancel()
+}
+
+// Go runs fn asynchronously in pool, and returns a Future of its result.
+// The context passed to fn is canceled when the Future is canceled or fn returns.
+// If fn panics, the Future fails with a PanicError.
+// If pool is a workerpool.Droppable and fn is dropped before it runs, the Future fails with the reason.
+func Go[T any](ctx context.Context, pool gopher.Gopher, fn func(ctx context.Context) (T, error)) (Future[T], error) {
+	return goFuture(ctx, pool, fn, nil)
+}
+
+// goFuture is Go, dropped is called if fn is dropped by pool.
+func goFuture[T any](ctx context.Context, pool gopher.Gopher, fn func(ctx context.Context) (T, error), dropped func()) (Future[T], error) {
+	ctx, cancel := context.WithCancel(ctx)
+	p := newPromise[T](cancel)
+	if err := workerpool.Submit(pool, func() {
+		defer cancel()
+		var res T
+		var err error
+		defer func() { p.resolve(res, err) }()
+		defer recoverPanic(&err)
+		res, err = fn(ctx)
+	}, func(err error) {
+		cancel()
+		if dropped != nil {
+			dropped()
+		}
+		p.resolve(*(new(T)), err)
+	}); err != nil {
+		cancel()
+		return nil, err
+	}
+	return p, nil
+}
+
+// Then returns a Future that calls fn with the result of f when f completes successfully.
+// If f fails, the returned Future fails with the same error.
+func Then[T any, R any](f Future[T], fn func(ctx context.Context, res T) (R, error)) Future[R] {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := newPromise[R](func() {
+		cancel()
+		f.Cancel()
+	})
+	go func() {
+		defer cancel()
+		res, err := f.Get(ctx)
+		if err != nil {
+			p.resolve(*(new(R)), err)
+			return
+		}
+		p.resolve(fn(ctx, res))
+	}()
+	return p
+}
+
+// Map returns a Future that converts the result of f by fn.
+func Map[T any, R any](f Future[T], fn func(res T) R) Future[R] {
+	return Then(f, func(_ context.Context, res T) (R, error) {
+		return fn(res), nil
+	})
+}
+
+// All returns a Future that completes when all futures complete successfully, or when any of futures fails.
+// On failure the rest of futures are canceled.
+func All[T any](futures ...Future[T]) Future[[]T] {
+	p := newPromise[[]T](func() { cancelAll(futures) })
+	errC := make(chan error, len(futures))
+	results := make([]T, len(futures))
+	for i, f := range futures {
+		go func(i int, f Future[T]) {
+			res, err := f.Get(context.Background())
+			results[i] = res
+			errC <- err
+		}(i, f)
+	}
+	go func() {
+		for range futures {
+			if err := <-errC; err != nil {
+				cancelAll(futures)
+				p.resolve(nil, err)
+				return
+			}
+		}
+		p.resolve(results, nil)
+	}()
+	return p
+}
+
+// Any returns a Future that completes with the first successful result of futures, the rest of futures are canceled.
+// If all futures fail, the returned Future fails with all errors joined.
+func Any[T any](futures ...Future[T]) Future[T] {
+	return first(futures, true)
+}
+
+// Race returns a Future that completes with the result of the first completed future, the rest of futures are canceled.
+func Race[T any](futures ...Future[T]) Future[T] {
+	return first(futures, false)
+}
+
+func first[T any](futures []Future[T], successOnly bool) Future[T] {
+	p := newPromise[T](func() { cancelAll(futures) })
+	if len(futures) == 0 {
+		p.resolve(*(new(T)), nil)
+		return p
+	}
+	type result struct {
+		res T
+		err error
+	}
+	resultC := make(chan result, len(futures))
+	for _, f := range futures {
+		go func(f Future[T]) {
+			res, err := f.Get(context.Background())
+			resultC <- result{res: res, err: err}
+		}(f)
+	}
+	go func() {
+		errs := make([]error, 0, len(futures))
+		for range futures {
+			r := <-resultC
+			if successOnly && r.err != nil {
+				errs = append(errs, r.err)
+				continue
+			}
+			cancelAll(futures)
+			p.resolve(r.res, r.err)
+			return
+		}
+		p.resolve(*(new(T)), errors.Join(errs...))
+	}()
+	return p
+}
+
+func cancelAll[T any](futures []Future[T]) {
+	for _, f := range futures {
+		f.Cancel()
+	}
+}
+
+// promise is the default implementation of Future.
+type promise[T any] struct {
+	done   chan struct{}
+	once   sync.Once
+	res    T
+	err    error
+	cancel func()
+}
+
+func (p *promise[T]) Get(ctx context.Context) (T, error) {
+	select {
+	case <-ctx.Done():
+		return *(new(T)), ctx.Err()
+	case <-p.done:
+		return p.res, p.err
+	}
+}
+
+func (p *promise[T]) TryGet() (T, bool, error) {
+	select {
+	case <-p.done:
+		return p.res, true, p.err
+	default:
+		return *(new(T)), false, nil
+	}
+}
+
+func (p *promise[T]) Done() <-chan struct{} {
+	return p.done
+}
+
+func (p *promise[T]) Cancel() {
+	if p.cancel != nil {
+		p.cancel()
+	}
+	p.resolve(*(new(T)), context.Canceled)
+}
+
+func (p *promise[T]) resolve(res T, err error) {
+	p.once.Do(func() {
+		p.res = res
+		p.err = err
+		close(p.done)
+	})
+}
+
+func newPromise[T any](cancel func()) *promise[T] {
+	return &promise[T]{done: make(chan struct{}), cancel: cancel}
+}
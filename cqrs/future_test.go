@@ -0,0 +1,156 @@
+package cqrs
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-leo/design-pattern/workerpool"
+	"github.com/go-leo/gox/syncx/gopher/sample"
+	"github.com/stretchr/testify/assert"
+)
+
+var errSlow = errors.New("slow")
+
+func delay[T any](t *testing.T, d time.Duration, res T, err error) Future[T] {
+	f, e := Go(context.Background(), sample.Gopher{}, func(ctx context.Context) (T, error) {
+		select {
+		case <-ctx.Done():
+			return *(new(T)), ctx.Err()
+		case <-time.After(d):
+			return res, err
+		}
+	})
+	assert.NoError(t, e)
+	return f
+}
+
+func TestFuture(t *testing.T) {
+	f := delay(t, 50*time.Millisecond, 1, nil)
+	_, done, _ := f.TryGet()
+	assert.False(t, done)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := f.Get(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	<-f.Done()
+	for i := 0; i < 2; i++ {
+		res, err := f.Get(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 1, res)
+	}
+	res, done, err := f.TryGet()
+	assert.True(t, done)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, res)
+}
+
+func TestFuture_Cancel(t *testing.T) {
+	canceled := make(chan struct{})
+	f, err := Go(context.Background(), sample.Gopher{}, func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		close(canceled)
+		return 0, ctx.Err()
+	})
+	assert.NoError(t, err)
+	f.Cancel()
+	_, err = f.Get(context.Background())
+	assert.ErrorIs(t, err, context.Canceled)
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("handler context is not canceled")
+	}
+}
+
+func TestThenAndMap(t *testing.T) {
+	f := Map(Then(delay(t, time.Millisecond, 21, nil), func(ctx context.Context, res int) (int, error) {
+		return res * 2, nil
+	}), strconv.Itoa)
+	res, err := f.Get(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "42", res)
+
+	f = Map(delay(t, time.Millisecond, 0, errSlow), strconv.Itoa)
+	_, err = f.Get(context.Background())
+	assert.ErrorIs(t, err, errSlow)
+}
+
+func TestAll(t *testing.T) {
+	res, err := All(delay(t, 20*time.Millisecond, 1, nil), delay(t, time.Millisecond, 2, nil)).Get(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, res)
+
+	slow := delay(t, time.Minute, 1, nil)
+	_, err = All(slow, delay(t, time.Millisecond, 2, errSlow)).Get(context.Background())
+	assert.ErrorIs(t, err, errSlow)
+	_, err = slow.Get(context.Background())
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestAnyAndRace(t *testing.T) {
+	slow := delay(t, time.Minute, 1, nil)
+	res, err := Any(slow, delay(t, time.Millisecond, 2, errSlow), delay(t, 10*time.Millisecond, 3, nil)).Get(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 3, res)
+	_, err = slow.Get(context.Background())
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = Any(delay(t, time.Millisecond, 1, errSlow), delay(t, time.Millisecond, 2, errNotPassed)).Get(context.Background())
+	assert.ErrorIs(t, err, errSlow)
+	assert.ErrorIs(t, err, errNotPassed)
+
+	_, err = Race(delay(t, time.Minute, 1, nil), delay(t, time.Millisecond, 2, errSlow)).Get(context.Background())
+	assert.ErrorIs(t, err, errSlow)
+}
+
+func TestAsyncQuery(t *testing.T) {
+	bus := NewBus()
+	assert.NoError(t, RegisterQuery[countQuery, int](bus, QueryHandlerFunc[countQuery, int](func(ctx context.Context, q countQuery) (int, error) {
+		return q.N, nil
+	})))
+	futures := make([]Future[int], 0, 3)
+	for i := 1; i <= 3; i++ {
+		f, err := AsyncQuery[countQuery, int](context.Background(), bus, countQuery{N: i})
+		assert.NoError(t, err)
+		futures = append(futures, f)
+	}
+	res, err := All(futures...).Get(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3}, res)
+}
+
+func TestAsyncQuery_Dropped(t *testing.T) {
+	pool := workerpool.New(1, 1, workerpool.Overflow(workerpool.DropOldest))
+	defer pool.Close(context.Background())
+	bus := NewBus(Pool(pool))
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	assert.NoError(t, RegisterQuery[countQuery, int](bus, QueryHandlerFunc[countQuery, int](func(ctx context.Context, q countQuery) (int, error) {
+		if q.N == 1 {
+			started <- struct{}{}
+			<-release
+		}
+		return q.N, nil
+	})))
+	running, err := AsyncQuery[countQuery, int](context.Background(), bus, countQuery{N: 1})
+	assert.NoError(t, err)
+	<-started
+	dropped, err := AsyncQuery[countQuery, int](context.Background(), bus, countQuery{N: 2})
+	assert.NoError(t, err)
+	queued, err := AsyncQuery[countQuery, int](context.Background(), bus, countQuery{N: 3})
+	assert.NoError(t, err)
+
+	_, err = dropped.Get(context.Background())
+	assert.ErrorIs(t, err, workerpool.ErrDropped)
+	close(release)
+	res, err := All(running, queued).Get(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 3}, res)
+	// the dropped query is not in flight anymore
+	assert.NoError(t, bus.Close(context.Background()))
+}
@@ -0,0 +1,393 @@
+package cqrs
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/go-leo/design-pattern/event"
+)
+
+// Register registers a CommandHandler to the bus.
+// Unlike Bus.RegisterCommand, the shape of handler is checked at compile time.
+// Args must be a concrete type, since Bus.Exec looks up the handler by the dynamic type of arguments,
+// ErrArgsInterface is returned if it is an interface. So are the other Register and Replace functions.
+func Register[Args any](bus Bus, handler CommandHandler[Args]) error {
+	b, ok := bus.(*defaultBus)
+	if !ok {
+		return bus.RegisterCommand(handler)
+	}
+	if err := b.checkHandler(handler); err != nil {
+		return err
+	}
+	return b.register(&commandHandler[Args]{handler: handler, inType: typeOf[Args]()})
+}
+
+// Replace registers a CommandHandler to the bus, replaces the registered one that handles Args.
+func Replace[Args any](bus Bus, handler CommandHandler[Args]) error {
+	b, ok := bus.(*defaultBus)
+	if !ok {
+		return bus.ReplaceCommand(handler)
+	}
+	if err := b.checkHandler(handler); err != nil {
+		return err
+	}
+	return b.replace(&commandHandler[Args]{handler: handler, inType: typeOf[Args]()})
+}
+
+// RegisterResult registers a CommandResultHandler to the bus.
+func RegisterResult[Args any, Result any](bus Bus, handler CommandResultHandler[Args, Result]) error {
+	b, ok := bus.(*defaultBus)
+	if !ok {
+		return bus.RegisterCommand(handler)
+	}
+	if err := b.checkHandler(handler); err != nil {
+		return err
+	}
+	return b.register(&commandResultHandler[Args, Result]{handler: handler, inType: typeOf[Args]()})
+}
+
+// RegisterQuery registers a QueryHandler to the bus.
+// Unlike Bus.RegisterQuery, the shape of handler is checked at compile time.
+func RegisterQuery[Args any, Result any](bus Bus, handler QueryHandler[Args, Result]) error {
+	b, ok := bus.(*defaultBus)
+	if !ok {
+		return bus.RegisterQuery(handler)
+	}
+	if err := b.checkHandler(handler); err != nil {
+		return err
+	}
+	return b.register(&queryHandler[Args, Result]{handler: handler, inType: typeOf[Args]()})
+}
+
+// ReplaceQuery registers a QueryHandler to the bus, replaces the registered one that handles Args.
+func ReplaceQuery[Args any, Result any](bus Bus, handler QueryHandler[Args, Result]) error {
+	b, ok := bus.(*defaultBus)
+	if !ok {
+		return bus.ReplaceQuery(handler)
+	}
+	if err := b.checkHandler(handler); err != nil {
+		return err
+	}
+	return b.replace(&queryHandler[Args, Result]{handler: handler, inType: typeOf[Args]()})
+}
+
+// RegisterStreamQuery registers a StreamQueryHandler to the bus.
+func RegisterStreamQuery[Args any, Result any](bus Bus, handler StreamQueryHandler[Args, Result]) error {
+	b, ok := bus.(*defaultBus)
+	if !ok {
+		return bus.RegisterQuery(handler)
+	}
+	if err := b.checkHandler(handler); err != nil {
+		return err
+	}
+	return b.register(&streamQueryHandler[Args, Result]{handler: handler, inType: typeOf[Args]()})
+}
+
+// Exec synchronously executes a command.
+// If the CommandHandler was registered by Register, it is called without reflection.
+func Exec[Args any](ctx context.Context, bus Bus, args Args) error {
+	b, ok := bus.(*defaultBus)
+	if !ok {
+		return bus.Exec(ctx, args)
+	}
+	if err := b.checkArgs(args); err != nil {
+		return err
+	}
+	h, err := b.loadHandler(typeOf[Args](), CommandKind)
+	if err != nil {
+		return err
+	}
+	ctx, done, err := b.enter(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+	_, err = b.exec(ctx, h, args)
+	return err
+}
+
+// ExecResult synchronously executes a command and returns the typed result of the CommandResultHandler.
+// For a Bus other than the one returned by NewBus, the result is got by AsyncExec, and it returns an error
+// that wraps ErrResultType if the result is nil or not a Result, e.g. the remote bus doesn't carry the results.
+func ExecResult[Args any, Result any](ctx context.Context, bus Bus, args Args) (Result, error) {
+	b, ok := bus.(*defaultBus)
+	if !ok {
+		f, err := bus.AsyncExec(ctx, args)
+		if err != nil {
+			return *(new(Result)), err
+		}
+		res, err := f.Get(ctx)
+		if err != nil {
+			return *(new(Result)), err
+		}
+		// a bus that doesn't carry the results, e.g. the remote bus, resolves the futures of commands with nil.
+		if res == nil {
+			return *(new(Result)), fmt.Errorf("%w: %T returns no result of command", ErrResultType, bus)
+		}
+		return assertResult[Result](res)
+	}
+	if err := b.checkArgs(args); err != nil {
+		return *(new(Result)), err
+	}
+	h, err := b.loadHandler(typeOf[Args](), CommandKind)
+	if err != nil {
+		return *(new(Result)), err
+	}
+	ctx, done, err := b.enter(ctx)
+	if err != nil {
+		return *(new(Result)), err
+	}
+	defer done()
+	res, err := b.exec(ctx, h, args)
+	if err != nil {
+		return *(new(Result)), err
+	}
+	return assertResult[Result](res)
+}
+
+// Query synchronously executes a query and returns the typed result.
+// If the QueryHandler was registered by RegisterQuery, it is called without reflection.
+func Query[Args any, Result any](ctx context.Context, bus Bus, args Args) (Result, error) {
+	b, ok := bus.(*defaultBus)
+	if !ok {
+		res, err := bus.Query(ctx, args)
+		if err != nil {
+			return *(new(Result)), err
+		}
+		return assertResult[Result](res)
+	}
+	if err := b.checkArgs(args); err != nil {
+		return *(new(Result)), err
+	}
+	h, err := b.loadHandler(typeOf[Args](), QueryKind)
+	if err != nil {
+		return *(new(Result)), err
+	}
+	ctx, done, err := b.enter(ctx)
+	if err != nil {
+		return *(new(Result)), err
+	}
+	defer done()
+	res, err := b.query(ctx, h, args)
+	if err != nil {
+		return *(new(Result)), err
+	}
+	return assertResult[Result](res)
+}
+
+// QueryStream executes a streaming query and returns the Stream of typed results.
+func QueryStream[Args any, Result any](ctx context.Context, bus Bus, args Args) (Stream[Result], error) {
+	b, ok := bus.(*defaultBus)
+	if !ok {
+		stream, err := bus.QueryStream(ctx, args)
+		if err != nil {
+			return nil, err
+		}
+		return MapStream(stream, assertResult[Result]), nil
+	}
+	if err := b.checkArgs(args); err != nil {
+		return nil, err
+	}
+	h, err := b.loadHandler(typeOf[Args](), StreamQueryKind)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := b.stream(ctx, h, args)
+	if err != nil {
+		return nil, err
+	}
+	return MapStream(stream, assertResult[Result]), nil
+}
+
+func assertResult[Result any](res any) (Result, error) {
+	if res == nil {
+		return *(new(Result)), nil
+	}
+	result, ok := res.(Result)
+	if !ok {
+		return *(new(Result)), ErrResultType
+	}
+	return result, nil
+}
+
+func typeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+type commandHandler[Args any] struct {
+	handler CommandHandler[Args]
+	inType  reflect.Type
+}
+
+func (h *commandHandler[Args]) Exec(ctx context.Context, args any) (any, []event.Event, error) {
+	return nil, nil, h.handler.Handle(ctx, args.(Args))
+}
+
+func (h *commandHandler[Args]) Query(ctx context.Context, args any) (any, error) {
+	res, _, err := h.Exec(ctx, args)
+	return res, err
+}
+
+func (h *commandHandler[Args]) Stream(ctx context.Context, args any, send func(any) error) error {
+	return h.handler.Handle(ctx, args.(Args))
+}
+
+func (h *commandHandler[Args]) InType() reflect.Type {
+	return h.inType
+}
+
+func (h *commandHandler[Args]) Kind() Kind {
+	return CommandKind
+}
+
+func (h *commandHandler[Args]) HandlerType() reflect.Type {
+	return reflect.TypeOf(h.handler)
+}
+
+type commandResultHandler[Args any, Result any] struct {
+	handler CommandResultHandler[Args, Result]
+	inType  reflect.Type
+}
+
+func (h *commandResultHandler[Args, Result]) Exec(ctx context.Context, args any) (any, []event.Event, error) {
+	return h.handler.Handle(ctx, args.(Args))
+}
+
+func (h *commandResultHandler[Args, Result]) Query(ctx context.Context, args any) (any, error) {
+	res, _, err := h.Exec(ctx, args)
+	return res, err
+}
+
+func (h *commandResultHandler[Args, Result]) Stream(ctx context.Context, args any, send func(any) error) error {
+	res, err := h.Query(ctx, args)
+	if err != nil {
+		return err
+	}
+	return send(res)
+}
+
+func (h *commandResultHandler[Args, Result]) InType() reflect.Type {
+	return h.inType
+}
+
+func (h *commandResultHandler[Args, Result]) Kind() Kind {
+	return CommandKind
+}
+
+func (h *commandResultHandler[Args, Result]) HandlerType() reflect.Type {
+	return reflect.TypeOf(h.handler)
+}
+
+type queryHandler[Args any, Result any] struct {
+	handler QueryHandler[Args, Result]
+	inType  reflect.Type
+}
+
+func (h *queryHandler[Args, Result]) Exec(ctx context.Context, args any) (any, []event.Event, error) {
+	res, err := h.Query(ctx, args)
+	return res, nil, err
+}
+
+func (h *queryHandler[Args, Result]) Query(ctx context.Context, args any) (any, error) {
+	return h.handler.Handle(ctx, args.(Args))
+}
+
+func (h *queryHandler[Args, Result]) Stream(ctx context.Context, args any, send func(any) error) error {
+	res, err := h.Query(ctx, args)
+	if err != nil {
+		return err
+	}
+	return send(res)
+}
+
+func (h *queryHandler[Args, Result]) InType() reflect.Type {
+	return h.inType
+}
+
+func (h *queryHandler[Args, Result]) Kind() Kind {
+	return QueryKind
+}
+
+func (h *queryHandler[Args, Result]) HandlerType() reflect.Type {
+	return reflect.TypeOf(h.handler)
+}
+
+type streamQueryHandler[Args any, Result any] struct {
+	handler StreamQueryHandler[Args, Result]
+	inType  reflect.Type
+}
+
+// Exec returns ErrUnregistered, a StreamQueryHandler only handles streaming queries.
+func (h *streamQueryHandler[Args, Result]) Exec(context.Context, any) (any, []event.Event, error) {
+	return nil, nil, ErrUnregistered
+}
+
+// Query returns ErrUnregistered, a StreamQueryHandler only handles streaming queries.
+func (h *streamQueryHandler[Args, Result]) Query(context.Context, any) (any, error) {
+	return nil, ErrUnregistered
+}
+
+func (h *streamQueryHandler[Args, Result]) Stream(ctx context.Context, args any, send func(any) error) error {
+	return h.handler.Handle(ctx, args.(Args), func(res Result) error {
+		return send(res)
+	})
+}
+
+func (h *streamQueryHandler[Args, Result]) InType() reflect.Type {
+	return h.inType
+}
+
+func (h *streamQueryHandler[Args, Result]) Kind() Kind {
+	return StreamQueryKind
+}
+
+func (h *streamQueryHandler[Args, Result]) HandlerType() reflect.Type {
+	return reflect.TypeOf(h.handler)
+}
+
+// AsyncExec asynchronously executes a command, result in Future.
+func AsyncExec[Args any](ctx context.Context, bus Bus, args Args) (Future[any], error) {
+	b, ok := bus.(*defaultBus)
+	if !ok {
+		return bus.AsyncExec(ctx, args)
+	}
+	if err := b.checkArgs(args); err != nil {
+		return nil, err
+	}
+	h, err := b.loadHandler(typeOf[Args](), CommandKind)
+	if err != nil {
+		return nil, err
+	}
+	return async(ctx, b, func(ctx context.Context) (any, error) {
+		return b.exec(ctx, h, args)
+	})
+}
+
+// AsyncQuery asynchronously executes a query, typed result in Future.
+func AsyncQuery[Args any, Result any](ctx context.Context, bus Bus, args Args) (Future[Result], error) {
+	b, ok := bus.(*defaultBus)
+	if !ok {
+		f, err := bus.AsyncQuery(ctx, args)
+		if err != nil {
+			return nil, err
+		}
+		return Then(f, func(_ context.Context, res any) (Result, error) {
+			return assertResult[Result](res)
+		}), nil
+	}
+	if err := b.checkArgs(args); err != nil {
+		return nil, err
+	}
+	h, err := b.loadHandler(typeOf[Args](), QueryKind)
+	if err != nil {
+		return nil, err
+	}
+	return async(ctx, b, func(ctx context.Context) (Result, error) {
+		res, err := b.query(ctx, h, args)
+		if err != nil {
+			return *(new(Result)), err
+		}
+		return assertResult[Result](res)
+	})
+}
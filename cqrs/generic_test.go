@@ -0,0 +1,103 @@
+package cqrs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type renameCmd struct {
+	Name string
+}
+
+type countQuery struct {
+	N int
+}
+
+var errEmptyName = errors.New("empty name")
+
+func TestGeneric(t *testing.T) {
+	bus := NewBus()
+	var renamed string
+
+	err := Register[*renameCmd](bus, CommandHandlerFunc[*renameCmd](func(ctx context.Context, cmd *renameCmd) error {
+		if cmd.Name == "" {
+			return errEmptyName
+		}
+		renamed = cmd.Name
+		return nil
+	}))
+	assert.NoError(t, err)
+
+	err = Register[*renameCmd](bus, NoopCommand[*renameCmd]{})
+	assert.ErrorIs(t, err, ErrRegistered)
+
+	err = Register[*renameCmd](bus, nil)
+	assert.ErrorIs(t, err, ErrHandlerNil)
+
+	// the handler of an interface can't be found by the dynamic type of arguments
+	err = Register[fmt.Stringer](bus, NoopCommand[fmt.Stringer]{})
+	assert.ErrorIs(t, err, ErrArgsInterface)
+	err = ReplaceQuery[any, int](bus, QueryHandlerFunc[any, int](func(ctx context.Context, q any) (int, error) {
+		return 0, nil
+	}))
+	assert.ErrorIs(t, err, ErrArgsInterface)
+
+	err = RegisterQuery[countQuery, int](bus, QueryHandlerFunc[countQuery, int](func(ctx context.Context, q countQuery) (int, error) {
+		return q.N * 2, nil
+	}))
+	assert.NoError(t, err)
+
+	err = Exec(context.Background(), bus, &renameCmd{Name: "jax"})
+	assert.NoError(t, err)
+	assert.Equal(t, "jax", renamed)
+
+	err = Exec(context.Background(), bus, &renameCmd{})
+	assert.ErrorIs(t, err, errEmptyName)
+
+	err = Exec(context.Background(), bus, renameCmd{})
+	assert.ErrorIs(t, err, ErrUnregistered)
+
+	n, err := Query[countQuery, int](context.Background(), bus, countQuery{N: 21})
+	assert.NoError(t, err)
+	assert.Equal(t, 42, n)
+
+	_, err = Query[countQuery, string](context.Background(), bus, countQuery{N: 21})
+	assert.ErrorIs(t, err, ErrResultType)
+
+	// the any-based methods still work with typed handlers
+	err = bus.Exec(context.Background(), &renameCmd{Name: "leo"})
+	assert.NoError(t, err)
+	assert.Equal(t, "leo", renamed)
+
+	res, err := bus.Query(context.Background(), countQuery{N: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, res)
+
+	// typed dispatch still works with reflected handlers
+	err = bus.RegisterQuery(&student{})
+	assert.NoError(t, err)
+	stu, err := Query[*studentQuery, *studentResult](context.Background(), bus, &studentQuery{Id: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, &studentResult{Name: "jax"}, stu)
+}
+
+func BenchmarkExec(b *testing.B) {
+	bus := NewBus()
+	_ = Register[*renameCmd](bus, NoopCommand[*renameCmd]{})
+	_ = bus.RegisterCommand(NoopCommand[renameCmd]{})
+	ctx := context.Background()
+	b.Run("generic", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = Exec(ctx, bus, &renameCmd{})
+		}
+	})
+	b.Run("reflect", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = bus.Exec(ctx, renameCmd{})
+		}
+	})
+}
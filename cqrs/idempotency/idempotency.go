@@ -0,0 +1,180 @@
+// Package idempotency deduplicates the commands that are retried by clients.
+//
+// A command carries an idempotency key by implementing Keyed, or by the context returned by WithKey.
+// Interceptor records the outcome of the first execution of a key in a Store,
+// and replays it for the duplicates without executing the command again.
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"time"
+
+	"github.com/go-leo/design-pattern/cqrs"
+	"github.com/go-leo/design-pattern/event"
+	"github.com/go-leo/design-pattern/middleware"
+)
+
+var (
+	// ErrInProgress the command with the same idempotency key is being executed, it is a cqrs.ErrConflict.
+	ErrInProgress = cqrs.NewError(cqrs.CodeConflict, "idempotency: command with the same key is in progress", nil)
+
+	// ErrLeaseLost the key is not reserved by the execution any more, e.g. its lease expired and another
+	// execution reserved the key, so the outcome of the execution is not recorded.
+	ErrLeaseLost = errors.New("idempotency: reservation of key was lost")
+)
+
+// Keyed is implemented by the commands that carry an idempotency key.
+type Keyed interface {
+	IdempotencyKey() string
+}
+
+type keyKey struct{}
+
+// WithKey returns a copy of ctx that carries the idempotency key of the command executed with it.
+// The key is not passed to the nested commands executed by the handler.
+func WithKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, keyKey{}, key)
+}
+
+// Key returns the idempotency key of args, the key carried by ctx takes precedence over Keyed.
+func Key(ctx context.Context, args any) (string, bool) {
+	if key, _ := ctx.Value(keyKey{}).(string); key != "" {
+		return key, true
+	}
+	if keyed, ok := args.(Keyed); ok {
+		if key := keyed.IdempotencyKey(); key != "" {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+type option struct {
+	TTL      time.Duration
+	Lease    time.Duration
+	Wait     time.Duration
+	Remember func(err error) bool
+}
+
+func newOption(opts ...Option) *option {
+	o := &option{
+		TTL:      24 * time.Hour,
+		Lease:    time.Minute,
+		Remember: Definite,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+type Option func(*option)
+
+// TTL sets how long the outcomes are remembered, default is 24h.
+func TTL(ttl time.Duration) Option {
+	return func(o *option) {
+		o.TTL = ttl
+	}
+}
+
+// Lease sets how long a key is reserved by an execution, default is 1m.
+// If the process crashed during the execution, the key can be executed again after the lease expires.
+func Lease(lease time.Duration) Option {
+	return func(o *option) {
+		o.Lease = lease
+	}
+}
+
+// Wait makes the duplicates wait for the in-progress execution, and checks its outcome every interval,
+// by default the duplicates fail with ErrInProgress immediately.
+func Wait(interval time.Duration) Option {
+	return func(o *option) {
+		o.Wait = interval
+	}
+}
+
+// Remember sets the predicate that reports whether an error is remembered and replayed,
+// the key of the errors that are not remembered is released, so that the command can be retried.
+// Default is Definite.
+func Remember(remember func(err error) bool) Option {
+	return func(o *option) {
+		o.Remember = remember
+	}
+}
+
+// Definite reports whether err is a definite outcome of the command, that is an error classified as
+// cqrs.ErrNotFound, cqrs.ErrConflict, cqrs.ErrValidation or cqrs.ErrUnauthorized.
+// The unclassified and transient errors may be fixed by a retry.
+func Definite(err error) bool {
+	switch cqrs.CodeOf(err) {
+	case cqrs.CodeNotFound, cqrs.CodeConflict, cqrs.CodeValidation, cqrs.CodeUnauthorized:
+		return true
+	default:
+		return false
+	}
+}
+
+// Interceptor returns a cqrs.Interceptor that deduplicates the commands with idempotency key by store.
+// The keys are scoped by the type name of command, that is event.TypeName, so it is unique across packages.
+// The commands without key are executed as usual.
+func Interceptor(store Store, opts ...Option) cqrs.Interceptor {
+	o := newOption(opts...)
+	return func(ctx context.Context, req any, invoker middleware.Invoker[any, any]) (any, error) {
+		key, ok := Key(ctx, req)
+		if !ok {
+			return invoker(ctx, req)
+		}
+		key = event.TypeName(reflect.TypeOf(req)) + ":" + key
+		token, outcome, done, err := begin(ctx, store, key, o)
+		if err != nil {
+			return nil, err
+		}
+		if done {
+			return outcome.Result, outcome.Err
+		}
+		return execute(WithKey(ctx, ""), store, key, token, req, invoker, o)
+	}
+}
+
+// begin reserves the key, or returns the remembered outcome.
+func begin(ctx context.Context, store Store, key string, o *option) (string, Outcome, bool, error) {
+	for {
+		token, outcome, done, err := store.Begin(ctx, key, o.Lease)
+		if !errors.Is(err, ErrInProgress) || o.Wait <= 0 {
+			return token, outcome, done, err
+		}
+		timer := time.NewTimer(o.Wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return "", Outcome{}, false, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// execute executes the command, and records its outcome.
+// The outcome is recorded even if ctx is canceled, and the key is released if the handler panics or fails with
+// an error that is not remembered. If the outcome can't be recorded, the key is kept reserved until the lease
+// expires, rather than released, since the command may have taken effect, and the error of store is returned.
+func execute(ctx context.Context, store Store, key string, token string, req any, invoker middleware.Invoker[any, any], o *option) (res any, err error) {
+	storeCtx := context.WithoutCancel(ctx)
+	returned := false
+	defer func() {
+		if !returned {
+			_ = store.Release(storeCtx, key, token)
+		}
+	}()
+	res, err = invoker(ctx, req)
+	returned = true
+	if err != nil && !o.Remember(err) {
+		_ = store.Release(storeCtx, key, token)
+		return res, err
+	}
+	if completeErr := store.Complete(storeCtx, key, token, Outcome{Result: res, Err: err}, o.TTL); completeErr != nil {
+		return res, errors.Join(err, completeErr)
+	}
+	return res, err
+}
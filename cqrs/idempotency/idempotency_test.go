@@ -0,0 +1,200 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-leo/design-pattern/cqrs"
+	"github.com/go-leo/design-pattern/event"
+	"github.com/stretchr/testify/assert"
+)
+
+type chargeCmd struct {
+	RequestID string
+	Amount    int
+}
+
+func (cmd chargeCmd) IdempotencyKey() string {
+	return cmd.RequestID
+}
+
+type notifyCmd struct{}
+
+func newChargeBus(t *testing.T, store Store, calls *atomic.Int32, release <-chan struct{}, opts ...Option) cqrs.Bus {
+	bus := cqrs.NewBus(cqrs.CommandInterceptors(Interceptor(store, opts...)))
+	assert.NoError(t, cqrs.RegisterResult[chargeCmd, int](bus, cqrs.CommandResultHandlerFunc[chargeCmd, int](func(ctx context.Context, cmd chargeCmd) (int, []event.Event, error) {
+		n := calls.Add(1)
+		if release != nil {
+			<-release
+		}
+		// the nested command doesn't inherit the key
+		_, ok := Key(ctx, notifyCmd{})
+		assert.False(t, ok)
+		switch {
+		case cmd.Amount < 0:
+			return 0, nil, cqrs.Validation("negative amount")
+		case cmd.Amount == 0:
+			return 0, nil, cqrs.Transient(errors.New("gateway unavailable"))
+		case cmd.Amount > 1000:
+			panic("too much")
+		}
+		return int(n), nil, nil
+	})))
+	return bus
+}
+
+func TestInterceptor(t *testing.T) {
+	var calls atomic.Int32
+	store := NewMemoryStore()
+	bus := newChargeBus(t, store, &calls, nil)
+	ctx := context.Background()
+
+	// replays the result
+	for i := 0; i < 3; i++ {
+		n, err := cqrs.ExecResult[chargeCmd, int](ctx, bus, chargeCmd{RequestID: "a", Amount: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, n)
+	}
+	assert.Equal(t, int32(1), calls.Load())
+
+	// the key of ctx takes precedence
+	n, err := cqrs.ExecResult[chargeCmd, int](WithKey(ctx, "b"), bus, chargeCmd{RequestID: "a", Amount: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	// commands without key are not deduplicated
+	for i := 0; i < 2; i++ {
+		_, err = cqrs.ExecResult[chargeCmd, int](ctx, bus, chargeCmd{Amount: 10})
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(4), calls.Load())
+
+	// replays the definite errors
+	for i := 0; i < 2; i++ {
+		_, err = cqrs.ExecResult[chargeCmd, int](ctx, bus, chargeCmd{RequestID: "c", Amount: -1})
+		assert.ErrorIs(t, err, cqrs.ErrValidation)
+	}
+	assert.Equal(t, int32(5), calls.Load())
+
+	// retries the transient errors and panics
+	for i := 0; i < 2; i++ {
+		_, err = cqrs.ExecResult[chargeCmd, int](ctx, bus, chargeCmd{RequestID: "d", Amount: 0})
+		assert.ErrorIs(t, err, cqrs.ErrTransient)
+		_, err = cqrs.ExecResult[chargeCmd, int](ctx, bus, chargeCmd{RequestID: "e", Amount: 1001})
+		var panicErr *cqrs.PanicError
+		assert.ErrorAs(t, err, &panicErr)
+	}
+	assert.Equal(t, int32(9), calls.Load())
+
+	// the keys are scoped by the package-qualified type of command
+	_, outcome, done, err := store.Begin(ctx, "github.com/go-leo/design-pattern/cqrs/idempotency.chargeCmd:a", time.Second)
+	assert.NoError(t, err)
+	assert.True(t, done)
+	assert.Equal(t, 1, outcome.Result)
+}
+
+func TestInterceptor_Concurrent(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	bus := newChargeBus(t, NewMemoryStore(), &calls, release)
+	ctx := context.Background()
+
+	done := make(chan error)
+	go func() {
+		_, err := cqrs.ExecResult[chargeCmd, int](ctx, bus, chargeCmd{RequestID: "a", Amount: 10})
+		done <- err
+	}()
+	assert.Eventually(t, func() bool { return calls.Load() == 1 }, time.Second, time.Millisecond)
+	_, err := cqrs.ExecResult[chargeCmd, int](ctx, bus, chargeCmd{RequestID: "a", Amount: 10})
+	assert.ErrorIs(t, err, ErrInProgress)
+	assert.ErrorIs(t, err, cqrs.ErrConflict)
+	close(release)
+	assert.NoError(t, <-done)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestInterceptor_Wait(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	bus := newChargeBus(t, NewMemoryStore(), &calls, release, Wait(time.Millisecond))
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	results := make([]int, 5)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			n, err := cqrs.ExecResult[chargeCmd, int](ctx, bus, chargeCmd{RequestID: "a", Amount: 10})
+			assert.NoError(t, err)
+			results[i] = n
+		}(i)
+	}
+	assert.Eventually(t, func() bool { return calls.Load() == 1 }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, []int{1, 1, 1, 1, 1}, results)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+// failingStore is a Store that fails to complete.
+type failingStore struct {
+	*MemoryStore
+}
+
+func (s failingStore) Complete(context.Context, string, string, Outcome, time.Duration) error {
+	return errors.New("store unavailable")
+}
+
+func TestInterceptor_CompleteFailed(t *testing.T) {
+	var calls atomic.Int32
+	bus := newChargeBus(t, failingStore{MemoryStore: NewMemoryStore()}, &calls, nil)
+	ctx := context.Background()
+
+	_, err := cqrs.ExecResult[chargeCmd, int](ctx, bus, chargeCmd{RequestID: "a", Amount: 10})
+	assert.ErrorContains(t, err, "store unavailable")
+	// the key is kept reserved, the command is not executed again until the lease expires
+	_, err = cqrs.ExecResult[chargeCmd, int](ctx, bus, chargeCmd{RequestID: "a", Amount: 10})
+	assert.ErrorIs(t, err, ErrInProgress)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestMemoryStore_Expire(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+
+	first, _, done, err := store.Begin(ctx, "a", time.Second)
+	assert.NoError(t, err)
+	assert.False(t, done)
+	_, _, _, err = store.Begin(ctx, "a", time.Second)
+	assert.ErrorIs(t, err, ErrInProgress)
+
+	// the lease expired, the key is reserved by second, and first can't complete or release it
+	now = now.Add(time.Second)
+	second, _, done, err := store.Begin(ctx, "a", time.Second)
+	assert.NoError(t, err)
+	assert.False(t, done)
+	assert.ErrorIs(t, store.Complete(ctx, "a", first, Outcome{Result: 0}, time.Hour), ErrLeaseLost)
+	assert.NoError(t, store.Release(ctx, "a", first))
+	_, _, _, err = store.Begin(ctx, "a", time.Second)
+	assert.ErrorIs(t, err, ErrInProgress)
+	assert.NoError(t, store.Complete(ctx, "a", second, Outcome{Result: 1}, time.Hour))
+	_, outcome, done, err := store.Begin(ctx, "a", time.Second)
+	assert.NoError(t, err)
+	assert.True(t, done)
+	assert.Equal(t, 1, outcome.Result)
+
+	// the outcome expired
+	now = now.Add(time.Hour)
+	_, _, done, err = store.Begin(ctx, "b", time.Second)
+	assert.NoError(t, err)
+	assert.False(t, done)
+	assert.Equal(t, 1, store.Len())
+}
@@ -0,0 +1,35 @@
+// Package remotecodec encodes the results of commands by remote.Registry, e.g. for idempotency.SQLStore.
+package remotecodec
+
+import (
+	"github.com/go-leo/design-pattern/cqrs/remote"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// Codec encodes the results as protobuf Any by a remote.Registry,
+// so the types of results must be protobuf messages or be registered.
+type Codec struct {
+	registry *remote.Registry
+}
+
+// New returns a Codec that encodes the results by registry.
+func New(registry *remote.Registry) *Codec {
+	return &Codec{registry: registry}
+}
+
+func (c *Codec) Marshal(v any) ([]byte, error) {
+	a, err := c.registry.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(a)
+}
+
+func (c *Codec) Unmarshal(data []byte) (any, error) {
+	a := &anypb.Any{}
+	if err := proto.Unmarshal(data, a); err != nil {
+		return nil, err
+	}
+	return c.registry.Unmarshal(a)
+}
@@ -0,0 +1,183 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-leo/design-pattern/cqrs"
+	"github.com/google/uuid"
+)
+
+var _ Store = (*SQLStore)(nil)
+
+// Codec encodes the results of commands for SQLStore, e.g. remotecodec.Codec encodes them by remote.Registry.
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte) (any, error)
+}
+
+// ReplayedError is the error replayed by SQLStore, it keeps the message and the cqrs.ErrorCode of the original error,
+// and matches the sentinel error of the code, e.g. cqrs.ErrConflict, by errors.Is.
+type ReplayedError struct {
+	Code    cqrs.ErrorCode
+	Message string
+}
+
+func (e *ReplayedError) Error() string {
+	return e.Message
+}
+
+func (e *ReplayedError) Unwrap() error {
+	return &cqrs.Error{Code: e.Code}
+}
+
+// SQLStore is a Store based on database/sql, the outcomes are stored in a table like:
+//
+//	CREATE TABLE idempotency_keys (
+//		idempotency_key VARCHAR(255) PRIMARY KEY,
+//		token           VARCHAR(64) NOT NULL,
+//		done            BOOLEAN NOT NULL,
+//		result          BLOB,
+//		error_code      INTEGER,
+//		error_message   TEXT,
+//		expire_at       TIMESTAMP NOT NULL
+//	)
+//
+// The primary key makes the concurrent reservations of a key exclusive,
+// and the token makes only the owner of a reservation able to complete or release it.
+// The results are encoded by Codec, and the errors are replayed as ReplayedError.
+type SQLStore struct {
+	db      *sql.DB
+	codec   Codec
+	options *sqlOption
+	now     func() time.Time
+
+	deleteExpired string
+	insert        string
+	selectKey     string
+	update        string
+	release       string
+}
+
+type sqlOption struct {
+	Table       string
+	Placeholder func(i int) string
+}
+
+type SQLOption func(o *sqlOption)
+
+// SQLTable sets the name of table, default is "idempotency_keys".
+func SQLTable(table string) SQLOption {
+	return func(o *sqlOption) {
+		o.Table = table
+	}
+}
+
+// SQLPlaceholder sets the function that returns the i-th placeholder of statements, i starts from 1,
+// default is "?", e.g. PostgreSQL uses "$1", "$2"...
+func SQLPlaceholder(placeholder func(i int) string) SQLOption {
+	return func(o *sqlOption) {
+		o.Placeholder = placeholder
+	}
+}
+
+// NewSQLStore returns a SQLStore that stores the outcomes by db, and encodes the results by codec.
+func NewSQLStore(db *sql.DB, codec Codec, opts ...SQLOption) *SQLStore {
+	o := &sqlOption{
+		Table:       "idempotency_keys",
+		Placeholder: func(int) string { return "?" },
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	p := o.Placeholder
+	return &SQLStore{
+		db:            db,
+		codec:         codec,
+		options:       o,
+		now:           time.Now,
+		deleteExpired: fmt.Sprintf("DELETE FROM %s WHERE idempotency_key = %s AND expire_at <= %s", o.Table, p(1), p(2)),
+		insert:        fmt.Sprintf("INSERT INTO %s (idempotency_key, token, done, expire_at) VALUES (%s, %s, %s, %s)", o.Table, p(1), p(2), p(3), p(4)),
+		selectKey:     fmt.Sprintf("SELECT done, result, error_code, error_message FROM %s WHERE idempotency_key = %s", o.Table, p(1)),
+		update: fmt.Sprintf("UPDATE %s SET done = %s, result = %s, error_code = %s, error_message = %s, expire_at = %s WHERE idempotency_key = %s AND token = %s AND done = %s",
+			o.Table, p(1), p(2), p(3), p(4), p(5), p(6), p(7), p(8)),
+		release: fmt.Sprintf("DELETE FROM %s WHERE idempotency_key = %s AND token = %s AND done = %s", o.Table, p(1), p(2), p(3)),
+	}
+}
+
+func (s *SQLStore) Begin(ctx context.Context, key string, lease time.Duration) (string, Outcome, bool, error) {
+	now := s.now()
+	if _, err := s.db.ExecContext(ctx, s.deleteExpired, key, now); err != nil {
+		return "", Outcome{}, false, err
+	}
+	token := uuid.NewString()
+	_, insertErr := s.db.ExecContext(ctx, s.insert, key, token, false, now.Add(lease))
+	if insertErr == nil {
+		return token, Outcome{}, false, nil
+	}
+	// the insert failed, most likely because the key exists.
+	var (
+		done    bool
+		result  []byte
+		code    sql.NullInt64
+		message sql.NullString
+	)
+	err := s.db.QueryRowContext(ctx, s.selectKey, key).Scan(&done, &result, &code, &message)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", Outcome{}, false, insertErr
+	}
+	if err != nil {
+		return "", Outcome{}, false, err
+	}
+	if !done {
+		return "", Outcome{}, false, ErrInProgress
+	}
+	var outcome Outcome
+	if result != nil {
+		if outcome.Result, err = s.codec.Unmarshal(result); err != nil {
+			return "", Outcome{}, false, err
+		}
+	}
+	if code.Valid {
+		outcome.Err = &ReplayedError{Code: cqrs.ErrorCode(code.Int64), Message: message.String}
+	}
+	return "", outcome, true, nil
+}
+
+func (s *SQLStore) Complete(ctx context.Context, key string, token string, outcome Outcome, ttl time.Duration) error {
+	var (
+		result  []byte
+		code    sql.NullInt64
+		message sql.NullString
+	)
+	if outcome.Result != nil {
+		var err error
+		if result, err = s.codec.Marshal(outcome.Result); err != nil {
+			return err
+		}
+	}
+	if outcome.Err != nil {
+		code = sql.NullInt64{Int64: int64(cqrs.CodeOf(outcome.Err)), Valid: true}
+		message = sql.NullString{String: outcome.Err.Error(), Valid: true}
+	}
+	r, err := s.db.ExecContext(ctx, s.update, true, result, code, message, s.now().Add(ttl), key, token, false)
+	if err != nil {
+		return err
+	}
+	n, err := r.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+func (s *SQLStore) Release(ctx context.Context, key string, token string) error {
+	_, err := s.db.ExecContext(ctx, s.release, key, token, false)
+	return err
+}
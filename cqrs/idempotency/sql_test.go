@@ -0,0 +1,197 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-leo/design-pattern/cqrs"
+	"github.com/go-leo/design-pattern/cqrs/idempotency/remotecodec"
+	"github.com/go-leo/design-pattern/cqrs/remote"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+var table = &tableDriver{}
+
+func init() {
+	sql.Register("idempotency-table", table)
+}
+
+func newSQLStore(t *testing.T) *SQLStore {
+	table.reset()
+	db, err := sql.Open("idempotency-table", "")
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	registry, err := remote.NewRegistry()
+	assert.NoError(t, err)
+	return NewSQLStore(db, remotecodec.New(registry), SQLTable("keys"), SQLPlaceholder(func(i int) string { return "?" }))
+}
+
+func TestSQLStore(t *testing.T) {
+	store := newSQLStore(t)
+	ctx := context.Background()
+
+	token, _, done, err := store.Begin(ctx, "a", time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, done)
+	_, _, _, err = store.Begin(ctx, "a", time.Minute)
+	assert.ErrorIs(t, err, ErrInProgress)
+
+	assert.NoError(t, store.Complete(ctx, "a", token, Outcome{Result: wrapperspb.Int64(42)}, time.Hour))
+	_, outcome, done, err := store.Begin(ctx, "a", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, done)
+	assert.Equal(t, int64(42), outcome.Result.(*wrapperspb.Int64Value).GetValue())
+	assert.NoError(t, outcome.Err)
+
+	token, _, _, err = store.Begin(ctx, "b", time.Minute)
+	assert.NoError(t, err)
+	assert.NoError(t, store.Complete(ctx, "b", token, Outcome{Err: cqrs.Conflict("already paid")}, time.Hour))
+	_, outcome, done, err = store.Begin(ctx, "b", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, done)
+	assert.Nil(t, outcome.Result)
+	assert.ErrorIs(t, outcome.Err, cqrs.ErrConflict)
+	assert.Equal(t, "cqrs: conflict: already paid", outcome.Err.Error())
+
+	// released keys can be reserved again, the completed ones are kept
+	token, _, _, err = store.Begin(ctx, "c", time.Minute)
+	assert.NoError(t, err)
+	assert.NoError(t, store.Release(ctx, "c", token))
+	assert.NoError(t, store.Release(ctx, "b", token))
+	_, _, done, err = store.Begin(ctx, "c", time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, done)
+	_, _, done, err = store.Begin(ctx, "b", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, done)
+
+	// expired
+	store.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	_, _, done, err = store.Begin(ctx, "a", time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, done)
+}
+
+func TestSQLStore_LeaseLost(t *testing.T) {
+	store := newSQLStore(t)
+	ctx := context.Background()
+
+	first, _, _, err := store.Begin(ctx, "a", time.Minute)
+	assert.NoError(t, err)
+	// the lease of first expired, and the key is reserved by second
+	store.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	second, _, _, err := store.Begin(ctx, "a", time.Minute)
+	assert.NoError(t, err)
+
+	assert.ErrorIs(t, store.Complete(ctx, "a", first, Outcome{Result: wrapperspb.Int64(1)}, time.Hour), ErrLeaseLost)
+	assert.NoError(t, store.Release(ctx, "a", first))
+	_, _, _, err = store.Begin(ctx, "a", time.Minute)
+	assert.ErrorIs(t, err, ErrInProgress)
+
+	assert.NoError(t, store.Complete(ctx, "a", second, Outcome{Result: wrapperspb.Int64(2)}, time.Hour))
+	_, outcome, done, err := store.Begin(ctx, "a", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, done)
+	assert.Equal(t, int64(2), outcome.Result.(*wrapperspb.Int64Value).GetValue())
+}
+
+// tableDriver is a database/sql driver that executes the statements of SQLStore on a map.
+type tableDriver struct {
+	mu   sync.Mutex
+	rows map[string][]driver.Value // key -> token, done, result, error_code, error_message, expire_at
+}
+
+func (d *tableDriver) reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.rows = make(map[string][]driver.Value)
+}
+
+func (d *tableDriver) Open(string) (driver.Conn, error) { return &tableConn{d: d}, nil }
+
+type tableConn struct{ d *tableDriver }
+
+func (c *tableConn) Prepare(query string) (driver.Stmt, error) {
+	return &tableStmt{d: c.d, query: query}, nil
+}
+func (c *tableConn) Close() error              { return nil }
+func (c *tableConn) Begin() (driver.Tx, error) { return nil, errors.New("unsupported") }
+
+type tableStmt struct {
+	d     *tableDriver
+	query string
+}
+
+func (s *tableStmt) Close() error  { return nil }
+func (s *tableStmt) NumInput() int { return -1 }
+
+func (s *tableStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	switch {
+	case strings.HasPrefix(s.query, "DELETE") && strings.Contains(s.query, "expire_at"):
+		key := args[0].(string)
+		if row, ok := s.d.rows[key]; ok && !row[5].(time.Time).After(args[1].(time.Time)) {
+			delete(s.d.rows, key)
+		}
+	case strings.HasPrefix(s.query, "DELETE"):
+		key := args[0].(string)
+		if row, ok := s.d.rows[key]; ok && row[0] == args[1] && row[1] == args[2] {
+			delete(s.d.rows, key)
+			return driver.RowsAffected(1), nil
+		}
+		return driver.RowsAffected(0), nil
+	case strings.HasPrefix(s.query, "INSERT"):
+		key := args[0].(string)
+		if _, ok := s.d.rows[key]; ok {
+			return nil, errors.New("duplicate key")
+		}
+		s.d.rows[key] = []driver.Value{args[1], args[2], nil, nil, nil, args[3]}
+	case strings.HasPrefix(s.query, "UPDATE"):
+		key := args[5].(string)
+		if row, ok := s.d.rows[key]; ok && row[0] == args[6] && row[1] == args[7] {
+			s.d.rows[key] = append([]driver.Value{row[0]}, args[:5]...)
+			return driver.RowsAffected(1), nil
+		}
+		return driver.RowsAffected(0), nil
+	default:
+		return nil, errors.New("unsupported")
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *tableStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	row, ok := s.d.rows[args[0].(string)]
+	if !ok {
+		return &tableRows{}, nil
+	}
+	return &tableRows{row: row[1:5]}, nil
+}
+
+type tableRows struct {
+	row  []driver.Value
+	read bool
+}
+
+func (r *tableRows) Columns() []string {
+	return []string{"done", "result", "error_code", "error_message"}
+}
+func (r *tableRows) Close() error { return nil }
+func (r *tableRows) Next(dest []driver.Value) error {
+	if r.row == nil || r.read {
+		return io.EOF
+	}
+	r.read = true
+	copy(dest, r.row)
+	return nil
+}
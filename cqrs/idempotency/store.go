@@ -0,0 +1,119 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Outcome is the outcome of the execution of a command.
+type Outcome struct {
+	// Result is the result of CommandResultHandler, it is nil for CommandHandler.
+	Result any
+	// Err is the error returned by the handler.
+	Err error
+}
+
+// Store records the outcomes of the commands by idempotency key.
+type Store interface {
+	// Begin reserves the key for an execution for lease, and returns the token that owns the reservation.
+	// If the outcome of the key is recorded, it is returned and done is true.
+	// If the key is reserved by another execution and the lease is not expired, it returns ErrInProgress.
+	Begin(ctx context.Context, key string, lease time.Duration) (token string, outcome Outcome, done bool, err error)
+
+	// Complete records the outcome of the key reserved by token, the outcome expires after ttl.
+	// It returns ErrLeaseLost if the key is not reserved by token any more, e.g. the lease expired and
+	// another execution reserved the key.
+	Complete(ctx context.Context, key string, token string, outcome Outcome, ttl time.Duration) error
+
+	// Release releases the key reserved by token without outcome, so that the command can be executed again.
+	// It does nothing if the key is not reserved by token.
+	Release(ctx context.Context, key string, token string) error
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// MemoryStore is an in-memory Store, the expired outcomes are removed lazily.
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]*memoryEntry
+	now       func() time.Time
+	nextSweep time.Time
+}
+
+// sweepInterval is the min interval between the removals of all expired keys.
+const sweepInterval = time.Minute
+
+type memoryEntry struct {
+	token    string
+	outcome  Outcome
+	done     bool
+	expireAt time.Time
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]*memoryEntry), now: time.Now}
+}
+
+func (s *MemoryStore) Begin(_ context.Context, key string, lease time.Duration) (string, Outcome, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.sweep(now)
+	if entry, ok := s.entries[key]; ok && now.Before(entry.expireAt) {
+		if entry.done {
+			return "", entry.outcome, true, nil
+		}
+		return "", Outcome{}, false, ErrInProgress
+	}
+	token := uuid.NewString()
+	s.entries[key] = &memoryEntry{token: token, expireAt: now.Add(lease)}
+	return token, Outcome{}, false, nil
+}
+
+func (s *MemoryStore) Complete(_ context.Context, key string, token string, outcome Outcome, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.owns(key, token) {
+		return ErrLeaseLost
+	}
+	s.entries[key] = &memoryEntry{token: token, outcome: outcome, done: true, expireAt: s.now().Add(ttl)}
+	return nil
+}
+
+func (s *MemoryStore) Release(_ context.Context, key string, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.owns(key, token) {
+		delete(s.entries, key)
+	}
+	return nil
+}
+
+// owns reports whether the key is reserved by token and is not completed.
+func (s *MemoryStore) owns(key string, token string) bool {
+	entry, ok := s.entries[key]
+	return ok && !entry.done && entry.token == token
+}
+
+// Len returns the number of keys, including the expired ones that are not removed yet.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Before(s.nextSweep) {
+		return
+	}
+	s.nextSweep = now.Add(sweepInterval)
+	for key, entry := range s.entries {
+		if !now.Before(entry.expireAt) {
+			delete(s.entries, key)
+		}
+	}
+}
@@ -2,6 +2,9 @@ package cqrs
 
 import (
 	"context"
+	"reflect"
+
+	"github.com/go-leo/design-pattern/event"
 )
 
 // ========================== Command ==========================
@@ -24,6 +27,20 @@ type NoopCommand[Args any] struct{}
 
 func (NoopCommand[Args]) Handle(context.Context, Args) error { return nil }
 
+// CommandResultHandler is a command handler that to update data, it returns a result and the events raised by the command.
+// The events are published to the event.Bus set by the EventBus option after the command succeeds.
+type CommandResultHandler[Args any, Result any] interface {
+	Handle(ctx context.Context, args Args) (Result, []event.Event, error)
+}
+
+// The CommandResultHandlerFunc type is an adapter to allow the use of ordinary functions as CommandResultHandler.
+type CommandResultHandlerFunc[Args any, Result any] func(ctx context.Context, args Args) (Result, []event.Event, error)
+
+// Handle calls f(ctx).
+func (f CommandResultHandlerFunc[Args, Result]) Handle(ctx context.Context, args Args) (Result, []event.Event, error) {
+	return f(ctx, args)
+}
+
 // ========================== Query ==========================
 
 // QueryHandler is a query handler that to handlers to read data.
@@ -46,12 +63,56 @@ func (NoopQuery[Args, Result]) Handle(context.Context, Args) (Result, error) {
 	return *(new(Result)), nil
 }
 
+// StreamQueryHandler is a query handler that reads data incrementally, it calls send with each result.
+// send blocks until the result is received by the consumer of Stream, and returns an error
+// if the consumer closed the Stream or ctx is done, then Handle should stop and return the error.
+type StreamQueryHandler[Args any, Result any] interface {
+	Handle(ctx context.Context, args Args, send func(Result) error) error
+}
+
+// The StreamQueryHandlerFunc type is an adapter to allow the use of ordinary functions as StreamQueryHandler.
+type StreamQueryHandlerFunc[Args any, Result any] func(ctx context.Context, args Args, send func(Result) error) error
+
+// Handle calls f(ctx).
+func (f StreamQueryHandlerFunc[Args, Result]) Handle(ctx context.Context, args Args, send func(Result) error) error {
+	return f(ctx, args, send)
+}
+
 // ========================== Bus ==========================
 
-// Future represents the result of an asynchronous computation.
-type Future interface {
-	// Get wait for computation completion, and to retrieve the result of the computation.
-	Get(ctx context.Context) (any, error)
+// Kind is the kind of handler.
+type Kind int
+
+const (
+	// CommandKind is the kind of CommandHandler.
+	CommandKind Kind = iota + 1
+	// QueryKind is the kind of QueryHandler.
+	QueryKind
+	// StreamQueryKind is the kind of StreamQueryHandler.
+	StreamQueryKind
+)
+
+func (k Kind) String() string {
+	switch k {
+	case CommandKind:
+		return "command"
+	case QueryKind:
+		return "query"
+	case StreamQueryKind:
+		return "stream query"
+	default:
+		return "unknown"
+	}
+}
+
+// HandlerInfo describes a handler registered in the bus.
+type HandlerInfo struct {
+	// Kind is the kind of handler.
+	Kind Kind
+	// ArgsType is the type of arguments that the handler handles.
+	ArgsType reflect.Type
+	// HandlerType is the type of handler.
+	HandlerType reflect.Type
 }
 
 // Bus is a bus, register CommandHandler and QueryHandler, execute Command and query Query
@@ -60,20 +121,39 @@ type Bus interface {
 	// RegisterCommand register CommandHandler
 	RegisterCommand(handler any) error
 
-	// RegisterQuery register QueryHandler
+	// RegisterQuery register QueryHandler or StreamQueryHandler
 	RegisterQuery(handler any) error
 
+	// ReplaceCommand register CommandHandler, replace the registered one that handles the same arguments type
+	ReplaceCommand(handler any) error
+
+	// ReplaceQuery register QueryHandler or StreamQueryHandler, replace the registered one that handles the same arguments type
+	ReplaceQuery(handler any) error
+
+	// UnregisterCommand unregister the CommandHandler that handles the type of args
+	UnregisterCommand(args any) error
+
+	// UnregisterQuery unregister the QueryHandler or StreamQueryHandler that handles the type of args
+	UnregisterQuery(args any) error
+
+	// Handlers returns the registered handlers, sorted by the name of arguments type
+	Handlers() []HandlerInfo
+
 	// Exec synchronously executes a command
 	Exec(ctx context.Context, args any) error
 
 	// Query synchronously executes a query
 	Query(ctx context.Context, args any) (any, error)
 
-	// AsyncExec asynchronously executes a command, result in Future
-	AsyncExec(ctx context.Context, args any) (Future, error)
+	// QueryStream executes a streaming query, the results are received from Stream
+	QueryStream(ctx context.Context, args any) (Stream[any], error)
+
+	// AsyncExec asynchronously executes a command, result in Future, the Future carries the result of
+	// CommandResultHandler, and nil for CommandHandler
+	AsyncExec(ctx context.Context, args any) (Future[any], error)
 
 	// AsyncQuery asynchronously executes a query, result in Future
-	AsyncQuery(ctx context.Context, args any) (Future, error)
+	AsyncQuery(ctx context.Context, args any) (Future[any], error)
 
 	// Close bus gracefully
 	Close(ctx context.Context) error
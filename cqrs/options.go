@@ -0,0 +1,176 @@
+package cqrs
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/go-leo/design-pattern/event"
+	"github.com/go-leo/design-pattern/instrument"
+	"github.com/go-leo/design-pattern/middleware"
+	"github.com/go-leo/gox/syncx/gopher"
+	"github.com/go-leo/gox/syncx/gopher/sample"
+)
+
+// Interceptor intercepts the execution of a command or query on the bus.
+// The req is the arguments of command or query, and the resp is the result of handler,
+// the resp of CommandHandler and StreamQueryHandler is always nil.
+type Interceptor = middleware.Middleware[any, any]
+
+type option struct {
+	Pool       gopher.Gopher
+	EventBus   event.Bus
+	UnitOfWork Transactor
+	Hook       instrument.Hook
+
+	OutboxRelayed bool
+
+	DrainTimeout time.Duration
+
+	CommandInterceptors      []Interceptor
+	QueryInterceptors        []Interceptor
+	TypedCommandInterceptors map[reflect.Type][]Interceptor
+	TypedQueryInterceptors   map[reflect.Type][]Interceptor
+
+	// chained interceptors, built by init.
+	commandChain      Interceptor
+	queryChain        Interceptor
+	typedCommandChain map[reflect.Type]Interceptor
+	typedQueryChain   map[reflect.Type]Interceptor
+}
+
+func (o *option) init() *option {
+	if o.Pool == nil {
+		o.Pool = sample.Gopher{}
+	}
+	o.commandChain = middleware.Chain(o.CommandInterceptors...)
+	o.queryChain = middleware.Chain(o.QueryInterceptors...)
+	o.typedCommandChain = chainTyped(o.CommandInterceptors, o.TypedCommandInterceptors)
+	o.typedQueryChain = chainTyped(o.QueryInterceptors, o.TypedQueryInterceptors)
+	return o
+}
+
+func (o *option) commandInterceptor(inType reflect.Type) Interceptor {
+	if interceptor, ok := o.typedCommandChain[inType]; ok {
+		return interceptor
+	}
+	return o.commandChain
+}
+
+func (o *option) queryInterceptor(inType reflect.Type) Interceptor {
+	if interceptor, ok := o.typedQueryChain[inType]; ok {
+		return interceptor
+	}
+	return o.queryChain
+}
+
+// chainTyped chains the global interceptors and the interceptors of each type,
+// the global interceptors are called first.
+func chainTyped(global []Interceptor, typed map[reflect.Type][]Interceptor) map[reflect.Type]Interceptor {
+	chains := make(map[reflect.Type]Interceptor, len(typed))
+	for inType, interceptors := range typed {
+		all := make([]Interceptor, 0, len(global)+len(interceptors))
+		all = append(all, global...)
+		all = append(all, interceptors...)
+		chains[inType] = middleware.Chain(all...)
+	}
+	return chains
+}
+
+func newOption(opts ...Option) *option {
+	o := &option{
+		TypedCommandInterceptors: make(map[reflect.Type][]Interceptor),
+		TypedQueryInterceptors:   make(map[reflect.Type][]Interceptor),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o.init()
+}
+
+type Option func(*option)
+
+// Pool sets the gopher.Gopher that runs the asynchronous commands, queries and streaming queries, default is sample.Gopher{},
+// which starts a goroutine per call. Use a workerpool.Pool to bound the goroutines and the queued calls.
+func Pool(pool gopher.Gopher) Option {
+	return func(o *option) {
+		o.Pool = pool
+	}
+}
+
+// EventBus sets the event.Bus that the events raised by CommandResultHandler are published to.
+func EventBus(bus event.Bus) Option {
+	return func(o *option) {
+		o.EventBus = bus
+	}
+}
+
+// UnitOfWork sets the Transactor that every command is executed in.
+// The transaction is committed if the CommandHandler succeeds, and rolled back if it returns an error or panics.
+// If the Transactor implements Outbox, the events raised by the CommandHandler are written in the transaction,
+// and they are also published to EventBus after the commit, unless RelayOutbox is set.
+// If a relay delivers the events of the outbox, set RelayOutbox, otherwise each event is delivered twice.
+func UnitOfWork(uow Transactor) Option {
+	return func(o *option) {
+		o.UnitOfWork = uow
+	}
+}
+
+// RelayOutbox makes the events written to the Outbox of UnitOfWork not published to EventBus,
+// since they are delivered by a relay that reads the outbox.
+// It is ignored if the Transactor doesn't write the events, e.g. a SQLUnitOfWork without SQLOutbox.
+func RelayOutbox() Option {
+	return func(o *option) {
+		o.OutboxRelayed = true
+	}
+}
+
+// Hook sets the instrument.Hook that observes every command, query and streaming query.
+// The events raised by a command are emitted with the context of the command, so they carry its trace.
+func Hook(hook instrument.Hook) Option {
+	return func(o *option) {
+		o.Hook = hook
+	}
+}
+
+// DrainTimeout sets the max duration that Close waits for the in-flight commands and queries,
+// after that, their contexts are canceled and Close returns an AbandonedError.
+// Zero means Close waits until its context is done.
+func DrainTimeout(timeout time.Duration) Option {
+	return func(o *option) {
+		o.DrainTimeout = timeout
+	}
+}
+
+// CommandInterceptors appends interceptors that are called around every command,
+// by Exec and AsyncExec.
+func CommandInterceptors(interceptors ...Interceptor) Option {
+	return func(o *option) {
+		o.CommandInterceptors = append(o.CommandInterceptors, interceptors...)
+	}
+}
+
+// QueryInterceptors appends interceptors that are called around every query,
+// by Query and AsyncQuery.
+func QueryInterceptors(interceptors ...Interceptor) Option {
+	return func(o *option) {
+		o.QueryInterceptors = append(o.QueryInterceptors, interceptors...)
+	}
+}
+
+// CommandInterceptorsFor appends interceptors that are called around the command of the type of args,
+// after the interceptors appended by CommandInterceptors.
+func CommandInterceptorsFor(args any, interceptors ...Interceptor) Option {
+	return func(o *option) {
+		inType := reflect.TypeOf(args)
+		o.TypedCommandInterceptors[inType] = append(o.TypedCommandInterceptors[inType], interceptors...)
+	}
+}
+
+// QueryInterceptorsFor appends interceptors that are called around the query of the type of args,
+// after the interceptors appended by QueryInterceptors.
+func QueryInterceptorsFor(args any, interceptors ...Interceptor) Option {
+	return func(o *option) {
+		inType := reflect.TypeOf(args)
+		o.TypedQueryInterceptors[inType] = append(o.TypedQueryInterceptors[inType], interceptors...)
+	}
+}
@@ -0,0 +1,70 @@
+package cqrs
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-leo/design-pattern/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+var errForbidden = errors.New("forbidden")
+
+func recordInterceptor(name string, records *[]string) Interceptor {
+	return func(ctx context.Context, req any, invoker middleware.Invoker[any, any]) (any, error) {
+		*records = append(*records, name)
+		return invoker(ctx, req)
+	}
+}
+
+func TestInterceptors(t *testing.T) {
+	var records []string
+	bus := NewBus(
+		CommandInterceptors(recordInterceptor("cmd1", &records), recordInterceptor("cmd2", &records)),
+		QueryInterceptors(recordInterceptor("query", &records)),
+		CommandInterceptorsFor(&renameCmd{}, func(ctx context.Context, req any, invoker middleware.Invoker[any, any]) (any, error) {
+			if req.(*renameCmd).Name == "root" {
+				return nil, errForbidden
+			}
+			return recordInterceptor("rename", &records)(ctx, req, invoker)
+		}),
+		QueryInterceptorsFor(countQuery{}, func(ctx context.Context, req any, invoker middleware.Invoker[any, any]) (any, error) {
+			res, err := invoker(ctx, req)
+			return res.(int) + 1, err
+		}),
+	)
+	assert.NoError(t, Register[*renameCmd](bus, NoopCommand[*renameCmd]{}))
+	assert.NoError(t, bus.RegisterCommand(&study{}))
+	assert.NoError(t, RegisterQuery[countQuery, int](bus, QueryHandlerFunc[countQuery, int](func(ctx context.Context, q countQuery) (int, error) {
+		return q.N, nil
+	})))
+
+	assert.NoError(t, Exec(context.Background(), bus, &renameCmd{Name: "jax"}))
+	assert.Equal(t, []string{"cmd1", "cmd2", "rename"}, records)
+
+	records = nil
+	assert.ErrorIs(t, bus.Exec(context.Background(), &renameCmd{Name: "root"}), errForbidden)
+	assert.Equal(t, []string{"cmd1", "cmd2"}, records)
+
+	records = nil
+	future, err := bus.AsyncExec(context.Background(), &studyCmd{})
+	assert.NoError(t, err)
+	_, err = future.Get(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"cmd1", "cmd2"}, records)
+
+	records = nil
+	n, err := Query[countQuery, int](context.Background(), bus, countQuery{N: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []string{"query"}, records)
+
+	records = nil
+	future, err = bus.AsyncQuery(context.Background(), countQuery{N: 2})
+	assert.NoError(t, err)
+	res, err := future.Get(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 3, res)
+	assert.Equal(t, []string{"query"}, records)
+}
@@ -0,0 +1,241 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-leo/design-pattern/cqrs"
+	"github.com/go-leo/design-pattern/internal/lifecycle"
+	"github.com/go-leo/gox/syncx/gopher"
+	"github.com/go-leo/gox/syncx/gopher/sample"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// ErrUnsupported handler can not be registered to or unregistered from the client-side Bus,
+// manage handlers by the bus of Server.
+var ErrUnsupported = errors.New("cqrs/remote: handler can not be managed by remote bus")
+
+var _ cqrs.Bus = (*bus)(nil)
+
+// bus is the client-side cqrs.Bus, it marshals commands and queries and sends them by Transport.
+type bus struct {
+	transport Transport
+	tracker   *lifecycle.Tracker // tracks in-flight commands and queries
+	options   *option
+}
+
+func (b *bus) RegisterCommand(any) error {
+	return ErrUnsupported
+}
+
+func (b *bus) RegisterQuery(any) error {
+	return ErrUnsupported
+}
+
+func (b *bus) ReplaceCommand(any) error {
+	return ErrUnsupported
+}
+
+func (b *bus) ReplaceQuery(any) error {
+	return ErrUnsupported
+}
+
+func (b *bus) UnregisterCommand(any) error {
+	return ErrUnsupported
+}
+
+func (b *bus) UnregisterQuery(any) error {
+	return ErrUnsupported
+}
+
+// Handlers returns nil, handlers are registered to the bus of Server.
+func (b *bus) Handlers() []cqrs.HandlerInfo {
+	return nil
+}
+
+func (b *bus) Exec(ctx context.Context, args any) error {
+	in, err := b.marshal(args)
+	if err != nil {
+		return err
+	}
+	ctx, done, err := b.enter(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+	return b.transport.Exec(ctx, in)
+}
+
+func (b *bus) Query(ctx context.Context, args any) (any, error) {
+	in, err := b.marshal(args)
+	if err != nil {
+		return nil, err
+	}
+	ctx, done, err := b.enter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	out, err := b.transport.Query(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return b.options.Registry.Unmarshal(out)
+}
+
+// QueryStream sends the streaming query, it is in flight until the Stream ends or is closed.
+func (b *bus) QueryStream(ctx context.Context, args any) (cqrs.Stream[any], error) {
+	in, err := b.marshal(args)
+	if err != nil {
+		return nil, err
+	}
+	ctx, done, err := b.enter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := b.transport.QueryStream(ctx, in)
+	if err != nil {
+		done()
+		return nil, err
+	}
+	return cqrs.MapStream[*anypb.Any, any](&trackedStream{Stream: stream, done: done}, b.options.Registry.Unmarshal), nil
+}
+
+func (b *bus) AsyncExec(ctx context.Context, args any) (cqrs.Future[any], error) {
+	in, err := b.marshal(args)
+	if err != nil {
+		return nil, err
+	}
+	return b.async(ctx, func(ctx context.Context) (any, error) {
+		return nil, b.transport.Exec(ctx, in)
+	})
+}
+
+func (b *bus) AsyncQuery(ctx context.Context, args any) (cqrs.Future[any], error) {
+	in, err := b.marshal(args)
+	if err != nil {
+		return nil, err
+	}
+	return b.async(ctx, func(ctx context.Context) (any, error) {
+		out, err := b.transport.Query(ctx, in)
+		if err != nil {
+			return nil, err
+		}
+		return b.options.Registry.Unmarshal(out)
+	})
+}
+
+// Close rejects new commands and queries, and waits for the in-flight ones to finish,
+// see cqrs.Bus for the draining.
+func (b *bus) Close(ctx context.Context) error {
+	err := b.tracker.Close(ctx, b.options.DrainTimeout)
+	if errors.Is(err, lifecycle.ErrClosed) {
+		return cqrs.ErrBusClosed
+	}
+	return err
+}
+
+func (b *bus) marshal(args any) (*anypb.Any, error) {
+	if args == nil {
+		return nil, cqrs.ErrArgsNil
+	}
+	return b.options.Registry.Marshal(args)
+}
+
+func (b *bus) enter(ctx context.Context) (context.Context, func(), error) {
+	ctx, done, err := b.tracker.Enter(ctx)
+	if err != nil {
+		return ctx, nil, cqrs.ErrBusClosed
+	}
+	return ctx, done, nil
+}
+
+func (b *bus) async(ctx context.Context, fn func(ctx context.Context) (any, error)) (cqrs.Future[any], error) {
+	ctx, done, err := b.enter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	f, err := cqrs.Go(ctx, b.options.Pool, func(ctx context.Context) (any, error) {
+		defer done()
+		return fn(ctx)
+	})
+	if err != nil {
+		done()
+		return nil, err
+	}
+	return f, nil
+}
+
+// trackedStream calls done when the stream ends or is closed.
+type trackedStream struct {
+	cqrs.Stream[*anypb.Any]
+	done func()
+}
+
+func (s *trackedStream) Recv() (*anypb.Any, error) {
+	out, err := s.Stream.Recv()
+	if err != nil {
+		s.done()
+	}
+	return out, err
+}
+
+func (s *trackedStream) Close() error {
+	defer s.done()
+	return s.Stream.Close()
+}
+
+type option struct {
+	Registry     *Registry
+	Pool         gopher.Gopher
+	DrainTimeout time.Duration
+}
+
+func newOption(opts ...Option) *option {
+	o := &option{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.Registry == nil {
+		o.Registry, _ = NewRegistry()
+	}
+	if o.Pool == nil {
+		o.Pool = sample.Gopher{}
+	}
+	return o
+}
+
+type Option func(*option)
+
+// WithRegistry sets the Registry that resolves the type of messages,
+// non-protobuf messages and results must be registered to it.
+func WithRegistry(registry *Registry) Option {
+	return func(o *option) {
+		o.Registry = registry
+	}
+}
+
+// Pool sets the pool that runs AsyncExec and AsyncQuery.
+func Pool(pool gopher.Gopher) Option {
+	return func(o *option) {
+		o.Pool = pool
+	}
+}
+
+// DrainTimeout sets the max duration that Close waits for the in-flight commands and queries,
+// see cqrs.DrainTimeout.
+func DrainTimeout(timeout time.Duration) Option {
+	return func(o *option) {
+		o.DrainTimeout = timeout
+	}
+}
+
+// NewBus returns a client-side cqrs.Bus, that sends commands and queries by transport.
+func NewBus(transport Transport, opts ...Option) cqrs.Bus {
+	return &bus{
+		transport: transport,
+		tracker:   lifecycle.NewTracker(),
+		options:   newOption(opts...),
+	}
+}
@@ -0,0 +1,160 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/go-leo/design-pattern/cqrs"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type createUserCmd struct {
+	Name string
+}
+
+type findUserQuery struct {
+	ID int
+}
+
+type listUsersQuery struct {
+	Fail bool
+}
+
+type userResult struct {
+	ID   int
+	Name string
+}
+
+var errNameEmpty = errors.New("name is empty")
+
+func newLocalBus(t *testing.T, users map[int]string) cqrs.Bus {
+	bus := cqrs.NewBus()
+	assert.NoError(t, cqrs.Register[*createUserCmd](bus, cqrs.CommandHandlerFunc[*createUserCmd](func(ctx context.Context, cmd *createUserCmd) error {
+		if cmd.Name == "" {
+			return errNameEmpty
+		}
+		users[len(users)+1] = cmd.Name
+		return nil
+	})))
+	assert.NoError(t, cqrs.RegisterQuery[findUserQuery, *userResult](bus, cqrs.QueryHandlerFunc[findUserQuery, *userResult](func(ctx context.Context, q findUserQuery) (*userResult, error) {
+		if q.ID < 0 {
+			panic("negative id")
+		}
+		name, ok := users[q.ID]
+		if !ok {
+			return nil, cqrs.NotFound("user %d", q.ID)
+		}
+		return &userResult{ID: q.ID, Name: name}, nil
+	})))
+	assert.NoError(t, cqrs.RegisterQuery[*wrapperspb.Int64Value, *wrapperspb.StringValue](bus, cqrs.QueryHandlerFunc[*wrapperspb.Int64Value, *wrapperspb.StringValue](func(ctx context.Context, q *wrapperspb.Int64Value) (*wrapperspb.StringValue, error) {
+		return wrapperspb.String(users[int(q.GetValue())]), nil
+	})))
+	assert.NoError(t, cqrs.RegisterStreamQuery[listUsersQuery, *userResult](bus, cqrs.StreamQueryHandlerFunc[listUsersQuery, *userResult](func(ctx context.Context, q listUsersQuery, send func(*userResult) error) error {
+		for id := 1; id <= len(users); id++ {
+			if err := send(&userResult{ID: id, Name: users[id]}); err != nil {
+				return err
+			}
+		}
+		if q.Fail {
+			return cqrs.Conflict("list users")
+		}
+		return nil
+	})))
+	return bus
+}
+
+func TestBus(t *testing.T) {
+	users := make(map[int]string)
+	registry, err := NewRegistry(&createUserCmd{}, findUserQuery{}, listUsersQuery{}, &userResult{})
+	assert.NoError(t, err)
+
+	lis := bufconn.Listen(1024 * 1024)
+	srv := grpc.NewServer()
+	RegisterBusServer(srv, NewServer(newLocalBus(t, users), registry))
+	go func() { _ = srv.Serve(lis) }()
+	defer srv.Stop()
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	bus := NewBus(NewGRPCTransport(conn), WithRegistry(registry))
+	ctx := context.Background()
+
+	assert.ErrorIs(t, bus.RegisterCommand(cqrs.NoopCommand[*createUserCmd]{}), ErrUnsupported)
+
+	assert.NoError(t, bus.Exec(ctx, &createUserCmd{Name: "jax"}))
+	assert.Equal(t, "jax", users[1])
+
+	// the results of commands are not carried
+	_, err = cqrs.ExecResult[*createUserCmd, int](ctx, bus, &createUserCmd{Name: "max"})
+	assert.ErrorIs(t, err, cqrs.ErrResultType)
+	assert.Equal(t, "max", users[2])
+	delete(users, 2)
+
+	err = bus.Exec(ctx, &createUserCmd{})
+	assert.Equal(t, codes.Unknown, status.Code(err))
+	assert.Contains(t, err.Error(), errNameEmpty.Error())
+
+	err = bus.Exec(ctx, createUserCmd{Name: "leo"})
+	assert.ErrorIs(t, err, ErrMessageUnknown)
+
+	res, err := cqrs.Query[findUserQuery, *userResult](ctx, bus, findUserQuery{ID: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, &userResult{ID: 1, Name: "jax"}, res)
+
+	_, err = bus.Query(ctx, findUserQuery{ID: 2})
+	assert.ErrorIs(t, err, cqrs.ErrNotFound)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = bus.Query(ctx, findUserQuery{ID: -1})
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	name, err := cqrs.Query[*wrapperspb.Int64Value, *wrapperspb.StringValue](ctx, bus, wrapperspb.Int64(1))
+	assert.NoError(t, err)
+	assert.Equal(t, "jax", name.GetValue())
+
+	_, err = bus.Query(ctx, wrapperspb.String("unregistered"))
+	assert.ErrorIs(t, err, cqrs.ErrUnregistered)
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+
+	future, err := bus.AsyncQuery(ctx, findUserQuery{ID: 1})
+	assert.NoError(t, err)
+	r, err := future.Get(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, &userResult{ID: 1, Name: "jax"}, r)
+
+	assert.NoError(t, bus.Exec(ctx, &createUserCmd{Name: "leo"}))
+	stream, err := cqrs.QueryStream[listUsersQuery, *userResult](ctx, bus, listUsersQuery{})
+	assert.NoError(t, err)
+	for id, name := range []string{"jax", "leo"} {
+		u, err := stream.Recv()
+		assert.NoError(t, err)
+		assert.Equal(t, &userResult{ID: id + 1, Name: name}, u)
+	}
+	_, err = stream.Recv()
+	assert.ErrorIs(t, err, io.EOF)
+
+	stream, err = cqrs.QueryStream[listUsersQuery, *userResult](ctx, bus, listUsersQuery{Fail: true})
+	assert.NoError(t, err)
+	_, _ = stream.Recv()
+	_, _ = stream.Recv()
+	_, err = stream.Recv()
+	assert.ErrorIs(t, err, cqrs.ErrConflict)
+	assert.Equal(t, codes.Aborted, status.Code(err))
+
+	assert.NoError(t, bus.Close(ctx))
+	assert.ErrorIs(t, bus.Exec(ctx, &createUserCmd{Name: "leo"}), cqrs.ErrBusClosed)
+}
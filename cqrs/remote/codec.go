@@ -0,0 +1,77 @@
+package remote
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+const typeURLPrefix = "type.googleapis.com/"
+
+// Marshal marshals the message into a protobuf Any.
+// A nil message is marshaled into an empty Any.
+func (r *Registry) Marshal(message any) (*anypb.Any, error) {
+	if isNil(message) {
+		return &anypb.Any{}, nil
+	}
+	if m, ok := message.(proto.Message); ok {
+		return anypb.New(m)
+	}
+	name, err := r.Name(message)
+	if err != nil {
+		return nil, err
+	}
+	value, err := json.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+	return &anypb.Any{TypeUrl: typeURLPrefix + name, Value: value}, nil
+}
+
+// Unmarshal unmarshals the protobuf Any into a message of the registered Go type.
+// An empty Any is unmarshaled into nil.
+func (r *Registry) Unmarshal(a *anypb.Any) (any, error) {
+	if a == nil || a.GetTypeUrl() == "" {
+		return nil, nil
+	}
+	name := a.GetTypeUrl()
+	if strings.HasPrefix(name, typeURLPrefix) {
+		name = strings.TrimPrefix(name, typeURLPrefix)
+	} else if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	messageType, err := r.Type(name)
+	if err != nil {
+		return nil, err
+	}
+	var ptr reflect.Value
+	if messageType.Kind() == reflect.Pointer {
+		ptr = reflect.New(messageType.Elem())
+	} else {
+		ptr = reflect.New(messageType)
+	}
+	if m, ok := ptr.Interface().(proto.Message); ok {
+		err = proto.Unmarshal(a.GetValue(), m)
+	} else {
+		err = json.Unmarshal(a.GetValue(), ptr.Interface())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrMessageMalformed, name, err)
+	}
+	if messageType.Kind() == reflect.Pointer {
+		return ptr.Interface(), nil
+	}
+	return ptr.Elem().Interface(), nil
+}
+
+func isNil(message any) bool {
+	if message == nil {
+		return true
+	}
+	value := reflect.ValueOf(message)
+	return value.Kind() == reflect.Pointer && value.IsNil()
+}
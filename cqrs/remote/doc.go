@@ -0,0 +1,6 @@
+// Package remote provides a cqrs.Bus that dispatches commands and queries to another process.
+//
+// The client-side Bus marshals the arguments into protobuf Any, and sends them by a Transport.
+// The Server unmarshals them by a Registry, and dispatches them into a local cqrs.Bus.
+// RegisterBusServer and NewGRPCTransport bind the Server and the Transport over gRPC.
+package remote
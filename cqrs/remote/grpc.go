@@ -0,0 +1,220 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/go-leo/design-pattern/cqrs"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+const (
+	Bus_Exec_FullMethodName  = "/leo.cqrs.Bus/Exec"
+	Bus_Query_FullMethodName = "/leo.cqrs.Bus/Query"
+
+	Bus_QueryStream_FullMethodName = "/leo.cqrs.Bus/QueryStream"
+)
+
+// BusServer is the server API for the leo.cqrs.Bus service.
+type BusServer interface {
+	Exec(context.Context, *anypb.Any) (*emptypb.Empty, error)
+	Query(context.Context, *anypb.Any) (*anypb.Any, error)
+	QueryStream(*anypb.Any, Bus_QueryStreamServer) error
+}
+
+// Bus_QueryStreamServer is the server-side stream of the QueryStream method.
+type Bus_QueryStreamServer interface {
+	Send(*anypb.Any) error
+	grpc.ServerStream
+}
+
+// RegisterBusServer registers the Server to the gRPC server, errors are converted by ToStatus.
+func RegisterBusServer(s grpc.ServiceRegistrar, srv *Server) {
+	s.RegisterService(&Bus_ServiceDesc, &grpcServer{server: srv})
+}
+
+type grpcServer struct {
+	server *Server
+}
+
+func (s *grpcServer) Exec(ctx context.Context, in *anypb.Any) (*emptypb.Empty, error) {
+	if err := s.server.Exec(ctx, in); err != nil {
+		return nil, ToStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *grpcServer) Query(ctx context.Context, in *anypb.Any) (*anypb.Any, error) {
+	out, err := s.server.Query(ctx, in)
+	if err != nil {
+		return nil, ToStatus(err)
+	}
+	return out, nil
+}
+
+func (s *grpcServer) QueryStream(in *anypb.Any, stream Bus_QueryStreamServer) error {
+	results, err := s.server.QueryStream(stream.Context(), in)
+	if err != nil {
+		return ToStatus(err)
+	}
+	defer results.Close()
+	for {
+		out, err := results.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return ToStatus(err)
+		}
+		if err := stream.Send(out); err != nil {
+			return err
+		}
+	}
+}
+
+var _ Transport = (*grpcTransport)(nil)
+
+// grpcTransport is the gRPC client of the leo.cqrs.Bus service.
+type grpcTransport struct {
+	cc   grpc.ClientConnInterface
+	opts []grpc.CallOption
+}
+
+func (t *grpcTransport) Exec(ctx context.Context, in *anypb.Any) error {
+	out := new(emptypb.Empty)
+	return FromStatus(t.cc.Invoke(ctx, Bus_Exec_FullMethodName, in, out, t.opts...))
+}
+
+func (t *grpcTransport) Query(ctx context.Context, in *anypb.Any) (*anypb.Any, error) {
+	out := new(anypb.Any)
+	if err := t.cc.Invoke(ctx, Bus_Query_FullMethodName, in, out, t.opts...); err != nil {
+		return nil, FromStatus(err)
+	}
+	return out, nil
+}
+
+func (t *grpcTransport) QueryStream(ctx context.Context, in *anypb.Any) (cqrs.Stream[*anypb.Any], error) {
+	ctx, cancel := context.WithCancel(ctx)
+	stream, err := t.cc.NewStream(ctx, &Bus_ServiceDesc.Streams[0], Bus_QueryStream_FullMethodName, t.opts...)
+	if err != nil {
+		cancel()
+		return nil, FromStatus(err)
+	}
+	// io.EOF means the stream was aborted, the status is returned by RecvMsg.
+	if err := stream.SendMsg(in); err != nil && !errors.Is(err, io.EOF) {
+		cancel()
+		return nil, FromStatus(err)
+	}
+	if err := stream.CloseSend(); err != nil {
+		cancel()
+		return nil, FromStatus(err)
+	}
+	return &grpcStream{stream: stream, cancel: cancel}, nil
+}
+
+// grpcStream is the client-side stream of the QueryStream method.
+type grpcStream struct {
+	stream grpc.ClientStream
+	cancel context.CancelFunc
+}
+
+func (s *grpcStream) Recv() (*anypb.Any, error) {
+	out := new(anypb.Any)
+	if err := s.stream.RecvMsg(out); err != nil {
+		s.cancel()
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		return nil, FromStatus(err)
+	}
+	return out, nil
+}
+
+func (s *grpcStream) Close() error {
+	s.cancel()
+	return nil
+}
+
+// NewGRPCTransport returns a Transport that sends commands and queries to the leo.cqrs.Bus service.
+func NewGRPCTransport(cc grpc.ClientConnInterface, opts ...grpc.CallOption) Transport {
+	return &grpcTransport{cc: cc, opts: opts}
+}
+
+func _Bus_Exec_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(anypb.Any)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BusServer).Exec(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Bus_Exec_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BusServer).Exec(ctx, req.(*anypb.Any))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Bus_Query_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(anypb.Any)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BusServer).Query(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Bus_Query_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BusServer).Query(ctx, req.(*anypb.Any))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Bus_QueryStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	in := new(anypb.Any)
+	if err := stream.RecvMsg(in); err != nil {
+		return err
+	}
+	return srv.(BusServer).QueryStream(in, &busQueryStreamServer{ServerStream: stream})
+}
+
+type busQueryStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *busQueryStreamServer) Send(m *anypb.Any) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// Bus_ServiceDesc is the grpc.ServiceDesc for the leo.cqrs.Bus service.
+var Bus_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "leo.cqrs.Bus",
+	HandlerType: (*BusServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Exec",
+			Handler:    _Bus_Exec_Handler,
+		},
+		{
+			MethodName: "Query",
+			Handler:    _Bus_Query_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "QueryStream",
+			Handler:       _Bus_QueryStream_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "leo/cqrs/bus",
+}
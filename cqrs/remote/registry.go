@@ -0,0 +1,113 @@
+package remote
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+var (
+	// ErrMessageNil message is nil
+	ErrMessageNil = errors.New("cqrs/remote: message is nil")
+
+	// ErrMessageUnknown message type is not registered
+	ErrMessageUnknown = errors.New("cqrs/remote: message type is unknown")
+
+	// ErrMessageMalformed message can not be unmarshaled
+	ErrMessageMalformed = errors.New("cqrs/remote: message is malformed")
+)
+
+// Registry is a message-type registry, it maps the name of message to the Go type.
+// Protobuf messages are named by their full name, other messages are named by
+// the package path and the name of their Go type, and are encoded in JSON.
+// Protobuf messages are also resolved by protoregistry.GlobalTypes, so they don't have to be registered.
+type Registry struct {
+	mu    sync.RWMutex
+	types map[string]reflect.Type
+	names map[reflect.Type]string
+}
+
+// Register registers the type of messages.
+func (r *Registry) Register(messages ...any) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, message := range messages {
+		if message == nil {
+			return ErrMessageNil
+		}
+		messageType := reflect.TypeOf(message)
+		name, err := nameOf(message)
+		if err != nil {
+			return err
+		}
+		if registered, ok := r.types[name]; ok && registered != messageType {
+			return fmt.Errorf("cqrs/remote: message name %s conflicts between %s and %s", name, registered, messageType)
+		}
+		r.types[name] = messageType
+		r.names[messageType] = name
+	}
+	return nil
+}
+
+// Name returns the name of the type of message, non-protobuf message must be registered.
+func (r *Registry) Name(message any) (string, error) {
+	if message == nil {
+		return "", ErrMessageNil
+	}
+	r.mu.RLock()
+	name, ok := r.names[reflect.TypeOf(message)]
+	r.mu.RUnlock()
+	if ok {
+		return name, nil
+	}
+	if m, ok := message.(proto.Message); ok {
+		return string(proto.MessageName(m)), nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrMessageUnknown, reflect.TypeOf(message))
+}
+
+// Type returns the Go type of the named message.
+func (r *Registry) Type(name string) (reflect.Type, error) {
+	r.mu.RLock()
+	messageType, ok := r.types[name]
+	r.mu.RUnlock()
+	if ok {
+		return messageType, nil
+	}
+	mt, err := protoregistry.GlobalTypes.FindMessageByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrMessageUnknown, name)
+	}
+	return reflect.TypeOf(mt.Zero().Interface()), nil
+}
+
+func nameOf(message any) (string, error) {
+	if m, ok := message.(proto.Message); ok {
+		return string(proto.MessageName(m)), nil
+	}
+	messageType := reflect.TypeOf(message)
+	for messageType.Kind() == reflect.Pointer {
+		messageType = messageType.Elem()
+	}
+	if messageType.Name() == "" {
+		return "", fmt.Errorf("cqrs/remote: message type %s is unnamed", reflect.TypeOf(message))
+	}
+	return messageType.PkgPath() + "." + messageType.Name(), nil
+}
+
+// NewRegistry returns a Registry with the type of messages registered.
+func NewRegistry(messages ...any) (*Registry, error) {
+	r := &Registry{
+		types: make(map[string]reflect.Type),
+		names: make(map[reflect.Type]string),
+	}
+	if err := r.Register(messages...); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
@@ -0,0 +1,62 @@
+package remote
+
+import (
+	"context"
+
+	"github.com/go-leo/design-pattern/cqrs"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+var _ Transport = (*Server)(nil)
+
+// Server is the server-side adapter of remote Bus, it unmarshals the commands and queries
+// received from Transport, and dispatches them into a local cqrs.Bus.
+// Server is also an in-process Transport.
+type Server struct {
+	bus      cqrs.Bus
+	registry *Registry
+}
+
+// Exec unmarshals the command and executes it by the local bus.
+func (s *Server) Exec(ctx context.Context, args *anypb.Any) error {
+	in, err := s.registry.Unmarshal(args)
+	if err != nil {
+		return err
+	}
+	return s.bus.Exec(ctx, in)
+}
+
+// Query unmarshals the query, executes it by the local bus, and marshals the result.
+func (s *Server) Query(ctx context.Context, args *anypb.Any) (*anypb.Any, error) {
+	in, err := s.registry.Unmarshal(args)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.bus.Query(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return s.registry.Marshal(res)
+}
+
+// QueryStream unmarshals the streaming query, executes it by the local bus, and marshals the results.
+func (s *Server) QueryStream(ctx context.Context, args *anypb.Any) (cqrs.Stream[*anypb.Any], error) {
+	in, err := s.registry.Unmarshal(args)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := s.bus.QueryStream(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return cqrs.MapStream(stream, s.registry.Marshal), nil
+}
+
+// NewServer returns a Server that dispatches into bus, the registry resolves the type of messages.
+// If registry is nil, only protobuf messages can be resolved.
+func NewServer(bus cqrs.Bus, registry *Registry) *Server {
+	if registry == nil {
+		registry, _ = NewRegistry()
+	}
+	return &Server{bus: bus, registry: registry}
+}
@@ -0,0 +1,146 @@
+package remote
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-leo/design-pattern/cqrs"
+	"github.com/go-leo/design-pattern/cqrs/validation"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// codeErrors maps the status code to the error that is returned to the client.
+var codeErrors = map[codes.Code]error{
+	codes.NotFound:         cqrs.ErrNotFound,
+	codes.Aborted:          cqrs.ErrConflict,
+	codes.InvalidArgument:  cqrs.ErrValidation,
+	codes.PermissionDenied: cqrs.ErrUnauthorized,
+	codes.Unavailable:      cqrs.ErrTransient,
+	codes.Unimplemented:    cqrs.ErrUnregistered,
+	codes.Canceled:         context.Canceled,
+	codes.DeadlineExceeded: context.DeadlineExceeded,
+}
+
+// sentinelErrors are recognized by the message of status.
+var sentinelErrors = []error{
+	cqrs.ErrArgsNil,
+	cqrs.ErrBusClosed,
+	cqrs.ErrUnregistered,
+}
+
+// ToStatus converts the error returned by Server into a gRPC status error.
+// The validation.FieldErrors in the chain of err are attached as the errdetails.BadRequest detail.
+func ToStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	st := status.New(codeOf(err), err.Error())
+	if fieldErrs := validation.Fields(err); fieldErrs != nil {
+		badRequest := &errdetails.BadRequest{}
+		for _, fieldErr := range fieldErrs {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       fieldErr.Field,
+				Description: fieldErr.Description,
+			})
+		}
+		if detailed, err := st.WithDetails(badRequest); err == nil {
+			st = detailed
+		}
+	}
+	return st.Err()
+}
+
+func codeOf(err error) codes.Code {
+	var panicErr *cqrs.PanicError
+	switch {
+	case errors.As(err, &panicErr):
+		return codes.Internal
+	case errors.Is(err, cqrs.ErrArgsNil),
+		errors.Is(err, ErrMessageNil),
+		errors.Is(err, ErrMessageUnknown),
+		errors.Is(err, ErrMessageMalformed):
+		return codes.InvalidArgument
+	case errors.Is(err, cqrs.ErrUnregistered):
+		return codes.Unimplemented
+	case errors.Is(err, cqrs.ErrBusClosed):
+		return codes.Unavailable
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded
+	}
+	switch cqrs.CodeOf(err) {
+	case cqrs.CodeNotFound:
+		return codes.NotFound
+	case cqrs.CodeConflict:
+		return codes.Aborted
+	case cqrs.CodeValidation:
+		return codes.InvalidArgument
+	case cqrs.CodeUnauthorized:
+		return codes.PermissionDenied
+	case cqrs.CodeTransient:
+		return codes.Unavailable
+	default:
+		return codes.Unknown
+	}
+}
+
+// FromStatus converts the gRPC status error into an error that wraps the matching cqrs error,
+// so that the error can be checked by errors.Is.
+// The errdetails.BadRequest detail is converted into validation.FieldErrors, that is returned by validation.Fields.
+func FromStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	var targets []error
+	if target, ok := codeErrors[st.Code()]; ok {
+		targets = append(targets, target)
+	}
+	for _, sentinel := range sentinelErrors {
+		if st.Message() == sentinel.Error() {
+			targets = append(targets, sentinel)
+		}
+	}
+	for _, detail := range st.Details() {
+		badRequest, ok := detail.(*errdetails.BadRequest)
+		if !ok {
+			continue
+		}
+		fieldErrs := make(validation.FieldErrors, 0, len(badRequest.GetFieldViolations()))
+		for _, violation := range badRequest.GetFieldViolations() {
+			fieldErrs = append(fieldErrs, validation.FieldError{Field: violation.GetField(), Description: violation.GetDescription()})
+		}
+		targets = append(targets, fieldErrs)
+	}
+	if len(targets) == 0 {
+		return err
+	}
+	return &statusError{status: st, errs: targets}
+}
+
+// statusError is a gRPC status error that wraps cqrs errors.
+type statusError struct {
+	status *status.Status
+	errs   []error
+}
+
+func (e *statusError) Error() string {
+	return e.status.Message()
+}
+
+func (e *statusError) Unwrap() []error {
+	return e.errs
+}
+
+func (e *statusError) GRPCStatus() *status.Status {
+	return e.status
+}
@@ -0,0 +1,31 @@
+package remote
+
+import (
+	"testing"
+
+	"github.com/go-leo/design-pattern/cqrs"
+	"github.com/go-leo/design-pattern/cqrs/validation"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStatus_FieldErrors(t *testing.T) {
+	fieldErrs := validation.FieldErrors{{Field: "name", Description: "is required"}, {Field: "age", Description: "must be positive"}}
+	err := ToStatus(cqrs.NewError(cqrs.CodeValidation, "", fieldErrs))
+	st := status.Convert(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	assert.Len(t, st.Details(), 1)
+	badRequest := st.Details()[0].(*errdetails.BadRequest)
+	assert.Equal(t, "name", badRequest.GetFieldViolations()[0].GetField())
+	assert.Equal(t, "must be positive", badRequest.GetFieldViolations()[1].GetDescription())
+
+	err = FromStatus(err)
+	assert.ErrorIs(t, err, cqrs.ErrValidation)
+	assert.Equal(t, fieldErrs, validation.Fields(err))
+
+	err = FromStatus(ToStatus(cqrs.Validation("bad request")))
+	assert.ErrorIs(t, err, cqrs.ErrValidation)
+	assert.Nil(t, validation.Fields(err))
+}
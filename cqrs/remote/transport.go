@@ -0,0 +1,20 @@
+package remote
+
+import (
+	"context"
+
+	"github.com/go-leo/design-pattern/cqrs"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// Transport carries the serialized command and query from the client Bus to the Server.
+type Transport interface {
+	// Exec sends the command and waits for it to be executed.
+	Exec(ctx context.Context, args *anypb.Any) error
+
+	// Query sends the query and waits for its result.
+	Query(ctx context.Context, args *anypb.Any) (*anypb.Any, error)
+
+	// QueryStream sends the streaming query and returns the Stream of its results.
+	QueryStream(ctx context.Context, args *anypb.Any) (cqrs.Stream[*anypb.Any], error)
+}
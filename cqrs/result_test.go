@@ -0,0 +1,108 @@
+package cqrs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-leo/design-pattern/event"
+	"github.com/stretchr/testify/assert"
+)
+
+type createOrderCmd struct {
+	Amount int
+}
+
+type orderCreated struct {
+	ID int
+}
+
+type createOrder struct {
+	nextID int
+}
+
+func (h *createOrder) Handle(ctx context.Context, cmd *createOrderCmd) (int, []event.Event, error) {
+	if cmd.Amount <= 0 {
+		return 0, []event.Event{event.NewEvent(orderCreated{ID: -1}, -1)}, errors.New("invalid amount")
+	}
+	h.nextID++
+	return h.nextID, []event.Event{event.NewEvent(orderCreated{ID: h.nextID}, h.nextID)}, nil
+}
+
+type orderListener struct {
+	created *[]int
+}
+
+func (l orderListener) Handle(e event.Event) error {
+	*l.created = append(*l.created, e.Body().(orderCreated).ID)
+	return nil
+}
+
+type deadlineListener struct {
+	deadlines *[]time.Time
+}
+
+func (l deadlineListener) Handle(e event.Event) error {
+	deadline, _ := e.Context().Deadline()
+	*l.deadlines = append(*l.deadlines, deadline)
+	return nil
+}
+
+func TestCommandResult(t *testing.T) {
+	var created []int
+	eventBus := event.NewBus()
+	assert.NoError(t, eventBus.On(event.NewEvent(orderCreated{}, nil), orderListener{created: &created}))
+
+	bus := NewBus(EventBus(eventBus))
+	assert.NoError(t, RegisterResult[*createOrderCmd, int](bus, &createOrder{}))
+
+	id, err := ExecResult[*createOrderCmd, int](context.Background(), bus, &createOrderCmd{Amount: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, id)
+	assert.Equal(t, []int{1}, created)
+
+	// events are not published when the command fails
+	_, err = ExecResult[*createOrderCmd, int](context.Background(), bus, &createOrderCmd{})
+	assert.Error(t, err)
+	assert.Equal(t, []int{1}, created)
+
+	assert.NoError(t, bus.Exec(context.Background(), &createOrderCmd{Amount: 1}))
+	assert.Equal(t, []int{1, 2}, created)
+
+	future, err := bus.AsyncExec(context.Background(), &createOrderCmd{Amount: 1})
+	assert.NoError(t, err)
+	res, err := future.Get(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 3, res)
+	assert.Equal(t, []int{1, 2, 3}, created)
+
+	// reflected CommandResultHandler
+	bus = NewBus(EventBus(eventBus))
+	assert.NoError(t, bus.RegisterCommand(&createOrder{nextID: 10}))
+	id, err = ExecResult[*createOrderCmd, int](context.Background(), bus, &createOrderCmd{Amount: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, 11, id)
+	assert.Equal(t, []int{1, 2, 3, 11}, created)
+
+	// the events are emitted with the context of command
+	var deadlines []time.Time
+	assert.NoError(t, eventBus.On(event.NewEvent(orderCreated{}, nil), deadlineListener{deadlines: &deadlines}))
+	deadline := time.Now().Add(time.Minute)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	_, err = ExecResult[*createOrderCmd, int](ctx, bus, &createOrderCmd{Amount: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Time{deadline}, deadlines)
+	// the listeners are not called after the command is canceled
+	cancel()
+	_, err = ExecResult[*createOrderCmd, int](ctx, bus, &createOrderCmd{Amount: 1})
+	assert.ErrorIs(t, err, ErrPublish)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Len(t, deadlines, 1)
+
+	// failed to publish
+	assert.NoError(t, eventBus.Close(context.Background()))
+	_, err = ExecResult[*createOrderCmd, int](context.Background(), bus, &createOrderCmd{Amount: 1})
+	assert.ErrorIs(t, err, ErrPublish)
+	assert.ErrorIs(t, err, event.ErrBusClosed)
+}
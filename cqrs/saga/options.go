@@ -0,0 +1,46 @@
+package saga
+
+import (
+	"time"
+)
+
+type option struct {
+	PollInterval time.Duration
+	Clock        func() time.Time
+	ErrorHandler func(name string, err error)
+}
+
+func newOption(opts ...Option) *option {
+	o := &option{
+		PollInterval: time.Second,
+		Clock:        time.Now,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+type Option func(*option)
+
+// PollInterval sets the interval that Run checks the timeouts and resumes the instances, default is 1s.
+func PollInterval(interval time.Duration) Option {
+	return func(o *option) {
+		o.PollInterval = interval
+	}
+}
+
+// Clock sets the function that returns the current time, default is time.Now.
+func Clock(now func() time.Time) Option {
+	return func(o *option) {
+		o.Clock = now
+	}
+}
+
+// ErrorHandler sets the function that is called when Run fails to drive the instances, name is the name of saga,
+// and err joins the errors of the instances, each of them carries the id of its instance.
+func ErrorHandler(handler func(name string, err error)) Option {
+	return func(o *option) {
+		o.ErrorHandler = handler
+	}
+}
@@ -0,0 +1,446 @@
+// Package saga orchestrates the long-running business processes that span multiple commands.
+//
+// A Saga is a sequence of steps, each step issues a command through cqrs.Bus, and optionally waits for an event of
+// event.Bus that completes it. If a step fails or times out, the compensations of the completed steps are issued
+// in reverse order. The state of every saga instance is keyed by a correlation id and persisted in a Store before
+// each command is issued, so that the instances are resumed after a crash, the commands are issued at least once.
+package saga
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/go-leo/design-pattern/cqrs"
+	"github.com/go-leo/design-pattern/event"
+)
+
+// Step is a step of saga.
+type Step[D any] struct {
+	// Name is the name of step, it is used in the Reason of compensation.
+	Name string
+
+	// Command returns the command that performs the step, nil means the step has no command.
+	Command func(data D) any
+
+	// Compensation returns the command that undoes the step, nil means there is nothing to undo.
+	Compensation func(data D) any
+
+	// Await reports whether the step waits for an event after its command succeeded.
+	// The step is completed by the reaction registered by On that returns Next.
+	Await bool
+
+	// Timeout is the max duration that an awaiting step waits, zero means no timeout.
+	// When a step times out, its compensation is issued too, since its outcome is unknown.
+	Timeout time.Duration
+}
+
+// Transition is the outcome of a reaction to event.
+type Transition int
+
+const (
+	// Stay keeps waiting, the changes of data are saved.
+	Stay Transition = iota
+	// Next completes the current step.
+	Next
+	// Fail fails the current step, the completed steps are compensated.
+	Fail
+)
+
+// Correlate returns the correlation id of the saga instance that the event belongs to.
+// The events with empty id are ignored.
+type Correlate func(e event.Event) string
+
+type reaction[D any] struct {
+	correlate Correlate
+	start     func(e event.Event, data *D) error
+	react     func(e event.Event, data *D) (Transition, error)
+}
+
+var _ event.Listener = (*Saga[any])(nil)
+
+// Saga is a saga definition and the runtime of its instances, D is the type of the data of instance.
+// It is an event.Listener of the events it reacts to.
+type Saga[D any] struct {
+	name      string
+	bus       cqrs.Bus
+	store     Store
+	options   *option
+	steps     []Step[D]
+	reactions map[reflect.Type]*reaction[D]
+
+	mu        sync.Mutex // guards instances
+	instances map[string]*instance
+}
+
+// instance is the runtime state of a saga instance that is in use.
+type instance struct {
+	mu     sync.Mutex // serializes the transitions of the instance
+	active bool       // the instance is driven by a goroutine, guarded by mu
+	refs   int        // the goroutines that use the instance, guarded by Saga.mu
+}
+
+// New returns a Saga named name, the commands are issued through bus, and the instances are persisted in store.
+// The steps and reactions must be defined before the saga handles any event.
+func New[D any](name string, bus cqrs.Bus, store Store, opts ...Option) *Saga[D] {
+	return &Saga[D]{
+		name:      name,
+		bus:       bus,
+		store:     store,
+		options:   newOption(opts...),
+		reactions: make(map[reflect.Type]*reaction[D]),
+		instances: make(map[string]*instance),
+	}
+}
+
+// acquire returns the runtime state of the instance, it must be released after use.
+func (s *Saga[D]) acquire(id string) *instance {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	in, ok := s.instances[id]
+	if !ok {
+		in = &instance{}
+		s.instances[id] = in
+	}
+	in.refs++
+	return in
+}
+
+// release releases the runtime state of the instance, it is deleted if no goroutine uses it.
+func (s *Saga[D]) release(id string, in *instance) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if in.refs--; in.refs == 0 {
+		delete(s.instances, id)
+	}
+}
+
+// dataOf returns the data of instance, or ErrDataType if it is not a D. A nil data is the zero D.
+func dataOf[D any](inst Instance) (D, error) {
+	if inst.Data == nil {
+		return *(new(D)), nil
+	}
+	data, ok := inst.Data.(D)
+	if !ok {
+		return data, fmt.Errorf("%w: %T is not %v", ErrDataType, inst.Data, reflect.TypeOf((*D)(nil)).Elem())
+	}
+	return data, nil
+}
+
+// Name returns the name of saga.
+func (s *Saga[D]) Name() string {
+	return s.name
+}
+
+// Step appends a step.
+func (s *Saga[D]) Step(step Step[D]) *Saga[D] {
+	s.steps = append(s.steps, step)
+	return s
+}
+
+// StartOn starts a new instance when the event with the type of body evt happens, init initializes the data.
+// The event is ignored if the instance has been started.
+func (s *Saga[D]) StartOn(evt any, correlate Correlate, init func(e event.Event, data *D) error) *Saga[D] {
+	s.reactions[reflect.TypeOf(evt)] = &reaction[D]{correlate: correlate, start: init}
+	return s
+}
+
+// On reacts to the event with the type of body evt, react updates the data and returns the Transition of
+// the current step. The event is ignored if the current step is not awaiting.
+func (s *Saga[D]) On(evt any, correlate Correlate, react func(e event.Event, data *D) (Transition, error)) *Saga[D] {
+	s.reactions[reflect.TypeOf(evt)] = &reaction[D]{correlate: correlate, react: react}
+	return s
+}
+
+// Subscribe registers the saga to bus as the listener of the events it reacts to.
+func (s *Saga[D]) Subscribe(bus event.Bus) error {
+	for eventType := range s.reactions {
+		if err := bus.On(event.NewEvent(reflect.Zero(eventType).Interface(), nil), s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Handle handles the event, and drives the instance until it waits or ends.
+func (s *Saga[D]) Handle(e event.Event) error {
+	r, ok := s.reactions[e.Type()]
+	if !ok {
+		return nil
+	}
+	id := r.correlate(e)
+	if id == "" {
+		return nil
+	}
+	ctx := e.Context()
+	if err := s.react(ctx, r, id, e); err != nil {
+		return err
+	}
+	return s.drive(ctx, id)
+}
+
+// Load returns the instance and its data, ErrDataType is returned if the data is not a D.
+func (s *Saga[D]) Load(ctx context.Context, id string) (Instance, D, error) {
+	inst, err := s.store.Load(ctx, s.name, id)
+	if err != nil {
+		return Instance{}, *(new(D)), err
+	}
+	data, err := dataOf[D](inst)
+	if err != nil {
+		return Instance{}, data, err
+	}
+	return inst, data, nil
+}
+
+// Resume drives the instances that were interrupted, e.g. by a crash, or whose compensation failed.
+func (s *Saga[D]) Resume(ctx context.Context) error {
+	instances, err := s.store.Pending(ctx, s.name)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, inst := range instances {
+		if inst.Status != Running && inst.Status != Compensating {
+			continue
+		}
+		if err := s.drive(ctx, inst.ID); err != nil {
+			errs = append(errs, fmt.Errorf("saga: %s %s: %w", s.name, inst.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// CheckTimeouts compensates the instances whose current step timed out.
+func (s *Saga[D]) CheckTimeouts(ctx context.Context) error {
+	instances, err := s.store.Pending(ctx, s.name)
+	if err != nil {
+		return err
+	}
+	now := s.options.Clock()
+	var errs []error
+	for _, inst := range instances {
+		if !timedOut(inst, now) {
+			continue
+		}
+		if err := s.timeout(ctx, inst.ID); err != nil {
+			errs = append(errs, fmt.Errorf("saga: %s %s: %w", s.name, inst.ID, err))
+			continue
+		}
+		if err := s.drive(ctx, inst.ID); err != nil {
+			errs = append(errs, fmt.Errorf("saga: %s %s: %w", s.name, inst.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Run checks the timeouts and resumes the instances periodically until ctx is done, it returns the error of ctx.
+// Only one process should run a saga, otherwise the commands may be issued by several processes at the same time.
+func (s *Saga[D]) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.options.PollInterval)
+	defer ticker.Stop()
+	for {
+		if err := errors.Join(s.CheckTimeouts(ctx), s.Resume(ctx)); err != nil && s.options.ErrorHandler != nil {
+			s.options.ErrorHandler(s.name, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func timedOut(inst Instance, now time.Time) bool {
+	return (inst.Status == Running || inst.Status == Waiting) && !inst.Deadline.IsZero() && !now.Before(inst.Deadline)
+}
+
+// react applies the event to the instance.
+func (s *Saga[D]) react(ctx context.Context, r *reaction[D], id string, e event.Event) error {
+	in := s.acquire(id)
+	defer s.release(id, in)
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	inst, err := s.store.Load(ctx, s.name, id)
+	if r.start != nil {
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, ErrInstanceNotFound) {
+			return err
+		}
+		var data D
+		if err := r.start(e, &data); err != nil {
+			return err
+		}
+		inst = Instance{ID: id, Saga: s.name, Step: -1, Data: data}
+		s.advance(&inst)
+		return s.store.Save(ctx, &inst)
+	}
+	if errors.Is(err, ErrInstanceNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if (inst.Status != Running && inst.Status != Waiting) || !s.steps[inst.Step].Await {
+		return nil
+	}
+	data, err := dataOf[D](inst)
+	if err != nil {
+		return err
+	}
+	transition, err := r.react(e, &data)
+	if err != nil {
+		return err
+	}
+	inst.Data = data
+	switch transition {
+	case Next:
+		s.advance(&inst)
+	case Fail:
+		s.compensate(&inst, inst.Step-1, fmt.Sprintf("step %s failed by %s", s.steps[inst.Step].Name, e.Type()))
+	}
+	return s.store.Save(ctx, &inst)
+}
+
+// timeout starts the compensation of the instance if its current step timed out.
+func (s *Saga[D]) timeout(ctx context.Context, id string) error {
+	in := s.acquire(id)
+	defer s.release(id, in)
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	inst, err := s.store.Load(ctx, s.name, id)
+	if err != nil {
+		return err
+	}
+	if !timedOut(inst, s.options.Clock()) {
+		return nil
+	}
+	s.compensate(&inst, inst.Step, fmt.Sprintf("step %s timed out", s.steps[inst.Step].Name))
+	return s.store.Save(ctx, &inst)
+}
+
+// drive issues the commands of the instance until it waits or ends.
+// Only one goroutine drives an instance, the others return immediately,
+// and the driving goroutine picks up the changes they made.
+// The instances are locked separately, so a slow Store or command of an instance doesn't stall the others.
+func (s *Saga[D]) drive(ctx context.Context, id string) error {
+	in := s.acquire(id)
+	defer s.release(id, in)
+	in.mu.Lock()
+	if in.active {
+		in.mu.Unlock()
+		return nil
+	}
+	in.active = true
+	in.mu.Unlock()
+	for {
+		in.mu.Lock()
+		inst, cmd, more, err := s.next(ctx, id)
+		if err != nil || !more {
+			in.active = false
+			in.mu.Unlock()
+			return err
+		}
+		in.mu.Unlock()
+
+		var execErr error
+		if cmd != nil {
+			execErr = s.bus.Exec(ctx, cmd)
+		}
+
+		in.mu.Lock()
+		if err := s.complete(ctx, inst, execErr); err != nil {
+			in.active = false
+			in.mu.Unlock()
+			return err
+		}
+		in.mu.Unlock()
+	}
+}
+
+// next returns the command that the instance issues next, more is false if the instance waits or ends.
+func (s *Saga[D]) next(ctx context.Context, id string) (inst Instance, cmd any, more bool, err error) {
+	inst, err = s.store.Load(ctx, s.name, id)
+	if err != nil {
+		return inst, nil, false, err
+	}
+	data, err := dataOf[D](inst)
+	if err != nil {
+		return inst, nil, false, err
+	}
+	switch inst.Status {
+	case Running:
+		if command := s.steps[inst.Step].Command; command != nil {
+			cmd = command(data)
+		}
+		return inst, cmd, true, nil
+	case Compensating:
+		return inst, s.steps[inst.Step].Compensation(data), true, nil
+	default:
+		return inst, nil, false, nil
+	}
+}
+
+// complete applies the result of the command issued by prev, it is ignored if the instance has moved on.
+func (s *Saga[D]) complete(ctx context.Context, prev Instance, execErr error) error {
+	inst, err := s.store.Load(ctx, s.name, prev.ID)
+	if err != nil {
+		return err
+	}
+	if inst.Status != prev.Status || inst.Step != prev.Step {
+		return nil
+	}
+	step := s.steps[inst.Step]
+	switch inst.Status {
+	case Running:
+		switch {
+		case execErr != nil:
+			s.compensate(&inst, inst.Step-1, fmt.Sprintf("step %s failed: %v", step.Name, execErr))
+		case step.Await:
+			inst.Status = Waiting
+		default:
+			s.advance(&inst)
+		}
+	case Compensating:
+		if execErr != nil {
+			return fmt.Errorf("compensation of step %s failed: %w", step.Name, execErr)
+		}
+		s.compensate(&inst, inst.Step-1, inst.Reason)
+	}
+	return s.store.Save(ctx, &inst)
+}
+
+// advance moves the instance to the next step.
+func (s *Saga[D]) advance(inst *Instance) {
+	inst.Step++
+	inst.Deadline = time.Time{}
+	if inst.Step >= len(s.steps) {
+		inst.Status = Completed
+		inst.Step = len(s.steps) - 1
+		return
+	}
+	inst.Status = Running
+	if step := s.steps[inst.Step]; step.Await && step.Timeout > 0 {
+		inst.Deadline = s.options.Clock().Add(step.Timeout)
+	}
+}
+
+// compensate moves the instance to the compensation of step from, the steps that have nothing to undo are skipped.
+func (s *Saga[D]) compensate(inst *Instance, from int, reason string) {
+	inst.Reason = reason
+	inst.Deadline = time.Time{}
+	inst.Step = from
+	for inst.Step >= 0 && s.steps[inst.Step].Compensation == nil {
+		inst.Step--
+	}
+	if inst.Step < 0 {
+		inst.Status = Compensated
+		inst.Step = 0
+		return
+	}
+	inst.Status = Compensating
+}
@@ -0,0 +1,259 @@
+package saga
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-leo/design-pattern/cqrs"
+	"github.com/go-leo/design-pattern/event"
+	"github.com/stretchr/testify/assert"
+)
+
+type orderPlaced struct {
+	OrderID string
+	Amount  int
+}
+
+type stockReserved struct {
+	OrderID string
+}
+
+type reserveStockCmd struct{ OrderID string }
+
+type releaseStockCmd struct{ OrderID string }
+
+type chargeCmd struct {
+	OrderID string
+	Amount  int
+}
+
+type order struct {
+	ID       string
+	Amount   int
+	Reserved bool
+}
+
+func byOrderID(e event.Event) string {
+	switch body := e.Body().(type) {
+	case orderPlaced:
+		return body.OrderID
+	case stockReserved:
+		return body.OrderID
+	}
+	return ""
+}
+
+func newOrderSaga(bus cqrs.Bus, store Store, opts ...Option) *Saga[order] {
+	return New[order]("order", bus, store, opts...).
+		StartOn(orderPlaced{}, byOrderID, func(e event.Event, data *order) error {
+			placed := e.Body().(orderPlaced)
+			data.ID, data.Amount = placed.OrderID, placed.Amount
+			return nil
+		}).
+		On(stockReserved{}, byOrderID, func(e event.Event, data *order) (Transition, error) {
+			data.Reserved = true
+			return Next, nil
+		}).
+		Step(Step[order]{
+			Name:         "reserve stock",
+			Command:      func(data order) any { return reserveStockCmd{OrderID: data.ID} },
+			Compensation: func(data order) any { return releaseStockCmd{OrderID: data.ID} },
+			Await:        true,
+			Timeout:      time.Minute,
+		}).
+		Step(Step[order]{
+			Name:    "charge",
+			Command: func(data order) any { return chargeCmd{OrderID: data.ID, Amount: data.Amount} },
+		})
+}
+
+type commandLog struct {
+	mu       sync.Mutex
+	commands []any
+}
+
+func (l *commandLog) add(cmd any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.commands = append(l.commands, cmd)
+}
+
+func newBuses(t *testing.T, log *commandLog) (cqrs.Bus, event.Bus) {
+	cmdBus := cqrs.NewBus()
+	eventBus := event.NewBus()
+	assert.NoError(t, cqrs.Register[reserveStockCmd](cmdBus, cqrs.CommandHandlerFunc[reserveStockCmd](func(ctx context.Context, cmd reserveStockCmd) error {
+		log.add(cmd)
+		// the event is emitted synchronously, before Exec returns.
+		return eventBus.Emit(event.NewEvent(stockReserved{OrderID: cmd.OrderID}, nil).WithContext(ctx))
+	})))
+	assert.NoError(t, cqrs.Register[releaseStockCmd](cmdBus, cqrs.CommandHandlerFunc[releaseStockCmd](func(ctx context.Context, cmd releaseStockCmd) error {
+		log.add(cmd)
+		return nil
+	})))
+	assert.NoError(t, cqrs.Register[chargeCmd](cmdBus, cqrs.CommandHandlerFunc[chargeCmd](func(ctx context.Context, cmd chargeCmd) error {
+		log.add(cmd)
+		if cmd.Amount > 100 {
+			return cqrs.Validation("insufficient funds")
+		}
+		return nil
+	})))
+	return cmdBus, eventBus
+}
+
+func TestSaga(t *testing.T) {
+	log := &commandLog{}
+	cmdBus, eventBus := newBuses(t, log)
+	s := newOrderSaga(cmdBus, NewMemoryStore())
+	assert.NoError(t, s.Subscribe(eventBus))
+
+	assert.NoError(t, eventBus.Emit(event.NewEvent(orderPlaced{OrderID: "1", Amount: 10}, nil)))
+	inst, data, err := s.Load(context.Background(), "1")
+	assert.NoError(t, err)
+	assert.Equal(t, Completed, inst.Status)
+	assert.Equal(t, order{ID: "1", Amount: 10, Reserved: true}, data)
+	assert.Equal(t, []any{reserveStockCmd{OrderID: "1"}, chargeCmd{OrderID: "1", Amount: 10}}, log.commands)
+
+	// duplicated start is ignored
+	assert.NoError(t, eventBus.Emit(event.NewEvent(orderPlaced{OrderID: "1", Amount: 10}, nil)))
+	assert.Len(t, log.commands, 2)
+}
+
+func TestSaga_Compensate(t *testing.T) {
+	log := &commandLog{}
+	cmdBus, eventBus := newBuses(t, log)
+	s := newOrderSaga(cmdBus, NewMemoryStore())
+	assert.NoError(t, s.Subscribe(eventBus))
+
+	assert.NoError(t, eventBus.Emit(event.NewEvent(orderPlaced{OrderID: "2", Amount: 1000}, nil)))
+	inst, _, err := s.Load(context.Background(), "2")
+	assert.NoError(t, err)
+	assert.Equal(t, Compensated, inst.Status)
+	assert.Contains(t, inst.Reason, "step charge failed")
+	assert.Equal(t, []any{
+		reserveStockCmd{OrderID: "2"},
+		chargeCmd{OrderID: "2", Amount: 1000},
+		releaseStockCmd{OrderID: "2"},
+	}, log.commands)
+}
+
+func TestSaga_Resume(t *testing.T) {
+	log := &commandLog{}
+	cmdBus, _ := newBuses(t, log)
+	store := NewMemoryStore()
+	// the process crashed after the stock was reserved, before the charge was issued.
+	crashed := &Instance{ID: "3", Saga: "order", Status: Running, Step: 1, Data: order{ID: "3", Amount: 10, Reserved: true}}
+	assert.NoError(t, store.Save(context.Background(), crashed))
+
+	s := newOrderSaga(cmdBus, store)
+	assert.NoError(t, s.Resume(context.Background()))
+	inst, _, err := s.Load(context.Background(), "3")
+	assert.NoError(t, err)
+	assert.Equal(t, Completed, inst.Status)
+	assert.Equal(t, []any{chargeCmd{OrderID: "3", Amount: 10}}, log.commands)
+	assert.NoError(t, s.Resume(context.Background()))
+	assert.Len(t, log.commands, 1)
+}
+
+func TestSaga_Run(t *testing.T) {
+	failed := errors.New("unavailable")
+	var fails int
+	var mu sync.Mutex
+	cmdBus := cqrs.NewBus()
+	assert.NoError(t, cqrs.Register[reserveStockCmd](cmdBus, cqrs.CommandHandlerFunc[reserveStockCmd](func(ctx context.Context, cmd reserveStockCmd) error {
+		return nil
+	})))
+	assert.NoError(t, cqrs.Register[releaseStockCmd](cmdBus, cqrs.CommandHandlerFunc[releaseStockCmd](func(ctx context.Context, cmd releaseStockCmd) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if fails < 2 {
+			fails++
+			return failed
+		}
+		return nil
+	})))
+	var errs []error
+	s := newOrderSaga(cmdBus, NewMemoryStore(), PollInterval(5*time.Millisecond), ErrorHandler(func(name string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(t, "order", name)
+		errs = append(errs, err)
+	}))
+	s.steps[0].Timeout = 10 * time.Millisecond
+	assert.NoError(t, s.Handle(event.NewEvent(orderPlaced{OrderID: "4"}, nil)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = s.Run(ctx) }()
+	assert.Eventually(t, func() bool {
+		inst, _, err := s.Load(ctx, "4")
+		return err == nil && inst.Status == Compensated
+	}, time.Second, time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 2, fails)
+	assert.NotEmpty(t, errs)
+	assert.ErrorIs(t, errs[0], failed)
+	assert.ErrorContains(t, errs[0], "4")
+}
+
+func TestSaga_DataType(t *testing.T) {
+	log := &commandLog{}
+	cmdBus, _ := newBuses(t, log)
+	store := NewMemoryStore()
+	// the data is decoded to a map by a JSON-backed store
+	decoded := &Instance{ID: "5", Saga: "order", Status: Running, Step: 1, Data: map[string]any{"ID": "5", "Amount": 10}}
+	assert.NoError(t, store.Save(context.Background(), decoded))
+
+	s := newOrderSaga(cmdBus, store)
+	assert.ErrorIs(t, s.Resume(context.Background()), ErrDataType)
+	_, _, err := s.Load(context.Background(), "5")
+	assert.ErrorIs(t, err, ErrDataType)
+	assert.Empty(t, log.commands)
+}
+
+// blockingStore is a Store whose Load of an instance blocks until it is released.
+type blockingStore struct {
+	*MemoryStore
+	id      string
+	loading chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingStore) Load(ctx context.Context, saga string, id string) (Instance, error) {
+	if id == s.id {
+		select {
+		case s.loading <- struct{}{}:
+		default:
+		}
+		<-s.release
+	}
+	return s.MemoryStore.Load(ctx, saga, id)
+}
+
+func TestSaga_Instances(t *testing.T) {
+	log := &commandLog{}
+	cmdBus, _ := newBuses(t, log)
+	store := &blockingStore{MemoryStore: NewMemoryStore(), id: "6", loading: make(chan struct{}, 1), release: make(chan struct{})}
+	s := newOrderSaga(cmdBus, store)
+
+	done := make(chan error)
+	go func() {
+		done <- s.Handle(event.NewEvent(orderPlaced{OrderID: "6", Amount: 10}, nil))
+	}()
+	<-store.loading
+	// the slow instance doesn't stall the others
+	assert.NoError(t, s.Handle(event.NewEvent(orderPlaced{OrderID: "7", Amount: 10}, nil)))
+	inst, _, err := s.Load(context.Background(), "7")
+	assert.NoError(t, err)
+	assert.Equal(t, Waiting, inst.Status)
+
+	close(store.release)
+	assert.NoError(t, <-done)
+	inst, _, err = s.Load(context.Background(), "6")
+	assert.NoError(t, err)
+	assert.Equal(t, Waiting, inst.Status)
+	assert.Empty(t, s.instances)
+}
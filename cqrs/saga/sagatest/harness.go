@@ -0,0 +1,166 @@
+// Package sagatest provides a Harness for driving the steps of saga.Saga in tests.
+package sagatest
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/go-leo/design-pattern/cqrs"
+	"github.com/go-leo/design-pattern/cqrs/saga"
+	"github.com/go-leo/design-pattern/event"
+)
+
+// Define defines a saga with the bus, store and options given by Harness.
+type Define[D any] func(bus cqrs.Bus, store saga.Store, opts ...saga.Option) *saga.Saga[D]
+
+// Harness drives a saga with a fake command bus and a fake clock.
+// The commands issued by the saga are recorded instead of executed, they succeed unless Fail is called.
+type Harness[D any] struct {
+	Saga  *saga.Saga[D]
+	Store *saga.MemoryStore
+
+	mu       sync.Mutex
+	now      time.Time
+	commands []any
+	failures map[reflect.Type]error
+}
+
+// New returns a Harness that drives the saga defined by define.
+func New[D any](define Define[D]) *Harness[D] {
+	h := &Harness[D]{
+		Store:    saga.NewMemoryStore(),
+		now:      time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC),
+		failures: make(map[reflect.Type]error),
+	}
+	h.Saga = define(&bus{harness: h}, h.Store, saga.Clock(h.Now))
+	return h
+}
+
+// Publish publishes an event with body to the saga.
+func (h *Harness[D]) Publish(body any) error {
+	return h.Saga.Handle(event.NewEvent(body, nil))
+}
+
+// Fail makes the commands with the type of cmd fail with err, a nil err makes them succeed again.
+func (h *Harness[D]) Fail(cmd any, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err == nil {
+		delete(h.failures, reflect.TypeOf(cmd))
+		return
+	}
+	h.failures[reflect.TypeOf(cmd)] = err
+}
+
+// Commands returns the commands issued by the saga, in the order they were issued.
+func (h *Harness[D]) Commands() []any {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]any(nil), h.commands...)
+}
+
+// Reset forgets the issued commands.
+func (h *Harness[D]) Reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.commands = nil
+}
+
+// Now returns the time of the fake clock.
+func (h *Harness[D]) Now() time.Time {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.now
+}
+
+// Advance moves the fake clock forward by d, and compensates the instances that timed out.
+func (h *Harness[D]) Advance(d time.Duration) error {
+	h.mu.Lock()
+	h.now = h.now.Add(d)
+	h.mu.Unlock()
+	return h.Saga.CheckTimeouts(context.Background())
+}
+
+// Resume resumes the interrupted instances, like a restarted process does.
+func (h *Harness[D]) Resume() error {
+	return h.Saga.Resume(context.Background())
+}
+
+// Instance returns the instance and its data.
+func (h *Harness[D]) Instance(id string) (saga.Instance, D, error) {
+	return h.Saga.Load(context.Background(), id)
+}
+
+// ErrUnsupported the method of cqrs.Bus is not supported by the bus of Harness, only Exec is supported.
+var ErrUnsupported = errors.New("sagatest: not supported by harness")
+
+var _ cqrs.Bus = (*bus)(nil)
+
+// bus is a cqrs.Bus that records the commands, only Exec is supported, the other methods return ErrUnsupported.
+type bus struct {
+	harness interface {
+		exec(args any) error
+	}
+}
+
+func (b *bus) Exec(_ context.Context, args any) error {
+	return b.harness.exec(args)
+}
+
+func (b *bus) RegisterCommand(any) error {
+	return ErrUnsupported
+}
+
+func (b *bus) RegisterQuery(any) error {
+	return ErrUnsupported
+}
+
+func (b *bus) ReplaceCommand(any) error {
+	return ErrUnsupported
+}
+
+func (b *bus) ReplaceQuery(any) error {
+	return ErrUnsupported
+}
+
+func (b *bus) UnregisterCommand(any) error {
+	return ErrUnsupported
+}
+
+func (b *bus) UnregisterQuery(any) error {
+	return ErrUnsupported
+}
+
+func (b *bus) Handlers() []cqrs.HandlerInfo {
+	return nil
+}
+
+func (b *bus) Query(context.Context, any) (any, error) {
+	return nil, ErrUnsupported
+}
+
+func (b *bus) QueryStream(context.Context, any) (cqrs.Stream[any], error) {
+	return nil, ErrUnsupported
+}
+
+func (b *bus) AsyncExec(context.Context, any) (cqrs.Future[any], error) {
+	return nil, ErrUnsupported
+}
+
+func (b *bus) AsyncQuery(context.Context, any) (cqrs.Future[any], error) {
+	return nil, ErrUnsupported
+}
+
+func (b *bus) Close(context.Context) error {
+	return nil
+}
+
+func (h *Harness[D]) exec(args any) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.commands = append(h.commands, args)
+	return h.failures[reflect.TypeOf(args)]
+}
@@ -0,0 +1,132 @@
+package sagatest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-leo/design-pattern/cqrs"
+	"github.com/go-leo/design-pattern/cqrs/saga"
+	"github.com/go-leo/design-pattern/event"
+	"github.com/stretchr/testify/assert"
+)
+
+type tripBooked struct{ TripID string }
+
+type flightBooked struct{ TripID string }
+
+type bookFlightCmd struct{ TripID string }
+
+type cancelFlightCmd struct{ TripID string }
+
+type bookHotelCmd struct{ TripID string }
+
+type trip struct{ ID string }
+
+func byTripID(e event.Event) string {
+	switch body := e.Body().(type) {
+	case tripBooked:
+		return body.TripID
+	case flightBooked:
+		return body.TripID
+	}
+	return ""
+}
+
+func newTripSaga(bus cqrs.Bus, store saga.Store, opts ...saga.Option) *saga.Saga[trip] {
+	return saga.New[trip]("trip", bus, store, opts...).
+		StartOn(tripBooked{}, byTripID, func(e event.Event, data *trip) error {
+			data.ID = e.Body().(tripBooked).TripID
+			return nil
+		}).
+		On(flightBooked{}, byTripID, func(e event.Event, data *trip) (saga.Transition, error) {
+			return saga.Next, nil
+		}).
+		Step(saga.Step[trip]{
+			Name:         "flight",
+			Command:      func(data trip) any { return bookFlightCmd{TripID: data.ID} },
+			Compensation: func(data trip) any { return cancelFlightCmd{TripID: data.ID} },
+			Await:        true,
+			Timeout:      time.Hour,
+		}).
+		Step(saga.Step[trip]{
+			Name:    "hotel",
+			Command: func(data trip) any { return bookHotelCmd{TripID: data.ID} },
+		})
+}
+
+func TestHarness(t *testing.T) {
+	h := New(newTripSaga)
+	assert.NoError(t, h.Publish(tripBooked{TripID: "1"}))
+	assert.Equal(t, []any{bookFlightCmd{TripID: "1"}}, h.Commands())
+	inst, _, err := h.Instance("1")
+	assert.NoError(t, err)
+	assert.Equal(t, saga.Waiting, inst.Status)
+	assert.Equal(t, h.Now().Add(time.Hour), inst.Deadline)
+
+	h.Reset()
+	assert.NoError(t, h.Publish(flightBooked{TripID: "1"}))
+	assert.Equal(t, []any{bookHotelCmd{TripID: "1"}}, h.Commands())
+	inst, data, err := h.Instance("1")
+	assert.NoError(t, err)
+	assert.Equal(t, saga.Completed, inst.Status)
+	assert.Equal(t, trip{ID: "1"}, data)
+}
+
+func TestHarness_Timeout(t *testing.T) {
+	h := New(newTripSaga)
+	assert.NoError(t, h.Publish(tripBooked{TripID: "1"}))
+	assert.NoError(t, h.Advance(59*time.Minute))
+	inst, _, _ := h.Instance("1")
+	assert.Equal(t, saga.Waiting, inst.Status)
+
+	assert.NoError(t, h.Advance(time.Minute))
+	inst, _, _ = h.Instance("1")
+	assert.Equal(t, saga.Compensated, inst.Status)
+	assert.Equal(t, "step flight timed out", inst.Reason)
+	assert.Equal(t, []any{bookFlightCmd{TripID: "1"}, cancelFlightCmd{TripID: "1"}}, h.Commands())
+
+	// late events are ignored
+	assert.NoError(t, h.Publish(flightBooked{TripID: "1"}))
+	assert.Len(t, h.Commands(), 2)
+}
+
+func TestHarness_Fail(t *testing.T) {
+	unavailable := errors.New("unavailable")
+	h := New(newTripSaga)
+	h.Fail(bookHotelCmd{}, unavailable)
+	h.Fail(cancelFlightCmd{}, unavailable)
+	assert.NoError(t, h.Publish(tripBooked{TripID: "1"}))
+	assert.ErrorIs(t, h.Publish(flightBooked{TripID: "1"}), unavailable)
+	inst, _, _ := h.Instance("1")
+	assert.Equal(t, saga.Compensating, inst.Status)
+
+	h.Fail(cancelFlightCmd{}, nil)
+	assert.NoError(t, h.Resume())
+	inst, _, _ = h.Instance("1")
+	assert.Equal(t, saga.Compensated, inst.Status)
+	assert.Equal(t, []any{
+		bookFlightCmd{TripID: "1"},
+		bookHotelCmd{TripID: "1"},
+		cancelFlightCmd{TripID: "1"},
+		cancelFlightCmd{TripID: "1"},
+	}, h.Commands())
+}
+
+func TestHarness_Bus(t *testing.T) {
+	var bus cqrs.Bus
+	New(func(b cqrs.Bus, store saga.Store, opts ...saga.Option) *saga.Saga[trip] {
+		bus = b
+		return newTripSaga(b, store, opts...)
+	})
+	ctx := context.Background()
+	assert.NoError(t, bus.Exec(ctx, bookFlightCmd{TripID: "1"}))
+	_, err := bus.Query(ctx, trip{})
+	assert.ErrorIs(t, err, ErrUnsupported)
+	_, err = bus.AsyncExec(ctx, bookFlightCmd{TripID: "1"})
+	assert.ErrorIs(t, err, ErrUnsupported)
+	_, err = bus.QueryStream(ctx, trip{})
+	assert.ErrorIs(t, err, ErrUnsupported)
+	assert.ErrorIs(t, bus.RegisterCommand(cqrs.NoopCommand[bookFlightCmd]{}), ErrUnsupported)
+}
@@ -0,0 +1,140 @@
+package saga
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrInstanceNotFound saga instance does not exist
+	ErrInstanceNotFound = errors.New("saga: instance not found")
+
+	// ErrVersionConflict saga instance was saved by others since it was loaded
+	ErrVersionConflict = errors.New("saga: version conflict")
+
+	// ErrDataType data of saga instance is not the data type of saga, e.g. it was decoded to another type by Store
+	ErrDataType = errors.New("saga: data type mismatch")
+)
+
+// Status is the status of saga Instance.
+type Status int
+
+const (
+	// Running the command of the current step is being executed.
+	Running Status = iota + 1
+	// Waiting the command of the current step succeeded, the saga waits for the event that completes the step.
+	Waiting
+	// Compensating the compensation of the current step is being executed.
+	Compensating
+	// Completed all steps are completed.
+	Completed
+	// Compensated a step failed, and the completed steps are compensated.
+	Compensated
+)
+
+func (s Status) String() string {
+	switch s {
+	case Running:
+		return "running"
+	case Waiting:
+		return "waiting"
+	case Compensating:
+		return "compensating"
+	case Completed:
+		return "completed"
+	case Compensated:
+		return "compensated"
+	default:
+		return "unknown"
+	}
+}
+
+// Done reports whether the saga instance is ended.
+func (s Status) Done() bool {
+	return s == Completed || s == Compensated
+}
+
+// Instance is the persisted state of a saga instance.
+type Instance struct {
+	// ID is the correlation key of the instance.
+	ID string
+	// Saga is the name of the saga.
+	Saga string
+	// Status is the status of the instance.
+	Status Status
+	// Step is the index of the current step.
+	Step int
+	// Data is the state of the instance.
+	Data any
+	// Deadline is the time that the current step times out, zero means no timeout.
+	Deadline time.Time
+	// Reason is the reason of compensation.
+	Reason string
+	// Version is increased every time the instance is saved.
+	Version int
+}
+
+// Store persists the saga instances.
+// A persistent Store must be able to serialize the Data of instances.
+type Store interface {
+	// Load returns the instance of saga, it returns ErrInstanceNotFound if the instance does not exist.
+	Load(ctx context.Context, saga string, id string) (Instance, error)
+
+	// Save saves the instance and increases its Version.
+	// It returns ErrVersionConflict if the stored Version is not inst.Version, a new instance has Version 0.
+	Save(ctx context.Context, inst *Instance) error
+
+	// Pending returns the instances of saga which are not done.
+	Pending(ctx context.Context, saga string) ([]Instance, error)
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// MemoryStore is an in-memory Store, the instances are lost when the process exits.
+type MemoryStore struct {
+	mu        sync.Mutex
+	instances map[[2]string]Instance
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{instances: make(map[[2]string]Instance)}
+}
+
+func (s *MemoryStore) Load(_ context.Context, saga string, id string) (Instance, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inst, ok := s.instances[[2]string{saga, id}]
+	if !ok {
+		return Instance{}, ErrInstanceNotFound
+	}
+	return inst, nil
+}
+
+func (s *MemoryStore) Save(_ context.Context, inst *Instance) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := [2]string{inst.Saga, inst.ID}
+	if s.instances[key].Version != inst.Version {
+		return ErrVersionConflict
+	}
+	inst.Version++
+	s.instances[key] = *inst
+	return nil
+}
+
+func (s *MemoryStore) Pending(_ context.Context, saga string) ([]Instance, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var pending []Instance
+	for key, inst := range s.instances {
+		if key[0] == saga && !inst.Status.Done() {
+			pending = append(pending, inst)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].ID < pending[j].ID })
+	return pending, nil
+}
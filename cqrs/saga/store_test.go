@@ -0,0 +1,37 @@
+package saga
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	_, err := store.Load(ctx, "order", "1")
+	assert.ErrorIs(t, err, ErrInstanceNotFound)
+
+	inst := &Instance{ID: "1", Saga: "order", Status: Running}
+	assert.NoError(t, store.Save(ctx, inst))
+	assert.Equal(t, 1, inst.Version)
+	assert.NoError(t, store.Save(ctx, &Instance{ID: "1", Saga: "shipment", Status: Completed}))
+
+	stale := *inst
+	inst.Status = Waiting
+	assert.NoError(t, store.Save(ctx, inst))
+	assert.ErrorIs(t, store.Save(ctx, &stale), ErrVersionConflict)
+	assert.ErrorIs(t, store.Save(ctx, &Instance{ID: "1", Saga: "order"}), ErrVersionConflict)
+
+	loaded, err := store.Load(ctx, "order", "1")
+	assert.NoError(t, err)
+	assert.Equal(t, *inst, loaded)
+
+	pending, err := store.Pending(ctx, "order")
+	assert.NoError(t, err)
+	assert.Equal(t, []Instance{*inst}, pending)
+	pending, err = store.Pending(ctx, "shipment")
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+}
@@ -0,0 +1,91 @@
+package scheduler
+
+import (
+	"time"
+)
+
+type option struct {
+	PollInterval time.Duration
+	Lease        time.Duration
+	BatchSize    int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	MaxAttempts  int
+	ErrorHandler func(job Job, err error)
+}
+
+func newOption(opts ...Option) *option {
+	o := &option{
+		BaseDelay: time.Second,
+		MaxDelay:  5 * time.Minute,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.PollInterval <= 0 {
+		o.PollInterval = time.Second
+	}
+	if o.Lease <= 0 {
+		o.Lease = 30 * time.Second
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = 100
+	}
+	if o.BaseDelay <= 0 {
+		o.BaseDelay = time.Second
+	}
+	if o.MaxDelay < o.BaseDelay {
+		o.MaxDelay = o.BaseDelay
+	}
+	return o
+}
+
+type Option func(*option)
+
+// PollInterval sets the interval of polling the due jobs from Store, default is 1s, and a non-positive interval means the default.
+func PollInterval(interval time.Duration) Option {
+	return func(o *option) {
+		o.PollInterval = interval
+	}
+}
+
+// Lease sets how long a job is held by the Scheduler that acquired it, default is 30s, and a non-positive lease means the default.
+// If the job is not done in the lease, e.g. the Scheduler crashed, it is executed again.
+// The lease should be longer than the execution of the commands.
+func Lease(lease time.Duration) Option {
+	return func(o *option) {
+		o.Lease = lease
+	}
+}
+
+// BatchSize sets the max number of jobs acquired in one poll, default is 100, and a non-positive size means the default.
+func BatchSize(size int) Option {
+	return func(o *option) {
+		o.BatchSize = size
+	}
+}
+
+// Backoff sets the delay before the first retry of a failed job and the max delay, default is 1s and 5m.
+// The delay doubles after each failed attempt. A non-positive base means the default,
+// and a max less than the base means the base.
+func Backoff(base, max time.Duration) Option {
+	return func(o *option) {
+		o.BaseDelay = base
+		o.MaxDelay = max
+	}
+}
+
+// MaxAttempts sets the max number of failed attempts of a job, default is 0, that means the job is retried forever.
+// A one-shot job is deleted after the last attempt fails, a recurring job is rescheduled to its next run.
+func MaxAttempts(n int) Option {
+	return func(o *option) {
+		o.MaxAttempts = n
+	}
+}
+
+// ErrorHandler sets the function that is called when a job fails or the Store fails.
+func ErrorHandler(handler func(job Job, err error)) Option {
+	return func(o *option) {
+		o.ErrorHandler = handler
+	}
+}
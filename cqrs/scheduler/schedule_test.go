@@ -0,0 +1,49 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSchedule(t *testing.T) {
+	from := time.Date(2024, time.January, 31, 10, 30, 15, 0, time.UTC) // Wednesday
+	tests := []struct {
+		spec string
+		next time.Time
+	}{
+		{spec: "@every 90s", next: from.Add(90 * time.Second)},
+		{spec: "* * * * *", next: time.Date(2024, time.January, 31, 10, 31, 0, 0, time.UTC)},
+		{spec: "*/15 * * * *", next: time.Date(2024, time.January, 31, 10, 45, 0, 0, time.UTC)},
+		{spec: "0 9-17/4 * * *", next: time.Date(2024, time.January, 31, 13, 0, 0, 0, time.UTC)},
+		{spec: "@daily", next: time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{spec: "@hourly", next: time.Date(2024, time.January, 31, 11, 0, 0, 0, time.UTC)},
+		{spec: "@monthly", next: time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 0 30 * *", next: time.Date(2024, time.March, 30, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 0 29 2 *", next: time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{spec: "30 8 * * 1,7", next: time.Date(2024, time.February, 4, 8, 30, 0, 0, time.UTC)},
+		// day of month or day of week
+		{spec: "0 0 15 * 5", next: time.Date(2024, time.February, 2, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		t.Run(test.spec, func(t *testing.T) {
+			schedule, err := ParseSchedule(test.spec)
+			assert.NoError(t, err)
+			assert.Equal(t, test.next, schedule.Next(from))
+		})
+	}
+}
+
+func TestParseSchedule_Invalid(t *testing.T) {
+	for _, spec := range []string{"", "@every", "@every -1s", "* * * *", "60 * * * *", "* * 0 * *", "5-1 * * * *", "*/0 * * * *", "a * * * *"} {
+		_, err := ParseSchedule(spec)
+		assert.ErrorIs(t, err, ErrScheduleInvalid, spec)
+	}
+}
+
+func TestParseSchedule_Never(t *testing.T) {
+	schedule, err := ParseSchedule("0 0 31 2 *")
+	assert.NoError(t, err)
+	assert.True(t, schedule.Next(time.Now()).IsZero())
+}
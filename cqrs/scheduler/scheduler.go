@@ -0,0 +1,177 @@
+// Package scheduler executes the commands of cqrs.Bus at a future time or on a recurring schedule.
+//
+// The pending commands are persisted in a Store, a job is deleted or rescheduled only after its command succeeds,
+// so every command is executed at least once, even if the process restarts.
+// The CommandHandler should be idempotent, it can get the ID of job by JobID.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-leo/design-pattern/cqrs"
+	"github.com/google/uuid"
+)
+
+// ErrArgsNil args is nil
+var ErrArgsNil = errors.New("scheduler: args is nil")
+
+// Scheduler dispatches the due jobs of Store through cqrs.Bus.Exec.
+type Scheduler struct {
+	bus     cqrs.Bus
+	store   Store
+	options *option
+	now     func() time.Time
+}
+
+// New returns a Scheduler that executes the commands on bus, and persists the jobs in store.
+func New(bus cqrs.Bus, store Store, opts ...Option) *Scheduler {
+	return &Scheduler{bus: bus, store: store, options: newOption(opts...), now: time.Now}
+}
+
+// Schedule schedules the command args to execute at when, it returns the ID of job.
+// If when is in the past, the command is executed in the next poll.
+func (s *Scheduler) Schedule(ctx context.Context, when time.Time, args any) (string, error) {
+	if args == nil {
+		return "", ErrArgsNil
+	}
+	job := Job{ID: uuid.NewString(), Args: args, RunAt: when}
+	if err := s.store.Add(ctx, job); err != nil {
+		return "", err
+	}
+	return job.ID, nil
+}
+
+// After schedules the command args to execute after delay, it returns the ID of job.
+func (s *Scheduler) After(ctx context.Context, delay time.Duration, args any) (string, error) {
+	return s.Schedule(ctx, s.now().Add(delay), args)
+}
+
+// Repeat schedules the command args to execute on the schedule spec, see ParseSchedule, it returns the ID of job.
+// The job is executed until it is canceled.
+func (s *Scheduler) Repeat(ctx context.Context, spec string, args any) (string, error) {
+	if args == nil {
+		return "", ErrArgsNil
+	}
+	schedule, err := ParseSchedule(spec)
+	if err != nil {
+		return "", err
+	}
+	next := schedule.Next(s.now())
+	if next.IsZero() {
+		return "", ErrScheduleInvalid
+	}
+	job := Job{ID: uuid.NewString(), Args: args, RunAt: next, Schedule: spec}
+	if err := s.store.Add(ctx, job); err != nil {
+		return "", err
+	}
+	return job.ID, nil
+}
+
+// Cancel cancels the job, it returns ErrJobNotFound if the job has been done or canceled.
+// A command that is executing is not interrupted.
+func (s *Scheduler) Cancel(ctx context.Context, id string) error {
+	return s.store.Delete(ctx, id)
+}
+
+// Run polls and executes the due jobs until ctx is done, it returns the error of ctx.
+func (s *Scheduler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.options.PollInterval)
+	defer ticker.Stop()
+	for {
+		s.poll(ctx)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll acquires the due jobs and executes them one by one.
+func (s *Scheduler) poll(ctx context.Context) {
+	for ctx.Err() == nil {
+		now := s.now()
+		jobs, err := s.store.Acquire(ctx, now, now.Add(s.options.Lease), s.options.BatchSize)
+		if err != nil {
+			s.handleError(Job{}, err)
+			return
+		}
+		for _, job := range jobs {
+			if ctx.Err() != nil {
+				return
+			}
+			s.dispatch(ctx, job)
+		}
+		if len(jobs) < s.options.BatchSize {
+			return
+		}
+	}
+}
+
+// dispatch executes the job, and then deletes or reschedules it.
+func (s *Scheduler) dispatch(ctx context.Context, job Job) {
+	execErr := s.bus.Exec(context.WithValue(ctx, jobIDKey{}, job.ID), job.Args)
+	// the result is stored even if ctx is canceled, otherwise the job is executed again after the lease.
+	storeCtx := context.WithoutCancel(ctx)
+	if execErr != nil {
+		s.handleError(job, execErr)
+		job.Attempts++
+		if s.options.MaxAttempts <= 0 || job.Attempts < s.options.MaxAttempts {
+			job.RunAt = s.now().Add(s.backoff(job.Attempts))
+			s.update(storeCtx, job)
+			return
+		}
+	}
+	if job.Schedule == "" {
+		if err := s.store.Delete(storeCtx, job.ID); err != nil && !errors.Is(err, ErrJobNotFound) {
+			s.handleError(job, err)
+		}
+		return
+	}
+	schedule, err := ParseSchedule(job.Schedule)
+	if err != nil {
+		s.handleError(job, err)
+		return
+	}
+	job.Attempts = 0
+	job.RunAt = schedule.Next(s.now())
+	if job.RunAt.IsZero() {
+		if err := s.store.Delete(storeCtx, job.ID); err != nil && !errors.Is(err, ErrJobNotFound) {
+			s.handleError(job, err)
+		}
+		return
+	}
+	s.update(storeCtx, job)
+}
+
+func (s *Scheduler) update(ctx context.Context, job Job) {
+	// ErrJobNotFound means the job was canceled while it was executing.
+	if err := s.store.Update(ctx, job); err != nil && !errors.Is(err, ErrJobNotFound) {
+		s.handleError(job, err)
+	}
+}
+
+// backoff returns the delay before the retry after attempts failed attempts.
+func (s *Scheduler) backoff(attempts int) time.Duration {
+	delay := s.options.BaseDelay
+	for i := 1; i < attempts && delay < s.options.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, s.options.MaxDelay)
+}
+
+func (s *Scheduler) handleError(job Job, err error) {
+	if s.options.ErrorHandler != nil {
+		s.options.ErrorHandler(job, err)
+	}
+}
+
+type jobIDKey struct{}
+
+// JobID returns the ID of job that the command is executed for, it is used to deduplicate the redelivered commands.
+func JobID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(jobIDKey{}).(string)
+	return id, ok
+}
@@ -0,0 +1,183 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-leo/design-pattern/cqrs"
+	"github.com/stretchr/testify/assert"
+)
+
+type expireReservationCmd struct {
+	ID int
+}
+
+type recorder struct {
+	mu    sync.Mutex
+	calls []int
+	jobs  []string
+	fail  int // the number of calls that fail
+}
+
+func (r *recorder) Handle(ctx context.Context, cmd expireReservationCmd) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, cmd.ID)
+	id, _ := JobID(ctx)
+	r.jobs = append(r.jobs, id)
+	if r.fail > 0 {
+		r.fail--
+		return cqrs.Transient(errors.New("unavailable"))
+	}
+	return nil
+}
+
+func (r *recorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.calls)
+}
+
+func newTestScheduler(t *testing.T, store Store, opts ...Option) (*Scheduler, *recorder) {
+	rec := &recorder{}
+	bus := cqrs.NewBus()
+	assert.NoError(t, cqrs.Register[expireReservationCmd](bus, rec))
+	opts = append([]Option{PollInterval(5 * time.Millisecond), Backoff(time.Millisecond, 10*time.Millisecond)}, opts...)
+	return New(bus, store, opts...), rec
+}
+
+func run(t *testing.T, s *Scheduler) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		assert.ErrorIs(t, <-done, context.Canceled)
+	})
+}
+
+func TestScheduler_Schedule(t *testing.T) {
+	store := NewMemoryStore()
+	s, rec := newTestScheduler(t, store)
+	ctx := context.Background()
+
+	later, err := s.After(ctx, time.Hour, expireReservationCmd{ID: 2})
+	assert.NoError(t, err)
+	id, err := s.Schedule(ctx, time.Now().Add(20*time.Millisecond), expireReservationCmd{ID: 1})
+	assert.NoError(t, err)
+	_, err = s.Schedule(ctx, time.Now(), nil)
+	assert.ErrorIs(t, err, ErrArgsNil)
+	run(t, s)
+
+	assert.Eventually(t, func() bool { return rec.count() == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, []int{1}, rec.calls)
+	assert.Equal(t, []string{id}, rec.jobs)
+	assert.Equal(t, 1, store.Len())
+
+	assert.NoError(t, s.Cancel(ctx, later))
+	assert.ErrorIs(t, s.Cancel(ctx, later), ErrJobNotFound)
+	assert.ErrorIs(t, s.Cancel(ctx, id), ErrJobNotFound)
+	assert.Equal(t, 0, store.Len())
+}
+
+func TestScheduler_Retry(t *testing.T) {
+	store := NewMemoryStore()
+	var mu sync.Mutex
+	var errs []error
+	s, rec := newTestScheduler(t, store, ErrorHandler(func(job Job, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs = append(errs, err)
+	}))
+	rec.fail = 2
+	_, err := s.Schedule(context.Background(), time.Now(), expireReservationCmd{ID: 1})
+	assert.NoError(t, err)
+	run(t, s)
+
+	assert.Eventually(t, func() bool { return store.Len() == 0 }, time.Second, time.Millisecond)
+	assert.Equal(t, 3, rec.count())
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Len(t, errs, 2)
+	assert.ErrorIs(t, errs[0], cqrs.ErrTransient)
+}
+
+func TestScheduler_MaxAttempts(t *testing.T) {
+	store := NewMemoryStore()
+	s, rec := newTestScheduler(t, store, MaxAttempts(2))
+	rec.fail = 5
+	_, err := s.Schedule(context.Background(), time.Now(), expireReservationCmd{ID: 1})
+	assert.NoError(t, err)
+	run(t, s)
+
+	assert.Eventually(t, func() bool { return store.Len() == 0 }, time.Second, time.Millisecond)
+	assert.Equal(t, 2, rec.count())
+}
+
+func TestScheduler_Repeat(t *testing.T) {
+	store := NewMemoryStore()
+	s, rec := newTestScheduler(t, store)
+	ctx := context.Background()
+	_, err := s.Repeat(ctx, "@every -1s", expireReservationCmd{ID: 1})
+	assert.ErrorIs(t, err, ErrScheduleInvalid)
+	id, err := s.Repeat(ctx, Every(10*time.Millisecond), expireReservationCmd{ID: 1})
+	assert.NoError(t, err)
+	run(t, s)
+
+	assert.Eventually(t, func() bool { return rec.count() >= 3 }, time.Second, time.Millisecond)
+	assert.Equal(t, 1, store.Len())
+	assert.NoError(t, s.Cancel(ctx, id))
+	count := rec.count()
+	time.Sleep(50 * time.Millisecond)
+	assert.LessOrEqual(t, rec.count(), count+1)
+	assert.Equal(t, 0, store.Len())
+}
+
+func TestScheduler_Redelivery(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	crashed, _ := newTestScheduler(t, store, Lease(30*time.Millisecond))
+	_, err := crashed.Schedule(ctx, time.Now(), expireReservationCmd{ID: 1})
+	assert.NoError(t, err)
+
+	// the crashed scheduler acquired the job but never finished it
+	now := time.Now()
+	jobs, err := store.Acquire(ctx, now, now.Add(30*time.Millisecond), 10)
+	assert.NoError(t, err)
+	assert.Len(t, jobs, 1)
+	jobs, err = store.Acquire(ctx, now, now.Add(30*time.Millisecond), 10)
+	assert.NoError(t, err)
+	assert.Empty(t, jobs)
+
+	// the restarted scheduler executes it after the lease expires
+	restarted, rec := newTestScheduler(t, store)
+	run(t, restarted)
+	assert.Eventually(t, func() bool { return store.Len() == 0 }, time.Second, time.Millisecond)
+	assert.Equal(t, 1, rec.count())
+	assert.GreaterOrEqual(t, time.Since(now), 30*time.Millisecond)
+}
+
+func TestScheduler_InvalidOptions(t *testing.T) {
+	o := newOption(PollInterval(0), Lease(-time.Second), BatchSize(0))
+	assert.Equal(t, time.Second, o.PollInterval)
+	assert.Equal(t, 30*time.Second, o.Lease)
+	assert.Equal(t, 100, o.BatchSize)
+	o = newOption(Backoff(0, time.Minute))
+	assert.Equal(t, time.Second, o.BaseDelay)
+	assert.Equal(t, time.Minute, o.MaxDelay)
+	o = newOption(Backoff(time.Minute, time.Second))
+	assert.Equal(t, time.Minute, o.BaseDelay)
+	assert.Equal(t, time.Minute, o.MaxDelay)
+
+	// a poll without due jobs returns
+	store := NewMemoryStore()
+	s, rec := newTestScheduler(t, store, BatchSize(0))
+	s.poll(context.Background())
+	_, err := s.Schedule(context.Background(), time.Now(), expireReservationCmd{ID: 1})
+	assert.NoError(t, err)
+	run(t, s)
+	assert.Eventually(t, func() bool { return rec.count() == 1 }, time.Second, time.Millisecond)
+}
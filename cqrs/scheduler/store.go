@@ -0,0 +1,119 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrJobNotFound job does not exist, it has been done or canceled
+var ErrJobNotFound = errors.New("scheduler: job not found")
+
+// Job is a command that is pending to be executed.
+type Job struct {
+	// ID identifies the job.
+	ID string
+	// Args is the arguments of the command.
+	Args any
+	// RunAt is the next time the job is due.
+	RunAt time.Time
+	// Schedule is the spec of Schedule of recurring job, it is empty for one-shot job.
+	Schedule string
+	// Attempts is the number of failed executions since the last success.
+	Attempts int
+	// LeaseUntil is the time until the job is held by a Scheduler,
+	// the job is due again after the lease expires, so it is redelivered if the Scheduler crashed.
+	LeaseUntil time.Time
+}
+
+// Store persists the pending jobs.
+// A persistent Store must be able to serialize the Args of jobs, e.g. by remote.Registry.
+type Store interface {
+	// Add persists a new job.
+	Add(ctx context.Context, job Job) error
+
+	// Acquire leases at most limit jobs which are due at now and not leased, ordered by RunAt.
+	// The leases expire at leaseUntil.
+	Acquire(ctx context.Context, now time.Time, leaseUntil time.Time, limit int) ([]Job, error)
+
+	// Update updates the RunAt and Attempts of the job and releases its lease.
+	// It returns ErrJobNotFound if the job was deleted.
+	Update(ctx context.Context, job Job) error
+
+	// Delete deletes the job, it returns ErrJobNotFound if the job does not exist.
+	Delete(ctx context.Context, id string) error
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// MemoryStore is an in-memory Store, the jobs are lost when the process exits.
+type MemoryStore struct {
+	mu   sync.Mutex
+	jobs map[string]Job
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{jobs: make(map[string]Job)}
+}
+
+func (s *MemoryStore) Add(_ context.Context, job Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[job.ID] = job
+	return nil
+}
+
+func (s *MemoryStore) Acquire(_ context.Context, now time.Time, leaseUntil time.Time, limit int) ([]Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var due []Job
+	for _, job := range s.jobs {
+		if job.RunAt.After(now) || job.LeaseUntil.After(now) {
+			continue
+		}
+		due = append(due, job)
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].RunAt.Before(due[j].RunAt) })
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+	for i := range due {
+		due[i].LeaseUntil = leaseUntil
+		s.jobs[due[i].ID] = due[i]
+	}
+	return due, nil
+}
+
+func (s *MemoryStore) Update(_ context.Context, job Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.jobs[job.ID]
+	if !ok {
+		return ErrJobNotFound
+	}
+	stored.RunAt = job.RunAt
+	stored.Attempts = job.Attempts
+	stored.LeaseUntil = time.Time{}
+	s.jobs[job.ID] = stored
+	return nil
+}
+
+func (s *MemoryStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.jobs[id]; !ok {
+		return ErrJobNotFound
+	}
+	delete(s.jobs, id)
+	return nil
+}
+
+// Len returns the number of pending jobs.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.jobs)
+}
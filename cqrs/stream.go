@@ -0,0 +1,98 @@
+package cqrs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+// ErrStreamClosed stream was closed by the consumer
+var ErrStreamClosed = errors.New("cqrs: stream was closed")
+
+// Stream is the results of a streaming query.
+type Stream[T any] interface {
+	// Recv blocks until the next result is sent by the StreamQueryHandler.
+	// It returns io.EOF after the last result, or the error returned by the StreamQueryHandler.
+	Recv() (T, error)
+
+	// Close stops the stream, the send of StreamQueryHandler returns ErrStreamClosed.
+	// Close must be called if the stream is not read until Recv returns an error.
+	Close() error
+}
+
+// pipe is a Stream, the results are passed without buffer,
+// so that the producer is blocked until the consumer is ready.
+type pipe[T any] struct {
+	results chan T
+	done    chan struct{} // closed when the producer ends
+	err     error         // error of the producer, it is readable after done is closed
+	ctx     context.Context
+	cancel  context.CancelCauseFunc
+	once    sync.Once
+}
+
+func newPipe[T any](ctx context.Context) *pipe[T] {
+	ctx, cancel := context.WithCancelCause(ctx)
+	return &pipe[T]{
+		results: make(chan T),
+		done:    make(chan struct{}),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// send passes the result to the consumer.
+func (p *pipe[T]) send(res T) error {
+	select {
+	case p.results <- res:
+		return nil
+	case <-p.ctx.Done():
+		return context.Cause(p.ctx)
+	}
+}
+
+// end ends the producer with err.
+func (p *pipe[T]) end(err error) {
+	p.once.Do(func() {
+		p.err = err
+		close(p.done)
+		p.cancel(nil)
+	})
+}
+
+func (p *pipe[T]) Recv() (T, error) {
+	select {
+	case res := <-p.results:
+		return res, nil
+	case <-p.done:
+		if p.err != nil {
+			return *(new(T)), p.err
+		}
+		return *(new(T)), io.EOF
+	}
+}
+
+func (p *pipe[T]) Close() error {
+	p.cancel(ErrStreamClosed)
+	return nil
+}
+
+// MapStream returns a Stream that converts the results of stream by fn.
+// If fn returns an error, Recv returns it, the stream is not closed.
+func MapStream[T any, R any](stream Stream[T], fn func(res T) (R, error)) Stream[R] {
+	return &mapStream[T, R]{Stream: stream, fn: fn}
+}
+
+type mapStream[T any, R any] struct {
+	Stream[T]
+	fn func(res T) (R, error)
+}
+
+func (s *mapStream[T, R]) Recv() (R, error) {
+	res, err := s.Stream.Recv()
+	if err != nil {
+		return *(new(R)), err
+	}
+	return s.fn(res)
+}
@@ -0,0 +1,131 @@
+package cqrs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type rangeQuery struct {
+	From, To int
+}
+
+type rangeHandler struct{}
+
+func (rangeHandler) Handle(ctx context.Context, q *rangeQuery, send func(int) error) error {
+	for i := q.From; i < q.To; i++ {
+		if err := send(i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func recvAll[T any](stream Stream[T]) ([]T, error) {
+	var results []T
+	for {
+		res, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return results, nil
+		}
+		if err != nil {
+			return results, err
+		}
+		results = append(results, res)
+	}
+}
+
+func TestQueryStream(t *testing.T) {
+	bus := NewBus()
+	assert.NoError(t, bus.RegisterQuery(rangeHandler{}))
+	infos := bus.Handlers()
+	assert.Len(t, infos, 1)
+	assert.Equal(t, StreamQueryKind, infos[0].Kind)
+
+	// reflected
+	stream, err := bus.QueryStream(context.Background(), &rangeQuery{From: 1, To: 4})
+	assert.NoError(t, err)
+	results, err := recvAll(stream)
+	assert.NoError(t, err)
+	assert.Equal(t, []any{1, 2, 3}, results)
+
+	_, err = bus.Query(context.Background(), &rangeQuery{})
+	assert.ErrorIs(t, err, ErrUnregistered)
+	assert.NoError(t, bus.UnregisterQuery(&rangeQuery{}))
+
+	// typed
+	errOdd := errors.New("odd")
+	assert.NoError(t, RegisterStreamQuery[rangeQuery, int](bus, StreamQueryHandlerFunc[rangeQuery, int](func(ctx context.Context, q rangeQuery, send func(int) error) error {
+		for i := q.From; i < q.To; i++ {
+			if i < 0 {
+				panic("negative")
+			}
+			if i%2 == 1 {
+				return errOdd
+			}
+			if err := send(i); err != nil {
+				return err
+			}
+		}
+		return nil
+	})))
+	typed, err := QueryStream[rangeQuery, int](context.Background(), bus, rangeQuery{From: 0, To: 3})
+	assert.NoError(t, err)
+	ints, err := recvAll(typed)
+	assert.ErrorIs(t, err, errOdd)
+	assert.Equal(t, []int{0}, ints)
+
+	typed, err = QueryStream[rangeQuery, int](context.Background(), bus, rangeQuery{From: -1, To: 0})
+	assert.NoError(t, err)
+	_, err = recvAll(typed)
+	var panicErr *PanicError
+	assert.ErrorAs(t, err, &panicErr)
+
+	_, err = QueryStream[*rangeQuery, int](context.Background(), bus, &rangeQuery{})
+	assert.ErrorIs(t, err, ErrUnregistered)
+}
+
+func TestQueryStream_Backpressure(t *testing.T) {
+	sent := make(chan int, 10)
+	stopped := make(chan error, 1)
+	bus := NewBus()
+	assert.NoError(t, RegisterStreamQuery[rangeQuery, int](bus, StreamQueryHandlerFunc[rangeQuery, int](func(ctx context.Context, q rangeQuery, send func(int) error) error {
+		for i := q.From; ; i++ {
+			if err := send(i); err != nil {
+				stopped <- err
+				return err
+			}
+			sent <- i
+		}
+	})))
+	stream, err := QueryStream[rangeQuery, int](context.Background(), bus, rangeQuery{})
+	assert.NoError(t, err)
+
+	// the handler is blocked until the result is received
+	time.Sleep(10 * time.Millisecond)
+	assert.Len(t, sent, 0)
+	res, err := stream.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, res)
+	assert.Equal(t, 0, <-sent)
+	time.Sleep(10 * time.Millisecond)
+	assert.Len(t, sent, 0)
+
+	// the bus waits for the streaming query until it is closed
+	closeErr := make(chan error)
+	go func() { closeErr <- bus.Close(context.Background()) }()
+	select {
+	case <-closeErr:
+		t.Fatal("bus closed before the stream ends")
+	case <-time.After(10 * time.Millisecond):
+	}
+	assert.NoError(t, stream.Close())
+	assert.ErrorIs(t, <-stopped, ErrStreamClosed)
+	assert.NoError(t, <-closeErr)
+	_, err = stream.Recv()
+	assert.ErrorIs(t, err, ErrStreamClosed)
+}
@@ -0,0 +1,96 @@
+package cqrs
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/go-leo/design-pattern/event"
+)
+
+// Transactor is the unit of work that maintains the transaction that a command is executed in.
+// The transaction is carried by the context, a Begin with a context that already carries
+// a transaction joins it, and the joined Commit and Rollback do nothing.
+type Transactor interface {
+	// Begin begins a transaction, and returns a context that carries the transaction.
+	Begin(ctx context.Context) (context.Context, error)
+
+	// Commit commits the transaction carried by ctx.
+	Commit(ctx context.Context) error
+
+	// Rollback rolls back the transaction carried by ctx.
+	Rollback(ctx context.Context) error
+}
+
+// Outbox is optionally implemented by Transactor, to write the events raised by a command
+// in the transaction of the command, before the transaction is committed.
+type Outbox interface {
+	// Write writes the events in the transaction carried by ctx.
+	Write(ctx context.Context, events []event.Event) error
+}
+
+// switchableOutbox is implemented by an Outbox that may be disabled, e.g. a SQLUnitOfWork without SQLOutbox,
+// whose Write does nothing.
+type switchableOutbox interface {
+	OutboxEnabled() bool
+}
+
+// outboxOf returns the Outbox of uow, ok is false if uow doesn't implement Outbox or its outbox is disabled.
+func outboxOf(uow Transactor) (Outbox, bool) {
+	outbox, ok := uow.(Outbox)
+	if !ok {
+		return nil, false
+	}
+	if switchable, ok := outbox.(switchableOutbox); ok && !switchable.OutboxEnabled() {
+		return nil, false
+	}
+	return outbox, true
+}
+
+// transact executes the command in a transaction of the Transactor.
+// The transaction is rolled back if the handler returns an error or panics.
+func transact(ctx context.Context, uow Transactor, h handler, args any) (res any, events []event.Event, err error) {
+	ctx, err = uow.Begin(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = uow.Rollback(ctx)
+			panic(p)
+		}
+	}()
+	res, events, err = h.Exec(ctx, args)
+	if err != nil {
+		return nil, nil, errors.Join(err, uow.Rollback(ctx))
+	}
+	if outbox, ok := outboxOf(uow); ok && len(events) > 0 {
+		if err := outbox.Write(ctx, events); err != nil {
+			return nil, nil, errors.Join(err, uow.Rollback(ctx))
+		}
+	}
+	if err := uow.Commit(ctx); err != nil {
+		return nil, nil, err
+	}
+	return res, events, nil
+}
+
+type pendingEventsKey struct{}
+
+// pendingEvents collects the events raised by the commands executed in the transaction of another command.
+type pendingEvents struct {
+	mu     sync.Mutex
+	events []event.Event
+}
+
+func (p *pendingEvents) Append(events []event.Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, events...)
+}
+
+func (p *pendingEvents) Load() []event.Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.events
+}
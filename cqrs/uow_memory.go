@@ -0,0 +1,145 @@
+package cqrs
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/go-leo/design-pattern/event"
+)
+
+// ErrNoTransaction context does not carry a transaction
+var ErrNoTransaction = errors.New("cqrs: context does not carry a transaction")
+
+var _ Transactor = (*MemoryUnitOfWork)(nil)
+var _ Outbox = (*MemoryUnitOfWork)(nil)
+
+// MemoryUnitOfWork is an in-memory Transactor for tests.
+// Functions registered by OnCommit and events written to the outbox are applied only when the transaction commits.
+type MemoryUnitOfWork struct {
+	mu         sync.Mutex
+	began      int
+	committed  int
+	rolledBack int
+	outbox     []event.Event
+}
+
+type memoryTxKey struct{}
+
+type memoryTx struct {
+	uow      *MemoryUnitOfWork
+	parent   *memoryTx // the joined transaction
+	onCommit []func()
+	events   []event.Event
+}
+
+func (tx *memoryTx) root() *memoryTx {
+	for tx.parent != nil {
+		tx = tx.parent
+	}
+	return tx
+}
+
+func (uow *MemoryUnitOfWork) Begin(ctx context.Context) (context.Context, error) {
+	if tx, err := uow.tx(ctx); err == nil {
+		return context.WithValue(ctx, memoryTxKey{}, &memoryTx{uow: uow, parent: tx}), nil
+	}
+	uow.mu.Lock()
+	uow.began++
+	uow.mu.Unlock()
+	return context.WithValue(ctx, memoryTxKey{}, &memoryTx{uow: uow}), nil
+}
+
+func (uow *MemoryUnitOfWork) Commit(ctx context.Context) error {
+	tx, err := uow.tx(ctx)
+	if err != nil {
+		return err
+	}
+	if tx.parent != nil {
+		return nil
+	}
+	for _, fn := range tx.onCommit {
+		fn()
+	}
+	uow.mu.Lock()
+	defer uow.mu.Unlock()
+	uow.committed++
+	uow.outbox = append(uow.outbox, tx.events...)
+	return nil
+}
+
+func (uow *MemoryUnitOfWork) Rollback(ctx context.Context) error {
+	tx, err := uow.tx(ctx)
+	if err != nil {
+		return err
+	}
+	if tx.parent != nil {
+		return nil
+	}
+	uow.mu.Lock()
+	defer uow.mu.Unlock()
+	uow.rolledBack++
+	return nil
+}
+
+func (uow *MemoryUnitOfWork) Write(ctx context.Context, events []event.Event) error {
+	tx, err := uow.tx(ctx)
+	if err != nil {
+		return err
+	}
+	tx = tx.root()
+	tx.events = append(tx.events, events...)
+	return nil
+}
+
+// OnCommit registers fn to be called when the transaction carried by ctx commits.
+func (uow *MemoryUnitOfWork) OnCommit(ctx context.Context, fn func()) error {
+	tx, err := uow.tx(ctx)
+	if err != nil {
+		return err
+	}
+	tx = tx.root()
+	tx.onCommit = append(tx.onCommit, fn)
+	return nil
+}
+
+// Began returns the number of began transactions.
+func (uow *MemoryUnitOfWork) Began() int {
+	uow.mu.Lock()
+	defer uow.mu.Unlock()
+	return uow.began
+}
+
+// Committed returns the number of committed transactions.
+func (uow *MemoryUnitOfWork) Committed() int {
+	uow.mu.Lock()
+	defer uow.mu.Unlock()
+	return uow.committed
+}
+
+// RolledBack returns the number of rolled back transactions.
+func (uow *MemoryUnitOfWork) RolledBack() int {
+	uow.mu.Lock()
+	defer uow.mu.Unlock()
+	return uow.rolledBack
+}
+
+// Outbox returns a copy of the events written by the committed transactions.
+func (uow *MemoryUnitOfWork) Outbox() []event.Event {
+	uow.mu.Lock()
+	defer uow.mu.Unlock()
+	return append([]event.Event(nil), uow.outbox...)
+}
+
+func (uow *MemoryUnitOfWork) tx(ctx context.Context) (*memoryTx, error) {
+	tx, ok := ctx.Value(memoryTxKey{}).(*memoryTx)
+	if !ok || tx.uow != uow {
+		return nil, ErrNoTransaction
+	}
+	return tx, nil
+}
+
+// NewMemoryUnitOfWork returns an in-memory Transactor.
+func NewMemoryUnitOfWork() *MemoryUnitOfWork {
+	return &MemoryUnitOfWork{}
+}
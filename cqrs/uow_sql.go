@@ -0,0 +1,153 @@
+package cqrs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-leo/design-pattern/event"
+)
+
+var _ Transactor = (*SQLUnitOfWork)(nil)
+var _ Outbox = (*SQLUnitOfWork)(nil)
+
+// SQLUnitOfWork is a Transactor based on database/sql, handlers get the transaction by Tx.
+// If the outbox is enabled by SQLOutbox, the events raised by a command are inserted
+// into the outbox table in the transaction of the command.
+type SQLUnitOfWork struct {
+	db      *sql.DB
+	options *sqlOption
+}
+
+type sqlTxKey struct{}
+
+type sqlTx struct {
+	tx     *sql.Tx
+	joined bool
+}
+
+// Tx returns the transaction carried by ctx.
+func Tx(ctx context.Context) (*sql.Tx, bool) {
+	tx, ok := ctx.Value(sqlTxKey{}).(*sqlTx)
+	if !ok {
+		return nil, false
+	}
+	return tx.tx, true
+}
+
+func (uow *SQLUnitOfWork) Begin(ctx context.Context) (context.Context, error) {
+	if tx, ok := ctx.Value(sqlTxKey{}).(*sqlTx); ok {
+		return context.WithValue(ctx, sqlTxKey{}, &sqlTx{tx: tx.tx, joined: true}), nil
+	}
+	tx, err := uow.db.BeginTx(ctx, uow.options.TxOptions)
+	if err != nil {
+		return ctx, err
+	}
+	return context.WithValue(ctx, sqlTxKey{}, &sqlTx{tx: tx}), nil
+}
+
+func (uow *SQLUnitOfWork) Commit(ctx context.Context) error {
+	tx, ok := ctx.Value(sqlTxKey{}).(*sqlTx)
+	if !ok {
+		return ErrNoTransaction
+	}
+	if tx.joined {
+		return nil
+	}
+	return tx.tx.Commit()
+}
+
+func (uow *SQLUnitOfWork) Rollback(ctx context.Context) error {
+	tx, ok := ctx.Value(sqlTxKey{}).(*sqlTx)
+	if !ok {
+		return ErrNoTransaction
+	}
+	if tx.joined {
+		return nil
+	}
+	return tx.tx.Rollback()
+}
+
+// OutboxEnabled reports whether the outbox is enabled by SQLOutbox.
+func (uow *SQLUnitOfWork) OutboxEnabled() bool {
+	return uow.options.OutboxTable != ""
+}
+
+// Write inserts the events into the outbox table in the transaction carried by ctx,
+// it does nothing if the outbox is not enabled.
+func (uow *SQLUnitOfWork) Write(ctx context.Context, events []event.Event) error {
+	if !uow.OutboxEnabled() {
+		return nil
+	}
+	tx, ok := Tx(ctx)
+	if !ok {
+		return ErrNoTransaction
+	}
+	query := fmt.Sprintf(uow.options.OutboxInsert, uow.options.OutboxTable)
+	for _, e := range events {
+		body, err := uow.options.Marshal(e.Body())
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, query, fmt.Sprint(e.ID()), e.Type().String(), body, e.When()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type sqlOption struct {
+	TxOptions    *sql.TxOptions
+	OutboxTable  string
+	OutboxInsert string
+	Marshal      func(v any) ([]byte, error)
+}
+
+type SQLOption func(o *sqlOption)
+
+// TxOptions sets the options of the transactions.
+func TxOptions(opts *sql.TxOptions) SQLOption {
+	return func(o *sqlOption) {
+		o.TxOptions = opts
+	}
+}
+
+// SQLOutbox enables the outbox, the events are inserted into the table,
+// the columns are event_id, event_type, body and occurred_on.
+func SQLOutbox(table string) SQLOption {
+	return func(o *sqlOption) {
+		o.OutboxTable = table
+	}
+}
+
+// SQLOutboxInsert sets the insert statement of outbox, the %s verb is replaced with the table,
+// the parameters are event id, event type, body and occurred time,
+// the default is "INSERT INTO %s (event_id, event_type, body, occurred_on) VALUES (?, ?, ?, ?)".
+func SQLOutboxInsert(query string) SQLOption {
+	return func(o *sqlOption) {
+		o.OutboxInsert = query
+	}
+}
+
+// SQLOutboxMarshal sets the function that marshals the body of events, the default is json.Marshal.
+func SQLOutboxMarshal(marshal func(v any) ([]byte, error)) SQLOption {
+	return func(o *sqlOption) {
+		o.Marshal = marshal
+	}
+}
+
+// NewSQLUnitOfWork returns a Transactor that begins the transactions by db.
+func NewSQLUnitOfWork(db *sql.DB, opts ...SQLOption) *SQLUnitOfWork {
+	o := &sqlOption{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.OutboxInsert == "" {
+		o.OutboxInsert = "INSERT INTO %s (event_id, event_type, body, occurred_on) VALUES (?, ?, ?, ?)"
+	}
+	if o.Marshal == nil {
+		o.Marshal = json.Marshal
+	}
+	return &SQLUnitOfWork{db: db, options: o}
+}
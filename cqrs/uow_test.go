@@ -0,0 +1,216 @@
+package cqrs
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/go-leo/design-pattern/event"
+	"github.com/stretchr/testify/assert"
+)
+
+type payCmd struct {
+	Amount int
+}
+
+type paid struct {
+	Amount int
+}
+
+type refundCmd struct {
+	Amount int
+}
+
+var errInsufficient = errors.New("insufficient")
+
+func TestUnitOfWork(t *testing.T) {
+	var published []int
+	eventBus := event.NewBus()
+	assert.NoError(t, eventBus.On(event.NewEvent(orderCreated{}, nil), orderListener{created: &published}))
+
+	uow := NewMemoryUnitOfWork()
+	bus := NewBus(EventBus(eventBus), UnitOfWork(uow))
+	balance := 10
+	assert.NoError(t, RegisterResult[*payCmd, int](bus, CommandResultHandlerFunc[*payCmd, int](func(ctx context.Context, cmd *payCmd) (int, []event.Event, error) {
+		if cmd.Amount < 0 {
+			panic("negative amount")
+		}
+		if cmd.Amount > balance {
+			return 0, nil, errInsufficient
+		}
+		if err := uow.OnCommit(ctx, func() { balance -= cmd.Amount }); err != nil {
+			return 0, nil, err
+		}
+		return balance - cmd.Amount, []event.Event{event.NewEvent(orderCreated{ID: cmd.Amount}, nil)}, nil
+	})))
+	assert.NoError(t, RegisterResult[*refundCmd, int](bus, CommandResultHandlerFunc[*refundCmd, int](func(ctx context.Context, cmd *refundCmd) (int, []event.Event, error) {
+		// pay in the transaction of refund
+		if err := bus.Exec(ctx, &payCmd{Amount: cmd.Amount}); err != nil {
+			return 0, nil, err
+		}
+		if len(published) != 1 {
+			return 0, nil, errors.New("nested events are published before commit")
+		}
+		return 0, []event.Event{event.NewEvent(orderCreated{ID: -cmd.Amount}, nil)}, nil
+	})))
+
+	left, err := ExecResult[*payCmd, int](context.Background(), bus, &payCmd{Amount: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, 7, left)
+	assert.Equal(t, 7, balance)
+	assert.Equal(t, []int{3}, published)
+	assert.Equal(t, 1, uow.Committed())
+	assert.Len(t, uow.Outbox(), 1)
+
+	err = bus.Exec(context.Background(), &payCmd{Amount: 8})
+	assert.ErrorIs(t, err, errInsufficient)
+	assert.Equal(t, 7, balance)
+	assert.Equal(t, 1, uow.RolledBack())
+
+	var panicErr *PanicError
+	assert.ErrorAs(t, bus.Exec(context.Background(), &payCmd{Amount: -1}), &panicErr)
+	assert.Equal(t, 2, uow.RolledBack())
+
+	err = bus.Exec(context.Background(), &refundCmd{Amount: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, 5, balance)
+	assert.Equal(t, []int{3, 2, -2}, published)
+	assert.Equal(t, 4, uow.Began())
+	assert.Equal(t, 2, uow.Committed())
+	assert.Len(t, uow.Outbox(), 3)
+}
+
+func TestUnitOfWork_RelayOutbox(t *testing.T) {
+	var published []int
+	eventBus := event.NewBus()
+	assert.NoError(t, eventBus.On(event.NewEvent(orderCreated{}, nil), orderListener{created: &published}))
+	uow := NewMemoryUnitOfWork()
+	bus := NewBus(EventBus(eventBus), UnitOfWork(uow), RelayOutbox())
+	assert.NoError(t, RegisterResult[*payCmd, int](bus, CommandResultHandlerFunc[*payCmd, int](func(ctx context.Context, cmd *payCmd) (int, []event.Event, error) {
+		return 0, []event.Event{event.NewEvent(orderCreated{ID: cmd.Amount}, nil)}, nil
+	})))
+
+	// the events are written to the outbox only
+	assert.NoError(t, bus.Exec(context.Background(), &payCmd{Amount: 3}))
+	assert.Len(t, uow.Outbox(), 1)
+	assert.Empty(t, published)
+}
+
+var drv = &recordDriver{}
+
+func init() {
+	sql.Register("cqrs-record", drv)
+}
+
+func TestSQLUnitOfWork(t *testing.T) {
+	drv.Reset()
+	db, err := sql.Open("cqrs-record", "")
+	assert.NoError(t, err)
+	defer db.Close()
+
+	bus := NewBus(UnitOfWork(NewSQLUnitOfWork(db, SQLOutbox("outbox"))))
+	assert.NoError(t, RegisterResult[*payCmd, int](bus, CommandResultHandlerFunc[*payCmd, int](func(ctx context.Context, cmd *payCmd) (int, []event.Event, error) {
+		tx, ok := Tx(ctx)
+		if !ok {
+			return 0, nil, ErrNoTransaction
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE account SET balance = balance - ?", cmd.Amount); err != nil {
+			return 0, nil, err
+		}
+		if cmd.Amount > 10 {
+			return 0, nil, errInsufficient
+		}
+		return 0, []event.Event{event.NewEvent(paid{Amount: cmd.Amount}, 1)}, nil
+	})))
+
+	assert.NoError(t, bus.Exec(context.Background(), &payCmd{Amount: 1}))
+	assert.Equal(t, []string{
+		"BEGIN",
+		"UPDATE account SET balance = balance - ?",
+		"INSERT INTO outbox (event_id, event_type, body, occurred_on) VALUES (?, ?, ?, ?)",
+		"COMMIT",
+	}, drv.Records())
+
+	drv.Reset()
+	assert.ErrorIs(t, bus.Exec(context.Background(), &payCmd{Amount: 11}), errInsufficient)
+	assert.Equal(t, []string{"BEGIN", "UPDATE account SET balance = balance - ?", "ROLLBACK"}, drv.Records())
+}
+
+func TestSQLUnitOfWork_RelayOutboxDisabled(t *testing.T) {
+	drv.Reset()
+	db, err := sql.Open("cqrs-record", "")
+	assert.NoError(t, err)
+	defer db.Close()
+	var published []int
+	eventBus := event.NewBus()
+	assert.NoError(t, eventBus.On(event.NewEvent(orderCreated{}, nil), orderListener{created: &published}))
+
+	// without SQLOutbox, the events are not written, so they are published even if RelayOutbox is set
+	bus := NewBus(EventBus(eventBus), UnitOfWork(NewSQLUnitOfWork(db)), RelayOutbox())
+	assert.NoError(t, RegisterResult[*payCmd, int](bus, CommandResultHandlerFunc[*payCmd, int](func(ctx context.Context, cmd *payCmd) (int, []event.Event, error) {
+		return 0, []event.Event{event.NewEvent(orderCreated{ID: cmd.Amount}, nil)}, nil
+	})))
+	assert.NoError(t, bus.Exec(context.Background(), &payCmd{Amount: 3}))
+	assert.Equal(t, []string{"BEGIN", "COMMIT"}, drv.Records())
+	assert.Equal(t, []int{3}, published)
+}
+
+// recordDriver is a database/sql driver that records the statements.
+type recordDriver struct {
+	mu      sync.Mutex
+	records []string
+}
+
+func (d *recordDriver) record(s string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.records = append(d.records, s)
+}
+
+func (d *recordDriver) Records() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.records...)
+}
+
+func (d *recordDriver) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.records = nil
+}
+
+func (d *recordDriver) Open(string) (driver.Conn, error) { return &recordConn{d: d}, nil }
+
+type recordConn struct{ d *recordDriver }
+
+func (c *recordConn) Prepare(query string) (driver.Stmt, error) {
+	return &recordStmt{d: c.d, query: query}, nil
+}
+func (c *recordConn) Close() error { return nil }
+func (c *recordConn) Begin() (driver.Tx, error) {
+	c.d.record("BEGIN")
+	return &recordTx{d: c.d}, nil
+}
+
+type recordTx struct{ d *recordDriver }
+
+func (tx *recordTx) Commit() error   { tx.d.record("COMMIT"); return nil }
+func (tx *recordTx) Rollback() error { tx.d.record("ROLLBACK"); return nil }
+
+type recordStmt struct {
+	d     *recordDriver
+	query string
+}
+
+func (s *recordStmt) Close() error  { return nil }
+func (s *recordStmt) NumInput() int { return -1 }
+func (s *recordStmt) Exec([]driver.Value) (driver.Result, error) {
+	s.d.record(s.query)
+	return driver.RowsAffected(1), nil
+}
+func (s *recordStmt) Query([]driver.Value) (driver.Rows, error) {
+	return nil, errors.New("unsupported")
+}
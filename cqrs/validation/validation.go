@@ -0,0 +1,130 @@
+// Package validation validates the commands and queries before they are handled.
+//
+// A message is validated by its own Validate method if it implements Validator,
+// and by the specification.Specification rules added to Rules for its type.
+// Interceptor runs the validation as an interceptor of cqrs.Bus.
+package validation
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/go-leo/design-pattern/cqrs"
+	"github.com/go-leo/design-pattern/middleware"
+	"github.com/go-leo/design-pattern/specification"
+)
+
+// Validator is implemented by the messages that validate themselves.
+// Validate returns FieldErrors for the invalid fields, other errors are treated as validation errors.
+type Validator interface {
+	Validate(ctx context.Context) error
+}
+
+// FieldError describes an invalid field.
+type FieldError struct {
+	// Field is the path of field, e.g. "address.city".
+	Field string
+	// Description describes why the field is invalid.
+	Description string
+}
+
+// FieldErrors are the invalid fields of a message.
+type FieldErrors []FieldError
+
+func (e FieldErrors) Error() string {
+	var b strings.Builder
+	for i, fe := range e {
+		if i > 0 {
+			b.WriteString("; ")
+		}
+		b.WriteString(fe.Field)
+		b.WriteString(": ")
+		b.WriteString(fe.Description)
+	}
+	return b.String()
+}
+
+// Fields returns the FieldErrors in the chain of err, nil if there is none.
+func Fields(err error) FieldErrors {
+	var fieldErrs FieldErrors
+	if errors.As(err, &fieldErrs) {
+		return fieldErrs
+	}
+	return nil
+}
+
+type rule struct {
+	field       string
+	description string
+	satisfied   func(ctx context.Context, args any) bool
+}
+
+// Rules holds the specification rules of message types, it is safe for concurrent use.
+type Rules struct {
+	mu    sync.RWMutex
+	rules map[reflect.Type][]rule
+}
+
+// NewRules returns an empty Rules.
+func NewRules() *Rules {
+	return &Rules{rules: make(map[reflect.Type][]rule)}
+}
+
+// AddRule adds a rule to the messages of type T, the field is invalid with description if spec is not satisfied.
+func AddRule[T any](rules *Rules, field string, description string, spec specification.Specification[T]) {
+	inType := reflect.TypeOf((*T)(nil)).Elem()
+	rules.mu.Lock()
+	defer rules.mu.Unlock()
+	rules.rules[inType] = append(rules.rules[inType], rule{
+		field:       field,
+		description: description,
+		satisfied: func(ctx context.Context, args any) bool {
+			return spec.IsSatisfiedBy(ctx, args.(T))
+		},
+	})
+}
+
+// Validate validates args, it returns nil if args is valid,
+// otherwise an error with cqrs.CodeValidation, which wraps FieldErrors if the invalid fields are known.
+// A nil Rules validates by Validator only.
+func (r *Rules) Validate(ctx context.Context, args any) error {
+	var fieldErrs FieldErrors
+	if validator, ok := args.(Validator); ok {
+		if err := validator.Validate(ctx); err != nil {
+			if fieldErrs = Fields(err); fieldErrs == nil {
+				if cqrs.CodeOf(err) != cqrs.CodeUnknown {
+					return err
+				}
+				return cqrs.NewError(cqrs.CodeValidation, "", err)
+			}
+		}
+	}
+	if r != nil {
+		r.mu.RLock()
+		rules := r.rules[reflect.TypeOf(args)]
+		r.mu.RUnlock()
+		for _, rule := range rules {
+			if !rule.satisfied(ctx, args) {
+				fieldErrs = append(fieldErrs, FieldError{Field: rule.field, Description: rule.description})
+			}
+		}
+	}
+	if len(fieldErrs) == 0 {
+		return nil
+	}
+	return cqrs.NewError(cqrs.CodeValidation, "", fieldErrs)
+}
+
+// Interceptor returns a cqrs.Interceptor that validates the commands and queries by rules before they are handled.
+// rules can be nil if the messages only validate themselves.
+func Interceptor(rules *Rules) cqrs.Interceptor {
+	return func(ctx context.Context, req any, invoker middleware.Invoker[any, any]) (any, error) {
+		if err := rules.Validate(ctx, req); err != nil {
+			return nil, err
+		}
+		return invoker(ctx, req)
+	}
+}
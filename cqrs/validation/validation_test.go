@@ -0,0 +1,85 @@
+package validation
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-leo/design-pattern/cqrs"
+	"github.com/go-leo/design-pattern/specification"
+	"github.com/stretchr/testify/assert"
+)
+
+type createUserCmd struct {
+	Name string
+	Age  int
+}
+
+type renameUserCmd struct {
+	ID   int
+	Name string
+}
+
+func (cmd *renameUserCmd) Validate(ctx context.Context) error {
+	if cmd.ID <= 0 {
+		return FieldErrors{{Field: "id", Description: "must be positive"}}
+	}
+	if cmd.Name == "admin" {
+		return errors.New("name is reserved")
+	}
+	if cmd.Name == "root" {
+		return cqrs.Unauthorized("rename to root")
+	}
+	return nil
+}
+
+func TestRules_Validate(t *testing.T) {
+	ctx := context.Background()
+	rules := NewRules()
+	AddRule(rules, "name", "is required", specification.New(func(ctx context.Context, cmd createUserCmd) bool {
+		return cmd.Name != ""
+	}))
+	AddRule(rules, "age", "must be adult", specification.New(func(ctx context.Context, cmd createUserCmd) bool {
+		return cmd.Age >= 18
+	}))
+	AddRule(rules, "name", "is too long", specification.New(func(ctx context.Context, cmd *renameUserCmd) bool {
+		return len(cmd.Name) <= 8
+	}))
+
+	assert.NoError(t, rules.Validate(ctx, createUserCmd{Name: "jax", Age: 18}))
+
+	err := rules.Validate(ctx, createUserCmd{Age: 1})
+	assert.ErrorIs(t, err, cqrs.ErrValidation)
+	assert.Equal(t, FieldErrors{{Field: "name", Description: "is required"}, {Field: "age", Description: "must be adult"}}, Fields(err))
+	assert.Equal(t, "cqrs: validation: name: is required; age: must be adult", err.Error())
+
+	// Validator and rules
+	err = rules.Validate(ctx, &renameUserCmd{Name: "a very long name"})
+	assert.Equal(t, FieldErrors{{Field: "id", Description: "must be positive"}, {Field: "name", Description: "is too long"}}, Fields(err))
+
+	err = rules.Validate(ctx, &renameUserCmd{ID: 1, Name: "admin"})
+	assert.ErrorIs(t, err, cqrs.ErrValidation)
+	assert.Nil(t, Fields(err))
+
+	err = rules.Validate(ctx, &renameUserCmd{ID: 1, Name: "root"})
+	assert.ErrorIs(t, err, cqrs.ErrUnauthorized)
+
+	// nil Rules validates by Validator only
+	var noRules *Rules
+	assert.NoError(t, noRules.Validate(ctx, createUserCmd{}))
+	assert.ErrorIs(t, noRules.Validate(ctx, &renameUserCmd{}), cqrs.ErrValidation)
+}
+
+func TestInterceptor(t *testing.T) {
+	var handled int
+	bus := cqrs.NewBus(cqrs.CommandInterceptors(Interceptor(nil)))
+	assert.NoError(t, cqrs.Register[*renameUserCmd](bus, cqrs.CommandHandlerFunc[*renameUserCmd](func(ctx context.Context, cmd *renameUserCmd) error {
+		handled++
+		return nil
+	})))
+	ctx := context.Background()
+	assert.NoError(t, bus.Exec(ctx, &renameUserCmd{ID: 1, Name: "jax"}))
+	err := bus.Exec(ctx, &renameUserCmd{Name: "jax"})
+	assert.ErrorIs(t, err, cqrs.ErrValidation)
+	assert.Equal(t, 1, handled)
+}
@@ -4,5 +4,13 @@ type Aggregate[T any, ID any] interface {
 	Root() Entity[T, ID]
 }
 
+// AggregateRoot is the entity through which an aggregate is accessed and persisted as a whole.
 type AggregateRoot interface {
+
+	// AggregateID return the identity of the aggregate.
+	AggregateID() string
+
+	// Version return the version of the aggregate, it is increased by every change,
+	// and is used to detect concurrent modifications when the aggregate is saved.
+	Version() int64
 }
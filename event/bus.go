@@ -3,209 +3,548 @@ package event
 import (
 	"context"
 	"errors"
-	"github.com/go-leo/gox/slicex"
-	"github.com/go-leo/gox/syncx"
-	"github.com/go-leo/gox/syncx/chanx"
+	"fmt"
+	"github.com/go-leo/design-pattern/instrument"
+	"github.com/go-leo/design-pattern/internal/lifecycle"
+	"github.com/go-leo/design-pattern/workerpool"
 	"reflect"
 	"runtime"
+	"slices"
+	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 type Bus interface {
-	// On adds a Listener to the Event bus.
-	On(e Event, lis Listener) error
+	// On adds a Listener to the Event bus, after the listeners of the same priority.
+	On(e Event, lis Listener, opts ...ListenerOption) error
 
-	// Prepend adds the Listener to the beginning of the listeners.
-	Prepend(e Event, lis Listener) error
+	// Prepend adds the Listener to the beginning of the listeners of the same priority.
+	Prepend(e Event, lis Listener, opts ...ListenerOption) error
 
 	// Once adds a one-time Listener to the Event bus.
-	Once(e Event, lis Listener) error
+	Once(e Event, lis Listener, opts ...ListenerOption) error
 
-	// PrependOnce adds a one-time Listener to the beginning of the listeners.
-	PrependOnce(e Event, lis Listener) error
+	// PrependOnce adds a one-time Listener to the beginning of the listeners of the same priority.
+	PrependOnce(e Event, lis Listener, opts ...ListenerOption) error
 
 	// Emit synchronously calls each of the listeners registered for the specified Event,
-	// in the order they were registered.
+	// in the order of priority, then in the order they were registered. It is EmitContext with the context of event.
+	// If a listener returns ErrStopPropagation, the rest of listeners are skipped.
 	Emit(e Event) error
 
+	// EmitContext is Emit with ctx as the context of event, the errors of listeners are joined.
+	// The listeners are not called after ctx is done, their errors are the error of ctx.
+	// A called listener should return when the context of its event is done, that is ctx is done
+	// or the listener timeout elapses.
+	EmitContext(ctx context.Context, e Event) error
+
 	// AsyncEmit asynchronously calls each of the listeners registered for the specified Event.
-	AsyncEmit(e Event) <-chan error
+	// It is AsyncEmitContext with the context of event.
+	AsyncEmit(e Event) *Report
+
+	// AsyncEmitContext is AsyncEmit with ctx as the context of event, the Report tells the result of each listener.
+	// The listeners are not called if ctx is done before they start, like EmitContext.
+	// The listeners run concurrently, so ErrStopPropagation doesn't skip any listener.
+	AsyncEmitContext(ctx context.Context, e Event) *Report
 
 	// Off removes the specified Listener from the listeners.
 	Off(e Event, lis Listener) error
 
+	// Route adds a Listener of the events matched by route, e.g. All, Assignable and Topic.
+	// The routed listeners are called along with the listeners of the exact type, in the order of priority.
+	// The max listeners is not applied to the routed listeners.
+	Route(route Route, lis Listener, opts ...ListenerOption) error
+
+	// OffRoute removes the specified Listener from the routed listeners.
+	OffRoute(lis Listener) error
+
+	// OffGroup removes the listeners of the group for the specified Event.
+	OffGroup(e Event, group string) error
+
 	// OffAll removes all listeners for the specified Event.
 	OffAll(e Event) error
 
-	// SetMaxListeners increases the max listeners of the bus.
-	// SetMaxListeners(n int)
+	// SetMaxListeners sets the max listeners of each event type, n <= 0 means no limit.
+	// Adding a listener beyond the limit is reported to the MaxListenersExceeded handler, it may be a leak.
+	SetMaxListeners(n int)
 
 	// GetMaxListeners returns the current max listener value for the bus.
-	// GetMaxListeners() int
+	GetMaxListeners() int
 
-	// Listeners returns a copy of the listeners for the event.
-	// Listeners(e Event) []Listener
+	// Listeners returns a copy of the listeners for the event, including the routed ones that match it,
+	// the one-time listeners are unwrapped.
+	Listeners(e Event) []Listener
 
-	// RawListeners returns a copy of listeners and wrappers for the event.
-	// RawListeners(e Event) []Listener
+	// RawListeners returns a copy of listeners and wrappers for the event,
+	// the wrapper of a one-time listener has an Unwrap() Listener method.
+	RawListeners(e Event) []Listener
 
 	// ListenerCount returns the number of listeners listening to the event.
-	// ListenerCount(e Event) int
+	ListenerCount(e Event) int
 
-	// Events returns an slice listing the events for which the bus has registered listeners.
-	// Events() []Event
+	// Events returns an slice listing the events for which the bus has registered listeners,
+	// each event has the zero value of its type as body, in the order of type names.
+	Events() []Event
 
-	// Close bus gracefully.
+	// Close bus gracefully, it rejects new events, and waits for the in-flight listeners to finish.
+	// If ctx is done or the drain timeout elapses first, the contexts of the in-flight events are canceled,
+	// and an AbandonedError that reports how many emits were abandoned is returned.
 	Close(ctx context.Context) error
 }
 
 var _ Bus = (*bus)(nil)
 
 type bus struct {
-	listenerMap     sync.Map
-	onceListenerMap sync.Map
-	wg              sync.WaitGroup
-	inShutdown      atomic.Bool // true when bus is in shutdown
-	options         *option
+	listenerMap  sync.Map // reflect.Type or routeKey -> *[]*entry, the entries are sorted and never modified
+	seq          atomic.Int64
+	gen          atomic.Int64 // the generation of listenerMap, it is increased on every change
+	index        sync.Map     // indexKey -> *indexed, the routed listeners resolved by type and topic
+	indexSize    atomic.Int64
+	tracker      *lifecycle.Tracker // tracks in-flight emits
+	options      *option
+	maxListeners atomic.Int64
+}
+
+func (b *bus) On(e Event, lis Listener, opts ...ListenerOption) error {
+	return b.add(e, lis, lis, false, opts)
+}
+
+func (b *bus) Prepend(e Event, lis Listener, opts ...ListenerOption) error {
+	return b.add(e, lis, lis, true, opts)
 }
 
-func (b *bus) On(e Event, lis Listener) error {
+func (b *bus) Once(e Event, lis Listener, opts ...ListenerOption) error {
+	return b.add(e, lis, &onceListener{Listener: lis}, false, opts)
+}
+
+func (b *bus) PrependOnce(e Event, lis Listener, opts ...ListenerOption) error {
+	return b.add(e, lis, &onceListener{Listener: lis}, true, opts)
+}
+
+// add adds the registered listener, which is lis or its wrapper, as an entry of the event type.
+func (b *bus) add(e Event, lis Listener, registered Listener, prepend bool, opts []ListenerOption) error {
 	if err := b.check(e, lis); err != nil {
 		return err
 	}
-	b.spin(&b.listenerMap, e.Type(), lis, b.appendListener)
-	return nil
+	o := newListenerOption(opts...)
+	eventType := e.Type()
+	en := &entry{listener: registered, priority: o.Priority, group: o.Group, seq: b.seq.Add(1)}
+	if prepend {
+		en.seq = -en.seq
+	}
+	return b.update(eventType, func(entries []*entry) ([]*entry, error) {
+		if err := b.checkMax(eventType, len(entries)+1); err != nil {
+			return nil, err
+		}
+		return insertEntry(entries, en), nil
+	})
 }
 
-func (b *bus) Prepend(e Event, lis Listener) error {
-	if err := b.check(e, lis); err != nil {
+func (b *bus) Route(route Route, lis Listener, opts ...ListenerOption) error {
+	if route == nil {
+		return ErrRouteInvalid
+	}
+	if r, ok := route.(interface{ validate() error }); ok {
+		if err := r.validate(); err != nil {
+			return err
+		}
+	}
+	if err := b.checkListener(lis); err != nil {
 		return err
 	}
-	b.spin(&b.listenerMap, e.Type(), lis, b.prependListener)
-	return nil
+	if b.shuttingDown() {
+		return ErrBusClosed
+	}
+	o := newListenerOption(opts...)
+	en := &entry{listener: lis, route: route, priority: o.Priority, group: o.Group, seq: b.seq.Add(1)}
+	return b.update(routeKey{}, func(entries []*entry) ([]*entry, error) {
+		return insertEntry(entries, en), nil
+	})
 }
 
-func (b *bus) Once(e Event, lis Listener) error {
-	if err := b.check(e, lis); err != nil {
+func (b *bus) OffRoute(lis Listener) error {
+	if err := b.checkListener(lis); err != nil {
 		return err
 	}
-	onceLis := &onceListener{Listener: lis, Once: sync.Once{}}
-	b.spin(&b.onceListenerMap, e.Type(), onceLis, b.appendListener)
-	return nil
+	if b.shuttingDown() {
+		return ErrBusClosed
+	}
+	return b.update(routeKey{}, func(entries []*entry) ([]*entry, error) {
+		return deleteEntries(entries, func(en *entry) bool { return en.is(lis) }), nil
+	})
 }
 
-func (b *bus) PrependOnce(e Event, lis Listener) error {
-	if err := b.check(e, lis); err != nil {
+func (b *bus) Emit(e Event) error {
+	if err := b.checkEvent(e); err != nil {
 		return err
 	}
-	onceLis := &onceListener{Listener: lis, Once: sync.Once{}}
-	b.spin(&b.onceListenerMap, e.Type(), onceLis, b.prependListener)
-	return nil
+	return b.EmitContext(e.Context(), e)
 }
 
-func (b *bus) Emit(e Event) error {
+func (b *bus) EmitContext(ctx context.Context, e Event) error {
 	if err := b.checkEvent(e); err != nil {
-		return ErrEventNil
+		return err
 	}
-	if b.shuttingDown() {
-		return ErrBusClosed
+	ctx, done, err := b.enter(ctx)
+	if err != nil {
+		return err
 	}
-	value, ok := b.listenerMap.Load(e.Type())
-	if !ok {
+	defer done()
+	eventType := e.Type()
+	entries := b.resolve(eventType, topicOf(e))
+	if len(entries) == 0 {
 		return nil
 	}
-	listeners := value.(*[]Listener)
-	errs := make([]error, 0, len(*listeners))
-	for _, listener := range *listeners {
-		errs = append(errs, listener.Handle(e))
+	e = e.WithContext(ctx)
+	errs := make([]error, 0, len(entries))
+	for _, en := range entries {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		listener, ok := b.claim(eventType, en)
+		if !ok {
+			continue
+		}
+		err := b.emit(ctx, e, listener)
+		errs = append(errs, err)
+		if errors.Is(err, ErrStopPropagation) {
+			break
+		}
 	}
 	return errors.Join(errs...)
 }
 
-func (b *bus) AsyncEmit(e Event) <-chan error {
+func (b *bus) AsyncEmit(e Event) *Report {
+	if err := b.checkEvent(e); err != nil {
+		return failedReport(err)
+	}
+	return b.AsyncEmitContext(e.Context(), e)
+}
+
+func (b *bus) AsyncEmitContext(ctx context.Context, e Event) *Report {
 	if err := b.checkEvent(e); err != nil {
-		errC := make(chan error, 1)
-		errC <- err
-		close(errC)
-		return errC
+		return failedReport(err)
 	}
 	if b.shuttingDown() {
-		errC := make(chan error, 1)
-		errC <- ErrBusClosed
-		close(errC)
-		return errC
+		return failedReport(ErrBusClosed)
 	}
 	eventType := e.Type()
-	value, ok := b.listenerMap.Load(eventType)
+	// the listeners are not called if ctx is already done, so the one-time listeners are not claimed.
+	claim := b.claim
+	if ctx.Err() != nil {
+		claim = peek
+	}
+	var listeners []Listener
+	for _, en := range b.resolve(eventType, topicOf(e)) {
+		if listener, ok := claim(eventType, en); ok {
+			listeners = append(listeners, listener)
+		}
+	}
+	if len(listeners) == 0 {
+		return failedReport(ErrListener{EventType: eventType})
+	}
+	report := newReport(listeners)
+	for i, listener := range listeners {
+		i, listener := i, listener
+		ctx, done, err := b.enter(ctx)
+		if err != nil {
+			report.complete(i, err, 0)
+			continue
+		}
+		ctx, end := b.observe(ctx, e, listener)
+		e := e.WithContext(ctx)
+		fail := func(err error) {
+			end(err)
+			done()
+			report.complete(i, err, 0)
+		}
+		err = workerpool.Submit(b.options.Pool, func() {
+			defer done()
+			defer endPanic(end)
+			duration, err := b.call(e, listener)
+			end(err)
+			report.complete(i, err, duration)
+		}, fail)
+		if err != nil {
+			fail(err)
+		}
+	}
+	return report
+}
+
+// emit calls the listener observed by the Hook.
+func (b *bus) emit(ctx context.Context, e Event, listener Listener) error {
+	ctx, end := b.observe(ctx, e, listener)
+	defer endPanic(end)
+	_, err := b.call(e.WithContext(ctx), listener)
+	end(err)
+	return err
+}
+
+// endPanic ends the observation of a listener that panics with the panic, and propagates the panic,
+// so that the Hook doesn't leak the observation. It must be called by defer.
+func endPanic(end func(err error)) {
+	if p := recover(); p != nil {
+		end(fmt.Errorf("event: listener panic: %v", p))
+		panic(p)
+	}
+}
+
+// call calls the listener with the context of event limited by the listener timeout,
+// the listener is not called if the context is done.
+func (b *bus) call(e Event, listener Listener) (time.Duration, error) {
+	ctx := e.Context()
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if timeout := b.timeout(listener); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+		e = e.WithContext(ctx)
+	}
+	start := time.Now()
+	err := listener.Handle(e)
+	return time.Since(start), err
+}
+
+// timeout returns the timeout of listener set by WithTimeout, or the ListenerTimeout of bus.
+func (b *bus) timeout(listener Listener) time.Duration {
+	if lis, ok := listener.(*timeoutListener); ok {
+		return lis.timeout
+	}
+	return b.options.ListenerTimeout
+}
+
+// claim returns the listener of entry to call, a one-time listener is taken and removed,
+// so that it is called by one emit only.
+func (b *bus) claim(eventType reflect.Type, en *entry) (Listener, bool) {
+	once, ok := en.listener.(*onceListener)
 	if !ok {
-		errC := make(chan error, 1)
-		errC <- ErrListener{EventType: eventType}
-		close(errC)
-		return errC
-	}
-	listeners := value.(*[]Listener)
-	errCs := make([]<-chan error, 0, len(*listeners))
-	for _, listener := range *listeners {
-		errC := make(chan error, 1)
-		b.wg.Add(1)
-		err := b.options.Pool.Go(func() {
-			defer b.wg.Done()
-			defer close(errC)
-			err := listener.Handle(e)
-			if err != nil {
-				errC <- err
-				return
-			}
+		return en.listener, true
+	}
+	if !once.take() {
+		return nil, false
+	}
+	_ = b.update(en.key(eventType), func(entries []*entry) ([]*entry, error) {
+		return deleteEntries(entries, func(other *entry) bool { return other == en }), nil
+	})
+	return once.Listener, true
+}
+
+// peek returns the listener of entry like claim, but a one-time listener is kept.
+func peek(_ reflect.Type, en *entry) (Listener, bool) {
+	once, ok := en.listener.(*onceListener)
+	if !ok {
+		return en.listener, true
+	}
+	if once.fired.Load() {
+		return nil, false
+	}
+	return once.Listener, true
+}
+
+// load returns the snapshot of entries of the key, it must not be modified.
+func (b *bus) load(key any) []*entry {
+	value, ok := b.listenerMap.Load(key)
+	if !ok {
+		return nil
+	}
+	return *(value.(*[]*entry))
+}
+
+// routeKey is the key of the routed listeners in listenerMap.
+type routeKey struct{}
+
+// indexKey is the key of the resolved listeners of an event.
+type indexKey struct {
+	eventType reflect.Type
+	topic     string
+}
+
+// indexed is the resolved listeners of an event at a generation of listenerMap.
+type indexed struct {
+	gen     int64
+	entries []*entry
+}
+
+// maxIndexSize is the max number of indexed type and topic pairs, the index is cleared when it is exceeded,
+// so that the topics with unbounded values don't leak.
+const maxIndexSize = 4096
+
+// resolve returns the entries of the exact type and the routed ones that match the type and topic, in order.
+// The result is cached until listenerMap changes, it must not be modified.
+func (b *bus) resolve(eventType reflect.Type, topic string) []*entry {
+	// load the generation first, so that a change after it invalidates the result.
+	gen := b.gen.Load()
+	routes := b.load(routeKey{})
+	if len(routes) == 0 {
+		return b.load(eventType)
+	}
+	key := indexKey{eventType: eventType, topic: topic}
+	if value, ok := b.index.Load(key); ok && value.(*indexed).gen == gen {
+		return value.(*indexed).entries
+	}
+	entries := slices.Clone(b.load(eventType))
+	for _, en := range routes {
+		if en.route.Match(eventType, topic) {
+			entries = append(entries, en)
+		}
+	}
+	slices.SortStableFunc(entries, func(a, b *entry) int {
+		switch {
+		case a.before(b):
+			return -1
+		case b.before(a):
+			return 1
+		default:
+			return 0
+		}
+	})
+	if _, loaded := b.index.Swap(key, &indexed{gen: gen, entries: entries}); !loaded && b.indexSize.Add(1) > maxIndexSize {
+		b.index.Range(func(key, _ any) bool {
+			b.index.Delete(key)
+			return true
 		})
-		if err != nil {
-			errC <- err
+		b.indexSize.Store(0)
+	}
+	return entries
+}
+
+// observe starts the operation of listener by the Hook of bus, end must be called with the error of listener.
+func (b *bus) observe(ctx context.Context, e Event, listener Listener) (context.Context, func(err error)) {
+	if b.options.Hook == nil {
+		return ctx, func(error) {}
+	}
+	for {
+		wrapper, ok := listener.(interface{ Unwrap() Listener })
+		if !ok {
+			break
 		}
-		errCs = append(errCs, errC)
+		listener = wrapper.Unwrap()
 	}
-	return chanx.Combine[error](errCs...)
+	return b.options.Hook.Start(ctx, instrument.Operation{
+		Kind:    instrument.Listener,
+		Message: e.Type().String(),
+		Handler: fmt.Sprintf("%T", listener),
+	})
 }
 
 func (b *bus) Off(e Event, lis Listener) error {
 	if err := b.check(e, lis); err != nil {
 		return err
 	}
-	eventType := e.Type()
-	b.spin(&b.listenerMap, eventType, lis, b.offListener)
-	b.spin(&b.onceListenerMap, eventType, lis, b.offOnceListener)
-	return nil
+	return b.update(e.Type(), func(entries []*entry) ([]*entry, error) {
+		return deleteEntries(entries, func(en *entry) bool { return en.is(lis) }), nil
+	})
 }
 
-func (b *bus) OffAll(e Event) error {
-	if e == nil {
-		return ErrEventNil
+func (b *bus) OffGroup(e Event, group string) error {
+	if err := b.checkEvent(e); err != nil {
+		return err
 	}
-	eventType := e.Type()
-	if eventType.Kind() == reflect.Invalid {
-		return ErrEventTypeInvalid
+	if b.shuttingDown() {
+		return ErrBusClosed
+	}
+	return b.update(e.Type(), func(entries []*entry) ([]*entry, error) {
+		return deleteEntries(entries, func(en *entry) bool { return en.group == group }), nil
+	})
+}
+
+func (b *bus) OffAll(e Event) error {
+	if err := b.checkEvent(e); err != nil {
+		return err
 	}
 	if b.shuttingDown() {
 		return ErrBusClosed
 	}
-	b.listenerMap.Delete(eventType)
-	b.onceListenerMap.Delete(eventType)
+	b.listenerMap.Delete(e.Type())
+	b.gen.Add(1)
 	return nil
 }
 
-func (b *bus) Close(ctx context.Context) error {
-	if b.inShutdown.CompareAndSwap(false, true) {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-syncx.WaitNotify(&b.wg):
-			return nil
+func (b *bus) SetMaxListeners(n int) {
+	b.maxListeners.Store(int64(max(n, 0)))
+}
+
+func (b *bus) GetMaxListeners() int {
+	return int(b.maxListeners.Load())
+}
+
+func (b *bus) Listeners(e Event) []Listener {
+	listeners := b.RawListeners(e)
+	for i, lis := range listeners {
+		if once, ok := lis.(*onceListener); ok {
+			listeners[i] = once.Listener
+		}
+	}
+	return listeners
+}
+
+func (b *bus) RawListeners(e Event) []Listener {
+	if b.checkEvent(e) != nil {
+		return nil
+	}
+	entries := b.resolve(e.Type(), topicOf(e))
+	if len(entries) == 0 {
+		return nil
+	}
+	listeners := make([]Listener, 0, len(entries))
+	for _, en := range entries {
+		listeners = append(listeners, en.listener)
+	}
+	return listeners
+}
+
+func (b *bus) ListenerCount(e Event) int {
+	if b.checkEvent(e) != nil {
+		return 0
+	}
+	return len(b.resolve(e.Type(), topicOf(e)))
+}
+
+func (b *bus) Events() []Event {
+	var eventTypes []reflect.Type
+	b.listenerMap.Range(func(key, value any) bool {
+		eventType, ok := key.(reflect.Type)
+		if ok && len(*(value.(*[]*entry))) > 0 {
+			eventTypes = append(eventTypes, eventType)
 		}
+		return true
+	})
+	slices.SortFunc(eventTypes, func(a, b reflect.Type) int {
+		return strings.Compare(a.String(), b.String())
+	})
+	events := make([]Event, 0, len(eventTypes))
+	for _, eventType := range eventTypes {
+		events = append(events, NewEvent(reflect.Zero(eventType).Interface(), nil))
+	}
+	return events
+}
+
+func (b *bus) Close(ctx context.Context) error {
+	err := b.tracker.Close(ctx, b.options.DrainTimeout)
+	if errors.Is(err, lifecycle.ErrClosed) {
+		return ErrBusClosed
+	}
+	return err
+}
+
+// enter tracks an emit until done is called, it returns ErrBusClosed if the bus was closed.
+// The events emitted by an in-flight listener with the context of its event are not rejected while the bus is draining.
+func (b *bus) enter(ctx context.Context) (context.Context, func(), error) {
+	ctx, done, err := b.tracker.Enter(ctx)
+	if err != nil {
+		return ctx, nil, ErrBusClosed
 	}
-	return ErrBusClosed
+	return ctx, done, nil
 }
 
 func (b *bus) shuttingDown() bool {
-	return b.inShutdown.Load()
+	return b.tracker.Closed()
 }
 
 func (b *bus) check(e Event, lis Listener) error {
@@ -221,11 +560,21 @@ func (b *bus) check(e Event, lis Listener) error {
 	return nil
 }
 
+// checkMax reports the listener being added to the MaxListenersExceeded handler
+// if count, the number of listeners including it, exceeds the max listeners.
+func (b *bus) checkMax(eventType reflect.Type, count int) error {
+	maxListeners := b.GetMaxListeners()
+	if maxListeners <= 0 || count <= maxListeners {
+		return nil
+	}
+	return b.options.OnExceeded(&MaxListenersError{EventType: eventType, Count: count, Max: maxListeners})
+}
+
 func (b *bus) checkEvent(e Event) error {
 	if e == nil {
 		return ErrEventNil
 	}
-	if e.Type().Kind() == reflect.Invalid {
+	if eventType := e.Type(); eventType == nil || eventType.Kind() == reflect.Invalid {
 		return ErrEventTypeInvalid
 	}
 	return nil
@@ -241,80 +590,33 @@ func (b *bus) checkListener(lis Listener) error {
 	return nil
 }
 
-func (*bus) loadAndOn(listenerMap *sync.Map, eventType reflect.Type, lis Listener, pendFunc func([]Listener, ...Listener) []Listener) (any, any, bool) {
-	ptr := &[]Listener{lis}
-	oldVal, ok := listenerMap.LoadOrStore(eventType, ptr)
-	if !ok {
-		return oldVal, nil, false
-	}
-	newListeners := pendFunc(*ptr, *(oldVal.(*[]Listener))...)
-	newVal := &newListeners
-	return oldVal, newVal, true
-}
-
-func (b *bus) appendListener(listenerMap *sync.Map, eventType reflect.Type, lis Listener) (any, any, bool) {
-	pendFunc := func(listeners []Listener, listener ...Listener) []Listener {
-		return append(listeners, listener...)
-	}
-	return b.loadAndOn(listenerMap, eventType, lis, pendFunc)
-}
-
-func (b *bus) prependListener(listenerMap *sync.Map, eventType reflect.Type, lis Listener) (any, any, bool) {
-	pendFunc := func(listeners []Listener, listener ...Listener) []Listener {
-		return slicex.Prepend(listeners, listener...)
-	}
-	return b.loadAndOn(listenerMap, eventType, lis, pendFunc)
-}
-
-func (*bus) loadAndOff(listenerMap *sync.Map, eventType reflect.Type, lis Listener, indexesFunc func([]Listener, Listener) []int) (any, any, bool) {
-	oldVal, ok := listenerMap.Load(eventType)
-	if !ok {
-		return oldVal, nil, false
-	}
-	oldPtr := oldVal.(*[]Listener)
-	if len(*oldPtr) == 0 {
-		return oldVal, nil, false
-	}
-	indexes := indexesFunc(*oldPtr, lis)
-	if len(indexes) <= 0 {
-		return oldVal, nil, false
-	}
-	newListeners := slicex.DeleteAll(*oldPtr, indexes...)
-	newVal := &newListeners
-	return oldVal, newVal, true
-}
-
-func (b *bus) offListener(listenerMap *sync.Map, eventType reflect.Type, lis Listener) (any, any, bool) {
-	indexesFunc := func(listeners []Listener, lis Listener) []int {
-		return slicex.Indexes(listeners, lis)
-	}
-	return b.loadAndOff(listenerMap, eventType, lis, indexesFunc)
-}
-
-// offOnceListener 删除once
-func (b *bus) offOnceListener(listenerMap *sync.Map, eventType reflect.Type, lis Listener) (any, any, bool) {
-	indexesFunc := func(listeners []Listener, lis Listener) []int {
-		f := func(onceLis Listener) bool {
-			if onceLis.(*onceListener).Listener == lis {
-				return true
+// update replaces the entries of the key with the result of f by compare-and-swap,
+// f is called again if the entries were changed concurrently, it must not modify the entries.
+func (b *bus) update(key any, f func(entries []*entry) ([]*entry, error)) error {
+	backoff := 1
+	for {
+		oldVal, loaded := b.listenerMap.Load(key)
+		var entries []*entry
+		if loaded {
+			entries = *(oldVal.(*[]*entry))
+		}
+		newEntries, err := f(entries)
+		if err != nil {
+			return err
+		}
+		if loaded && b.listenerMap.CompareAndSwap(key, oldVal, &newEntries) {
+			b.gen.Add(1)
+			return nil
+		}
+		if !loaded {
+			if len(newEntries) == 0 {
+				return nil
+			}
+			if _, loaded := b.listenerMap.LoadOrStore(key, &newEntries); !loaded {
+				b.gen.Add(1)
+				return nil
 			}
-			return false
 		}
-		return slicex.IndexesFunc(listeners, f)
-	}
-	return b.loadAndOff(listenerMap, eventType, lis, indexesFunc)
-}
-
-func (b *bus) spin(listenerMap *sync.Map, eventType reflect.Type, lis Listener, load func(listenerMap *sync.Map, eventType reflect.Type, lis Listener) (any, any, bool)) {
-	var oldVal any
-	var newVal any
-	var ok bool
-	oldVal, newVal, ok = load(listenerMap, eventType, lis)
-	if !ok {
-		return
-	}
-	backoff := 1
-	for !listenerMap.CompareAndSwap(eventType, oldVal, &newVal) {
 		// Leverage the exponential backoff algorithm, see https://en.wikipedia.org/wiki/Exponential_backoff.
 		for i := 0; i < backoff; i++ {
 			runtime.Gosched()
@@ -322,9 +624,5 @@ func (b *bus) spin(listenerMap *sync.Map, eventType reflect.Type, lis Listener,
 		if backoff < b.options.MaxBackoff {
 			backoff <<= 1
 		}
-		oldVal, newVal, ok = load(listenerMap, eventType, lis)
-		if !ok {
-			return
-		}
 	}
 }
@@ -1,8 +1,15 @@
 package event
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
 	"sync"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestMap(t *testing.T) {
@@ -26,3 +33,304 @@ func TestMap_CompareAndSwap(t *testing.T) {
 	t.Log(m.CompareAndSwap("slice", &num, &seq))
 	t.Log(m.Load("slice"))
 }
+
+type blockListener struct {
+	started chan struct{}
+}
+
+func (l *blockListener) Handle(e Event) error {
+	l.started <- struct{}{}
+	<-e.Context().Done()
+	return e.Context().Err()
+}
+
+func TestBus_Close(t *testing.T) {
+	bus := NewBus(DrainTimeout(20 * time.Millisecond))
+	lis := &blockListener{started: make(chan struct{}, 2)}
+	assert.NoError(t, bus.On(NewEvent(1, nil), lis))
+
+	report := bus.AsyncEmit(NewEvent(1, nil))
+	<-lis.started
+	err := bus.Close(context.Background())
+	var abandonedErr *AbandonedError
+	assert.ErrorAs(t, err, &abandonedErr)
+	assert.Equal(t, 1, abandonedErr.Abandoned)
+	assert.ErrorIs(t, err, ErrDrainTimeout)
+	assert.ErrorIs(t, report.Err(), context.Canceled)
+
+	assert.ErrorIs(t, bus.Emit(NewEvent(1, nil)), ErrBusClosed)
+	assert.ErrorIs(t, bus.AsyncEmit(NewEvent(1, nil)).Err(), ErrBusClosed)
+	assert.ErrorIs(t, bus.Close(context.Background()), ErrBusClosed)
+
+	bus = NewBus()
+	assert.NoError(t, bus.Close(context.Background()))
+}
+
+type userCreated struct {
+	Name string
+}
+
+func TestSubscribe(t *testing.T) {
+	bus := NewBus()
+	var calls []string
+	first, err := Subscribe[userCreated](bus, func(ctx context.Context, body userCreated, meta Meta) error {
+		calls = append(calls, "first:"+body.Name+":"+meta.ID.(string))
+		return nil
+	})
+	assert.NoError(t, err)
+	_, err = Subscribe[userCreated](bus, func(ctx context.Context, body userCreated, meta Meta) error {
+		calls = append(calls, "second:"+body.Name)
+		return nil
+	})
+	assert.NoError(t, err)
+	_, err = Subscribe[*userCreated](bus, func(ctx context.Context, body *userCreated, meta Meta) error {
+		calls = append(calls, "pointer:"+body.Name)
+		return nil
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, bus.Emit(NewEvent(userCreated{Name: "leo"}, "1")))
+	assert.Equal(t, []string{"first:leo:1", "second:leo"}, calls)
+
+	calls = nil
+	assert.NoError(t, first())
+	assert.NoError(t, first())
+	assert.NoError(t, bus.Emit(NewEvent(userCreated{Name: "go"}, "2")))
+	assert.NoError(t, bus.Emit(NewEvent(&userCreated{Name: "ptr"}, "3")))
+	assert.Equal(t, []string{"second:go", "pointer:ptr"}, calls)
+}
+
+func TestSubscribe_Invalid(t *testing.T) {
+	bus := NewBus()
+	_, err := Subscribe[userCreated](bus, nil)
+	assert.ErrorIs(t, err, ErrListenerNil)
+	assert.NoError(t, bus.Close(context.Background()))
+	_, err = Subscribe[userCreated](bus, func(ctx context.Context, body userCreated, meta Meta) error { return nil })
+	assert.ErrorIs(t, err, ErrBusClosed)
+}
+
+type recordListener struct {
+	name  string
+	calls *[]string
+}
+
+func (l *recordListener) Handle(e Event) error {
+	*l.calls = append(*l.calls, l.name)
+	return nil
+}
+
+func TestBus_Once(t *testing.T) {
+	bus := NewBus()
+	var calls []string
+	a := &recordListener{name: "a", calls: &calls}
+	b := &recordListener{name: "b", calls: &calls}
+	c := &recordListener{name: "c", calls: &calls}
+	assert.NoError(t, bus.On(NewEvent(1, nil), a))
+	assert.NoError(t, bus.Once(NewEvent(1, nil), b))
+	assert.NoError(t, bus.PrependOnce(NewEvent(1, nil), c))
+
+	assert.NoError(t, bus.Emit(NewEvent(1, nil)))
+	assert.NoError(t, bus.Emit(NewEvent(1, nil)))
+	assert.Equal(t, []string{"c", "a", "b", "a"}, calls)
+	assert.Equal(t, 1, bus.ListenerCount(NewEvent(1, nil)))
+
+	calls = nil
+	assert.NoError(t, bus.Once(NewEvent("s", nil), b))
+	assert.NoError(t, bus.AsyncEmit(NewEvent("s", nil)).Err())
+	assert.ErrorAs(t, bus.AsyncEmit(NewEvent("s", nil)).Err(), &ErrListener{})
+	assert.Equal(t, []string{"b"}, calls)
+}
+
+func TestBus_Introspection(t *testing.T) {
+	bus := NewBus()
+	var calls []string
+	a := &recordListener{name: "a", calls: &calls}
+	b := &recordListener{name: "b", calls: &calls}
+	assert.NoError(t, bus.On(NewEvent(1, nil), a))
+	assert.NoError(t, bus.Prepend(NewEvent(1, nil), b))
+	assert.NoError(t, bus.Once(NewEvent(1, nil), a))
+	assert.NoError(t, bus.Once(NewEvent("s", nil), b))
+
+	assert.Equal(t, []Listener{b, a, a}, bus.Listeners(NewEvent(1, nil)))
+	raw := bus.RawListeners(NewEvent(1, nil))
+	assert.Len(t, raw, 3)
+	assert.Equal(t, a, raw[2].(interface{ Unwrap() Listener }).Unwrap())
+	assert.Equal(t, 3, bus.ListenerCount(NewEvent(1, nil)))
+	assert.Equal(t, 0, bus.ListenerCount(NewEvent(1.0, nil)))
+	assert.Nil(t, bus.Listeners(NewEvent(nil, nil)))
+
+	events := bus.Events()
+	assert.Len(t, events, 2)
+	assert.Equal(t, 0, events[0].Body())
+	assert.Equal(t, "", events[1].Body())
+
+	assert.NoError(t, bus.OffAll(NewEvent("s", nil)))
+	assert.NoError(t, bus.Off(NewEvent(1, nil), a))
+	assert.Equal(t, []Listener{b}, bus.Listeners(NewEvent(1, nil)))
+	assert.Len(t, bus.Events(), 1)
+}
+
+func TestBus_MaxListeners(t *testing.T) {
+	var exceeded []*MaxListenersError
+	bus := NewBus(MaxListeners(2), MaxListenersExceeded(func(err *MaxListenersError) error {
+		exceeded = append(exceeded, err)
+		return nil
+	}))
+	assert.Equal(t, 2, bus.GetMaxListeners())
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, bus.On(NewEvent(1, nil), &recordListener{}))
+	}
+	assert.Equal(t, 3, bus.ListenerCount(NewEvent(1, nil)))
+	assert.Equal(t, []*MaxListenersError{{EventType: reflect.TypeOf(1), Count: 3, Max: 2}}, exceeded)
+
+	// rejected by default
+	bus = NewBus()
+	bus.SetMaxListeners(1)
+	assert.NoError(t, bus.On(NewEvent(1, nil), &recordListener{}))
+	assert.ErrorIs(t, bus.Once(NewEvent(1, nil), &recordListener{}), ErrMaxListeners)
+	assert.NoError(t, bus.On(NewEvent("s", nil), &recordListener{}))
+	assert.Equal(t, 1, bus.ListenerCount(NewEvent(1, nil)))
+
+	bus.SetMaxListeners(0)
+	assert.NoError(t, bus.On(NewEvent(1, nil), &recordListener{}))
+}
+
+type ctxListener struct {
+	name  string
+	calls chan string
+}
+
+func (l *ctxListener) Handle(e Event) error {
+	l.calls <- l.name
+	<-e.Context().Done()
+	return e.Context().Err()
+}
+
+func TestBus_EmitContext(t *testing.T) {
+	bus := NewBus(ListenerTimeout(time.Hour))
+	calls := make(chan string, 3)
+	slow := &ctxListener{name: "slow", calls: calls}
+	fast := WithTimeout(&ctxListener{name: "fast", calls: calls}, 10*time.Millisecond)
+	assert.NoError(t, bus.On(NewEvent(1, nil), fast))
+	assert.NoError(t, bus.On(NewEvent(1, nil), slow))
+	assert.NoError(t, bus.On(NewEvent(1, nil), &recordListener{calls: new([]string)}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := bus.EmitContext(ctx, NewEvent(1, nil))
+	// fast times out by its own timeout, slow is canceled by ctx, and the last one is not called.
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, "fast", <-calls)
+	assert.Equal(t, "slow", <-calls)
+	assert.Empty(t, calls)
+
+	assert.NoError(t, bus.Off(NewEvent(1, nil), fast))
+	assert.Equal(t, 2, bus.ListenerCount(NewEvent(1, nil)))
+}
+
+func TestBus_AsyncEmitContext(t *testing.T) {
+	failed := errors.New("failed")
+	bus := NewBus(ListenerTimeout(10 * time.Millisecond))
+	calls := make(chan string, 1)
+	slow := &ctxListener{name: "slow", calls: calls}
+	failing := &errListener{err: failed}
+	assert.NoError(t, bus.On(NewEvent(1, nil), slow))
+	assert.NoError(t, bus.On(NewEvent(1, nil), failing))
+
+	report := bus.AsyncEmitContext(context.Background(), NewEvent(1, nil))
+	results, err := report.Wait(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+	assert.Equal(t, slow, results[0].Listener)
+	assert.ErrorIs(t, results[0].Err, context.DeadlineExceeded)
+	assert.GreaterOrEqual(t, results[0].Duration, 10*time.Millisecond)
+	assert.Equal(t, failing, results[1].Listener)
+	assert.ErrorIs(t, results[1].Err, failed)
+	assert.ErrorIs(t, report.Err(), failed)
+	<-report.Done()
+	assert.Equal(t, "slow", <-calls)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results, err = bus.AsyncEmitContext(ctx, NewEvent(1, nil)).Wait(context.Background())
+	assert.NoError(t, err)
+	for _, result := range results {
+		assert.ErrorIs(t, result.Err, context.Canceled)
+		assert.Zero(t, result.Duration)
+	}
+	assert.Empty(t, calls)
+
+	// a one-time listener is kept if it is not called
+	once := &errListener{err: failed}
+	assert.NoError(t, bus.Once(NewEvent("once", nil), once))
+	results, err = bus.AsyncEmitContext(ctx, NewEvent("once", nil)).Wait(context.Background())
+	assert.NoError(t, err)
+	if assert.Len(t, results, 1) {
+		assert.Equal(t, once, results[0].Listener)
+		assert.ErrorIs(t, results[0].Err, context.Canceled)
+	}
+	assert.ErrorIs(t, bus.AsyncEmit(NewEvent("once", nil)).Err(), failed)
+	assert.Zero(t, bus.ListenerCount(NewEvent("once", nil)))
+}
+
+type errListener struct {
+	err error
+}
+
+func (l *errListener) Handle(e Event) error {
+	return l.err
+}
+
+func TestBus_Priority(t *testing.T) {
+	bus := NewBus()
+	var calls []string
+	add := func(name string, opts ...ListenerOption) *recordListener {
+		lis := &recordListener{name: name, calls: &calls}
+		assert.NoError(t, bus.On(NewEvent(1, nil), lis, opts...))
+		return lis
+	}
+	add("default")
+	add("audit-1", Group("audit"))
+	add("low", Priority(-1))
+	add("high", Priority(10))
+	add("default-2")
+	add("audit-2", Group("audit"))
+	add("high-audit", Priority(10), Group("audit"))
+	assert.NoError(t, bus.Prepend(NewEvent(1, nil), &recordListener{name: "first", calls: &calls}))
+	assert.NoError(t, bus.PrependOnce(NewEvent(1, nil), &recordListener{name: "audit-0", calls: &calls}, Group("audit")))
+
+	assert.NoError(t, bus.Emit(NewEvent(1, nil)))
+	assert.Equal(t, []string{"high", "high-audit", "first", "default", "audit-0", "audit-1", "audit-2", "default-2", "low"}, calls)
+
+	calls = nil
+	assert.NoError(t, bus.OffGroup(NewEvent(1, nil), "audit"))
+	assert.NoError(t, bus.Emit(NewEvent(1, nil)))
+	assert.Equal(t, []string{"high", "first", "default", "default-2", "low"}, calls)
+}
+
+func TestBus_StopPropagation(t *testing.T) {
+	bus := NewBus()
+	var calls []string
+	vetoed := fmt.Errorf("order is locked: %w", ErrStopPropagation)
+	assert.NoError(t, bus.On(NewEvent(1, nil), &recordListener{name: "commit", calls: &calls}))
+	assert.NoError(t, bus.Once(NewEvent(1, nil), &recordListener{name: "once", calls: &calls}))
+	veto := &errListener{err: vetoed}
+	assert.NoError(t, bus.On(NewEvent(1, nil), veto, Priority(100), Group("pre-commit")))
+
+	err := bus.Emit(NewEvent(1, nil))
+	assert.ErrorIs(t, err, ErrStopPropagation)
+	assert.ErrorIs(t, err, vetoed)
+	assert.Empty(t, calls)
+	// the skipped one-time listener is kept
+	assert.Equal(t, 3, bus.ListenerCount(NewEvent(1, nil)))
+
+	assert.NoError(t, bus.Off(NewEvent(1, nil), veto))
+	assert.NoError(t, bus.Emit(NewEvent(1, nil)))
+	assert.Equal(t, []string{"commit", "once"}, calls)
+
+	// AsyncEmit calls all listeners
+	assert.NoError(t, bus.On(NewEvent(1, nil), veto, Priority(100)))
+	results, err := bus.AsyncEmit(NewEvent(1, nil)).Wait(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+}
@@ -0,0 +1,92 @@
+package event
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Codec encodes the bodies of events for the durable stores.
+type Codec interface {
+	// Marshal returns the type name and the encoded data of body.
+	Marshal(body any) (typeName string, data []byte, err error)
+
+	// Unmarshal decodes the data to a body of the type name.
+	Unmarshal(typeName string, data []byte) (any, error)
+}
+
+var _ Codec = (*JSONCodec)(nil)
+
+// JSONCodec is a Codec that encodes the bodies in JSON. The body types must be registered to be decoded,
+// the type name is the package path and the name of type, e.g. "github.com/foo/order.Created",
+// a pointer type is prefixed with "*".
+type JSONCodec struct {
+	mu    sync.RWMutex
+	types map[string]reflect.Type
+}
+
+// NewJSONCodec returns a JSONCodec with the types of bodies registered.
+func NewJSONCodec(bodies ...any) *JSONCodec {
+	c := &JSONCodec{types: make(map[string]reflect.Type)}
+	c.Register(bodies...)
+	return c
+}
+
+// Register registers the types of bodies, e.g. Register(OrderCreated{}, &OrderShipped{}).
+// The nil bodies are skipped, since they have no type.
+func (c *JSONCodec) Register(bodies ...any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, body := range bodies {
+		if body == nil {
+			continue
+		}
+		t := reflect.TypeOf(body)
+		c.types[TypeName(t)] = t
+	}
+}
+
+func (c *JSONCodec) Marshal(body any) (string, []byte, error) {
+	if body == nil {
+		return "", nil, ErrEventTypeInvalid
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return "", nil, err
+	}
+	return TypeName(reflect.TypeOf(body)), data, nil
+}
+
+func (c *JSONCodec) Unmarshal(typeName string, data []byte) (any, error) {
+	c.mu.RLock()
+	t, ok := c.types[typeName]
+	c.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrTypeUnregistered, typeName)
+	}
+	if t.Kind() == reflect.Pointer {
+		ptr := reflect.New(t.Elem())
+		if err := json.Unmarshal(data, ptr.Interface()); err != nil {
+			return nil, err
+		}
+		return ptr.Interface(), nil
+	}
+	ptr := reflect.New(t)
+	if err := json.Unmarshal(data, ptr.Interface()); err != nil {
+		return nil, err
+	}
+	return ptr.Elem().Interface(), nil
+}
+
+// TypeName returns the stable name of type, it is the package path and the name of type,
+// a pointer type is prefixed with "*", and an unnamed type is its literal, e.g. "[]string".
+func TypeName(t reflect.Type) string {
+	if t.Kind() == reflect.Pointer {
+		return "*" + TypeName(t.Elem())
+	}
+	if t.PkgPath() == "" || t.Name() == "" {
+		return t.String()
+	}
+	return t.PkgPath() + "." + t.Name()
+}
@@ -0,0 +1,30 @@
+package event
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONCodec(t *testing.T) {
+	codec := NewJSONCodec(orderCreated{}, nil)
+	codec.Register(nil, &orderCreated{})
+
+	typeName, data, err := codec.Marshal(orderCreated{ID: "1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "github.com/go-leo/design-pattern/event.orderCreated", typeName)
+	body, err := codec.Unmarshal(typeName, data)
+	assert.NoError(t, err)
+	assert.Equal(t, orderCreated{ID: "1"}, body)
+
+	typeName, data, err = codec.Marshal(&orderCreated{ID: "2"})
+	assert.NoError(t, err)
+	body, err = codec.Unmarshal(typeName, data)
+	assert.NoError(t, err)
+	assert.Equal(t, &orderCreated{ID: "2"}, body)
+
+	_, _, err = codec.Marshal(nil)
+	assert.ErrorIs(t, err, ErrEventTypeInvalid)
+	_, err = codec.Unmarshal("int", []byte("1"))
+	assert.ErrorIs(t, err, ErrTypeUnregistered)
+}
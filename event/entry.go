@@ -0,0 +1,75 @@
+package event
+
+import (
+	"reflect"
+	"slices"
+)
+
+// entry is a listener registered for an event type.
+type entry struct {
+	// listener is the registered Listener, a one-time listener is wrapped by onceListener.
+	listener Listener
+	// route is the Route of a routed listener, it is nil for the listeners of an exact type.
+	route    Route
+	priority int
+	group    string
+	// seq is the sequence of registration, it is negative if the listener was prepended.
+	seq int64
+	// rank orders the listeners of the same priority, the listeners of a group share the rank of the group,
+	// so that they are kept together.
+	rank int64
+}
+
+// key returns the key of the entry in listenerMap.
+func (en *entry) key(eventType reflect.Type) any {
+	if en.route != nil {
+		return routeKey{}
+	}
+	return eventType
+}
+
+// is reports whether the entry is of lis.
+func (en *entry) is(lis Listener) bool {
+	if en.listener == lis {
+		return true
+	}
+	once, ok := en.listener.(*onceListener)
+	return ok && once.Listener == lis
+}
+
+// before reports whether en is called before other.
+func (en *entry) before(other *entry) bool {
+	if en.priority != other.priority {
+		return en.priority > other.priority
+	}
+	if en.rank != other.rank {
+		return en.rank < other.rank
+	}
+	return en.seq < other.seq
+}
+
+// insertEntry returns a copy of entries with en inserted in order, entries is not modified.
+func insertEntry(entries []*entry, en *entry) []*entry {
+	en.rank = en.seq
+	if en.group != "" {
+		for _, other := range entries {
+			if other.group == en.group && other.priority == en.priority {
+				en.rank = other.rank
+				break
+			}
+		}
+	}
+	i := slices.IndexFunc(entries, en.before)
+	if i < 0 {
+		i = len(entries)
+	}
+	newEntries := make([]*entry, 0, len(entries)+1)
+	newEntries = append(newEntries, entries[:i]...)
+	newEntries = append(newEntries, en)
+	return append(newEntries, entries[i:]...)
+}
+
+// deleteEntries returns a copy of entries without the ones that del returns true, entries is not modified.
+func deleteEntries(entries []*entry, del func(en *entry) bool) []*entry {
+	return slices.DeleteFunc(slices.Clone(entries), del)
+}
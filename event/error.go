@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+
+	"github.com/go-leo/design-pattern/internal/lifecycle"
 )
 
 var (
@@ -21,8 +23,37 @@ var (
 
 	// ErrListenerIncomparable listener is incomparable
 	ErrListenerIncomparable = errors.New("listener is incomparable")
+
+	// ErrDrainTimeout in-flight listeners were not drained in the drain timeout
+	ErrDrainTimeout = lifecycle.ErrDrainTimeout
+
+	// ErrMaxListeners the number of listeners exceeds the max listeners
+	ErrMaxListeners = errors.New("max listeners exceeded")
+
+	// ErrRouteInvalid route is invalid
+	ErrRouteInvalid = errors.New("route is invalid")
+
+	// ErrVersionConflict the version of stream is not the expected version
+	ErrVersionConflict = errors.New("version conflict")
+
+	// ErrStoreClosed store was closed
+	ErrStoreClosed = errors.New("store was closed")
+
+	// ErrEventIDInvalid the id of event is not a string, that is required by Store
+	ErrEventIDInvalid = errors.New("event id is not a string")
+
+	// ErrTypeUnregistered the type of event body is not registered to the Codec
+	ErrTypeUnregistered = errors.New("event type is not registered")
+
+	// ErrStopPropagation is returned by a listener, or wrapped in its error, to skip the rest of listeners of Emit,
+	// e.g. a veto of a pre-commit hook. Emit returns the error of the listener.
+	ErrStopPropagation = errors.New("stop propagation")
 )
 
+// AbandonedError is returned by Close when the in-flight listeners were not drained
+// before the context of Close is done or the drain timeout elapses.
+type AbandonedError = lifecycle.AbandonedError
+
 type ErrListener struct {
 	EventType reflect.Type
 }
@@ -30,3 +61,36 @@ type ErrListener struct {
 func (e ErrListener) Error() string {
 	return fmt.Sprintf("%s listener not found", e.EventType)
 }
+
+// MaxListenersError reports that the number of listeners of an event type exceeds the max listeners,
+// which is usually a leak of listeners. It matches ErrMaxListeners by errors.Is.
+type MaxListenersError struct {
+	EventType reflect.Type
+	// Count is the number of listeners including the one being added.
+	Count int
+	Max   int
+}
+
+func (e *MaxListenersError) Error() string {
+	return fmt.Sprintf("%d %s listeners exceed the max listeners %d, possible listener leak", e.Count, e.EventType, e.Max)
+}
+
+func (e *MaxListenersError) Is(target error) bool {
+	return target == ErrMaxListeners
+}
+
+// VersionConflictError is returned by Store.Append when the version of stream is not the expected version,
+// it matches ErrVersionConflict by errors.Is.
+type VersionConflictError struct {
+	StreamID string
+	Expected int64
+	Actual   int64
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("version conflict of stream %q, expected version %d, actual version %d", e.StreamID, e.Expected, e.Actual)
+}
+
+func (e *VersionConflictError) Is(target error) bool {
+	return target == ErrVersionConflict
+}
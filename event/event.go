@@ -77,5 +77,10 @@ func (e *event) Context() context.Context {
 }
 
 func NewEvent(body any, id any) Event {
-	return &event{body: body, id: id, occurredOn: time.Now()}
+	return NewEventAt(body, id, time.Now())
+}
+
+// NewEventAt returns an Event that occurred on the time, e.g. an event restored from a Store.
+func NewEventAt(body any, id any, occurredOn time.Time) Event {
+	return &event{body: body, id: id, occurredOn: occurredOn}
 }
@@ -0,0 +1,302 @@
+// Package filestore provides an event.Store embedded in a local file.
+//
+// The events are appended to the file as JSON lines, and indexed in memory by sequence and stream when the file is
+// opened, so the bodies of events are read from the file on demand. An incomplete last line, which is left by a crash
+// during append, is truncated on open, and any other line that can't be decoded fails the open with ErrCorrupted.
+package filestore
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-leo/design-pattern/event"
+)
+
+// ErrCorrupted file is corrupted, a complete line of file can't be decoded
+var ErrCorrupted = errors.New("filestore: file is corrupted")
+
+type option struct {
+	Sync bool
+}
+
+func newOption(opts ...Option) *option {
+	o := &option{Sync: true}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+type Option func(*option)
+
+// Sync sets whether Append syncs the file to the disk before it returns, default is true.
+// Without sync, the appended events may be lost by a crash of the operating system, but not by a crash of process.
+func Sync(sync bool) Option {
+	return func(o *option) {
+		o.Sync = sync
+	}
+}
+
+var _ event.Store = (*Store)(nil)
+
+// Store is an event.Store embedded in a local file.
+type Store struct {
+	mu      sync.RWMutex
+	file    *os.File
+	size    int64
+	codec   event.Codec
+	options *option
+	lines   []line             // sequence - 1 -> line
+	streams map[string][]int64 // stream -> sequences
+	closed  bool
+}
+
+// line is the location of an event in the file.
+type line struct {
+	offset int64
+	length int64
+}
+
+// record is the JSON line of an event.
+type record struct {
+	Sequence   int64     `json:"sequence"`
+	StreamID   string    `json:"stream_id"`
+	Version    int64     `json:"version"`
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	Body       []byte    `json:"body"`
+	OccurredOn time.Time `json:"occurred_on"`
+}
+
+// Open opens the Store of the file at path, the file is created if it doesn't exist.
+// The bodies of events are encoded by codec.
+func Open(path string, codec event.Codec, opts ...Option) (*Store, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	s := &Store{file: file, codec: codec, options: newOption(opts...), streams: make(map[string][]int64)}
+	if err := s.load(); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// load indexes the events of file, and truncates the last line if it is incomplete, that is left by a crash during
+// append. A complete line that can't be decoded is not a torn write, and ErrCorrupted is returned.
+func (s *Store) load() error {
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	reader := bufio.NewReader(s.file)
+	var offset int64
+	for {
+		data, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// data is the incomplete last line, if any.
+			break
+		}
+		if err != nil {
+			return err
+		}
+		var r record
+		if err := json.Unmarshal(data, &r); err != nil {
+			return fmt.Errorf("%w: line %d: %w", ErrCorrupted, len(s.lines)+1, err)
+		}
+		if r.Sequence != int64(len(s.lines))+1 {
+			return fmt.Errorf("%w: line %d: sequence %d", ErrCorrupted, len(s.lines)+1, r.Sequence)
+		}
+		s.index(r, line{offset: offset, length: int64(len(data))})
+		offset += int64(len(data))
+	}
+	s.size = offset
+	return s.file.Truncate(offset)
+}
+
+func (s *Store) index(r record, l line) {
+	s.lines = append(s.lines, l)
+	s.streams[r.StreamID] = append(s.streams[r.StreamID], r.Sequence)
+}
+
+func (s *Store) Append(_ context.Context, streamID string, expectedVersion int64, events ...event.Event) ([]event.StoredEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, event.ErrStoreClosed
+	}
+	version := int64(len(s.streams[streamID]))
+	if err := event.CheckVersion(streamID, expectedVersion, version); err != nil {
+		return nil, err
+	}
+	records := make([]record, 0, len(events))
+	lines := make([]line, 0, len(events))
+	var buf []byte
+	for i, e := range events {
+		if err := event.CheckStoredEvent(e); err != nil {
+			return nil, err
+		}
+		r, err := s.encode(e)
+		if err != nil {
+			return nil, err
+		}
+		r.Sequence = int64(len(s.lines) + i + 1)
+		r.StreamID = streamID
+		r.Version = version + int64(i) + 1
+		data, err := json.Marshal(r)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, '\n')
+		lines = append(lines, line{offset: s.size + int64(len(buf)), length: int64(len(data))})
+		records = append(records, r)
+		buf = append(buf, data...)
+	}
+	if len(buf) == 0 {
+		return nil, nil
+	}
+	if err := s.write(buf); err != nil {
+		return nil, err
+	}
+	stored := make([]event.StoredEvent, 0, len(events))
+	for i, r := range records {
+		s.index(r, lines[i])
+		stored = append(stored, event.StoredEvent{
+			Sequence: r.Sequence,
+			StreamID: streamID,
+			Version:  r.Version,
+			Event:    event.NewEventAt(events[i].Body(), events[i].ID(), r.OccurredOn),
+		})
+	}
+	return stored, nil
+}
+
+// write appends buf to the end of file, the file is truncated back if it fails, so that no partial events are left.
+func (s *Store) write(buf []byte) error {
+	if _, err := s.file.WriteAt(buf, s.size); err != nil {
+		_ = s.file.Truncate(s.size)
+		return err
+	}
+	if s.options.Sync {
+		if err := s.file.Sync(); err != nil {
+			_ = s.file.Truncate(s.size)
+			return err
+		}
+	}
+	s.size += int64(len(buf))
+	return nil
+}
+
+func (s *Store) encode(e event.Event) (record, error) {
+	typeName, body, err := s.codec.Marshal(e.Body())
+	if err != nil {
+		return record{}, err
+	}
+	return record{ID: e.ID().(string), Type: typeName, Body: body, OccurredOn: e.When()}, nil
+}
+
+func (s *Store) decode(r record) (event.StoredEvent, error) {
+	body, err := s.codec.Unmarshal(r.Type, r.Body)
+	if err != nil {
+		return event.StoredEvent{}, err
+	}
+	return event.StoredEvent{
+		Sequence: r.Sequence,
+		StreamID: r.StreamID,
+		Version:  r.Version,
+		Event:    event.NewEventAt(body, r.ID, r.OccurredOn),
+	}, nil
+}
+
+// read reads and decodes the events of the sequences, the caller must hold mu.
+func (s *Store) read(sequences []int64) ([]event.StoredEvent, error) {
+	if len(sequences) == 0 {
+		return nil, nil
+	}
+	events := make([]event.StoredEvent, 0, len(sequences))
+	for _, sequence := range sequences {
+		l := s.lines[sequence-1]
+		data := make([]byte, l.length)
+		if _, err := s.file.ReadAt(data, l.offset); err != nil {
+			return nil, err
+		}
+		var r record
+		if err := json.Unmarshal(data, &r); err != nil {
+			return nil, fmt.Errorf("%w: sequence %d: %w", ErrCorrupted, sequence, err)
+		}
+		e, err := s.decode(r)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+func (s *Store) AllStoredEventsBetween(_ context.Context, lowStoredEventId int64, highStoredEventId int64) ([]event.StoredEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return nil, event.ErrStoreClosed
+	}
+	low, high := event.SequenceRange(lowStoredEventId, highStoredEventId, int64(len(s.lines)))
+	var sequences []int64
+	for sequence := low; sequence <= high; sequence++ {
+		sequences = append(sequences, sequence)
+	}
+	return s.read(sequences)
+}
+
+func (s *Store) AllStoredEventsSince(ctx context.Context, storedEventId int64) ([]event.StoredEvent, error) {
+	return s.AllStoredEventsBetween(ctx, storedEventId+1, 0)
+}
+
+func (s *Store) StreamEvents(_ context.Context, streamID string, fromVersion int64, toVersion int64) ([]event.StoredEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return nil, event.ErrStoreClosed
+	}
+	sequences := s.streams[streamID]
+	from, to := event.SequenceRange(fromVersion, toVersion, int64(len(sequences)))
+	if from > to {
+		return nil, nil
+	}
+	return s.read(sequences[from-1 : to])
+}
+
+func (s *Store) StreamVersion(_ context.Context, streamID string) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return 0, event.ErrStoreClosed
+	}
+	return int64(len(s.streams[streamID])), nil
+}
+
+func (s *Store) CountStoredEvents(_ context.Context) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return 0, event.ErrStoreClosed
+	}
+	return int64(len(s.lines)), nil
+}
+
+// Close closes the file.
+func (s *Store) Close(_ context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return event.ErrStoreClosed
+	}
+	s.closed = true
+	return s.file.Close()
+}
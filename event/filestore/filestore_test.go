@@ -0,0 +1,108 @@
+package filestore
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-leo/design-pattern/event"
+	"github.com/go-leo/design-pattern/event/storetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) event.Store {
+		store, err := Open(filepath.Join(t.TempDir(), "events"), storetest.Codec(), Sync(false))
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		return store
+	})
+}
+
+func TestStore_Reopen(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "events")
+	store, err := Open(path, storetest.Codec())
+	if !assert.NoError(t, err) {
+		return
+	}
+	stored, err := store.Append(ctx, "user-1", event.NoStream,
+		event.NewEvent(storetest.Created{Name: "jax"}, "1"),
+		event.NewEvent(&storetest.Renamed{From: "jax", To: "leo"}, "2"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, store.Close(ctx))
+
+	// a crash during append leaves an incomplete line
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = file.WriteString(`{"sequence":3,"stream_id":"user-1","vers`)
+	assert.NoError(t, err)
+	assert.NoError(t, file.Close())
+
+	store, err = Open(path, storetest.Codec())
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer store.Close(ctx)
+	events, err := store.StreamEvents(ctx, "user-1", 1, 0)
+	assert.NoError(t, err)
+	storetest.AssertEqual(t, stored, events)
+
+	more, err := store.Append(ctx, "user-1", 2, event.NewEvent(&storetest.Renamed{From: "leo", To: "max"}, "3"))
+	assert.NoError(t, err)
+	events, err = store.AllStoredEventsSince(ctx, 0)
+	assert.NoError(t, err)
+	storetest.AssertEqual(t, append(stored, more...), events)
+}
+
+func TestOpen_Corrupted(t *testing.T) {
+	valid := `{"sequence":1,"stream_id":"a","version":1,"id":"1","type":"x","body":"e30=","occurred_on":"2024-01-01T00:00:00Z"}` + "\n"
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "middle", content: valid + "garbage\n" + strings.Replace(valid, `"sequence":1`, `"sequence":3`, 1)},
+		// a complete last line is not a torn write
+		{name: "last", content: valid + "garbage\n"},
+		{name: "sequence", content: valid + valid},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "events")
+			assert.NoError(t, os.WriteFile(path, []byte(test.content), 0o644))
+			_, err := Open(path, storetest.Codec())
+			assert.ErrorIs(t, err, ErrCorrupted)
+			// the file is not truncated
+			data, err := os.ReadFile(path)
+			assert.NoError(t, err)
+			assert.Equal(t, test.content, string(data))
+		})
+	}
+}
+
+func TestOpen_UnregisteredType(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "events")
+	store, err := Open(path, storetest.Codec())
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = store.Append(ctx, "user-1", event.NoStream, event.NewEvent(struct{ Name string }{Name: "jax"}, "1"))
+	assert.NoError(t, err)
+	assert.NoError(t, store.Close(ctx))
+
+	store, err = Open(path, event.NewJSONCodec())
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer store.Close(ctx)
+	_, err = store.AllStoredEventsSince(ctx, 0)
+	assert.ErrorIs(t, err, event.ErrTypeUnregistered)
+}
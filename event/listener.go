@@ -1,6 +1,12 @@
 package event
 
-import "sync"
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"time"
+)
 
 // Listener is Event listener interface.
 type Listener interface {
@@ -8,42 +14,104 @@ type Listener interface {
 	Handle(event Event) error
 }
 
+// onceListener wraps a one-time Listener, it is listed by Bus.RawListeners.
 type onceListener struct {
 	Listener Listener
-	Once     sync.Once
+	fired    atomic.Bool
 }
 
+// Handle calls the wrapped Listener if it was not called yet.
 func (listener *onceListener) Handle(event Event) error {
-	var err error
-	listener.Once.Do(func() {
-		err = listener.Listener.Handle(event)
-	})
-	return err
+	if !listener.take() {
+		return nil
+	}
+	return listener.Listener.Handle(event)
+}
+
+// Unwrap returns the wrapped Listener.
+func (listener *onceListener) Unwrap() Listener {
+	return listener.Listener
+}
+
+// take reports whether the caller is the first one to fire the listener.
+func (listener *onceListener) take() bool {
+	return listener.fired.CompareAndSwap(false, true)
+}
+
+// WithTimeout returns a Listener that calls lis with the context of event limited by timeout,
+// it overrides the ListenerTimeout of bus. The returned Listener must be used to Off it.
+func WithTimeout(lis Listener, timeout time.Duration) Listener {
+	if lis == nil {
+		return nil
+	}
+	return &timeoutListener{Listener: lis, timeout: timeout}
+}
+
+// timeoutListener is the Listener of WithTimeout, the timeout is applied by bus.
+type timeoutListener struct {
+	Listener Listener
+	timeout  time.Duration
+}
+
+func (listener *timeoutListener) Handle(event Event) error {
+	return listener.Listener.Handle(event)
+}
+
+// Unwrap returns the wrapped Listener.
+func (listener *timeoutListener) Unwrap() Listener {
+	return listener.Listener
+}
+
+// Meta is the metadata of the Event passed to the listeners added by Subscribe.
+type Meta struct {
+	// ID is the id of the event.
+	ID any
+	// When is the time of the event.
+	When time.Time
+}
+
+// Unsubscribe removes the listener added by Subscribe, it is safe to call it more than once.
+type Unsubscribe func() error
+
+// Subscribe adds a listener of the events whose body is of type T, fn is called with the body and the context of event.
+// If T is an interface type, the listener is routed by Assignable, it listens to every body type that implements T.
+// fn needn't be comparable, the listener is removed by the returned Unsubscribe.
+// opts are the ListenerOption of the listener, e.g. Priority and Group.
+func Subscribe[T any](bus Bus, fn func(ctx context.Context, body T, meta Meta) error, opts ...ListenerOption) (Unsubscribe, error) {
+	if fn == nil {
+		return nil, ErrListenerNil
+	}
+	lis := &typedListener[T]{fn: fn}
+	if reflect.TypeOf((*T)(nil)).Elem().Kind() == reflect.Interface {
+		if err := bus.Route(Assignable[T](), lis, opts...); err != nil {
+			return nil, err
+		}
+		return func() error {
+			return bus.OffRoute(lis)
+		}, nil
+	}
+	e := NewEvent(*new(T), nil)
+	if err := bus.On(e, lis, opts...); err != nil {
+		return nil, err
+	}
+	return func() error {
+		return bus.Off(e, lis)
+	}, nil
+}
+
+// typedListener is the Listener of Subscribe, the body is asserted to T without reflection.
+type typedListener[T any] struct {
+	fn func(ctx context.Context, body T, meta Meta) error
+}
+
+func (listener *typedListener[T]) Handle(event Event) error {
+	body, ok := event.Body().(T)
+	if !ok {
+		return fmt.Errorf("%w: %T is not %T", ErrEventTypeInvalid, event.Body(), body)
+	}
+	return listener.fn(event.Context(), body, Meta{ID: event.ID(), When: event.When()})
 }
 
-//var wrapReflectListenerSlice = syncx.WrapSlice[[]*reflectListener]
-//
-//type syncReflectListenerSlice = syncx.Slice[[]*reflectListener, *reflectListener]
-//
-//type reflectListener struct {
-//	listenerVal    reflect.Value
-//	listenerMethod reflect.Method
-//	eventType      reflect.Type
-//}
-//
-//func (listener *reflectListener) Handle(event Event) error {
-//	resultValues := listener.listenerMethod.Func.Call(
-//		[]reflect.Value{
-//			listener.listenerVal,
-//			reflect.ValueOf(event),
-//		})
-//	err := resultValues[0].Interface()
-//	if err != nil {
-//		return err.(error)
-//	}
-//	return nil
-//}
-//
 //var wrapOnceListenerSlice = syncx.WrapSlice[[]*onceListener]
 //
 //type syncOnceListenerSlice = syncx.Slice[[]*onceListener, *onceListener]
@@ -1,15 +1,23 @@
 package event
 
 import (
+	"github.com/go-leo/design-pattern/instrument"
+	"github.com/go-leo/design-pattern/internal/lifecycle"
 	"github.com/go-leo/gox/syncx/gopher"
 	"github.com/go-leo/gox/syncx/gopher/sample"
+	"log"
 	"sync"
-	"sync/atomic"
+	"time"
 )
 
 type option struct {
-	Pool       gopher.Gopher
-	MaxBackoff int
+	Pool            gopher.Gopher
+	MaxBackoff      int
+	DrainTimeout    time.Duration
+	Hook            instrument.Hook
+	MaxListeners    int
+	ListenerTimeout time.Duration
+	OnExceeded      func(err *MaxListenersError) error
 }
 
 func newOption(opts ...Option) *option {
@@ -23,11 +31,18 @@ func newOption(opts ...Option) *option {
 	if o.MaxBackoff == 0 {
 		o.MaxBackoff = 16
 	}
+	if o.OnExceeded == nil {
+		o.OnExceeded = func(err *MaxListenersError) error {
+			return err
+		}
+	}
 	return o
 }
 
 type Option func(*option)
 
+// Pool sets the gopher.Gopher that runs the listeners of AsyncEmit, default is sample.Gopher{},
+// which starts a goroutine per call. Use a workerpool.Pool to bound the goroutines and the queued calls.
 func Pool(pool gopher.Gopher) Option {
 	return func(o *option) {
 		o.Pool = pool
@@ -40,12 +55,94 @@ func MaxBackoff(backoff int) Option {
 	}
 }
 
+// DrainTimeout sets the max duration that Close waits for the in-flight listeners,
+// after that, the contexts of their events are canceled and Close returns an AbandonedError.
+// Zero means Close waits until its context is done.
+func DrainTimeout(timeout time.Duration) Option {
+	return func(o *option) {
+		o.DrainTimeout = timeout
+	}
+}
+
+// Hook sets the instrument.Hook that observes every call of listeners.
+// The listeners of AsyncEmit are observed since they are submitted to the pool.
+func Hook(hook instrument.Hook) Option {
+	return func(o *option) {
+		o.Hook = hook
+	}
+}
+
+// ListenerTimeout sets the max duration of each call of listeners, the context of event passed to a listener
+// is canceled after the timeout, default is no timeout. WithTimeout overrides it for a listener.
+func ListenerTimeout(timeout time.Duration) Option {
+	return func(o *option) {
+		o.ListenerTimeout = timeout
+	}
+}
+
+// MaxListeners sets the max listeners of each event type, default is 0 that means no limit.
+// It can be changed by Bus.SetMaxListeners later. A listener beyond the limit is rejected with a MaxListenersError,
+// unless MaxListenersExceeded sets another handler.
+func MaxListeners(n int) Option {
+	return func(o *option) {
+		o.MaxListeners = n
+	}
+}
+
+// MaxListenersExceeded sets the function that is called when a listener is added beyond the max listeners,
+// the listener is not added if it returns an error. Default returns err, that rejects the listener,
+// use WarnMaxListeners to log a warning and add the listener.
+func MaxListenersExceeded(f func(err *MaxListenersError) error) Option {
+	return func(o *option) {
+		o.OnExceeded = f
+	}
+}
+
+// WarnMaxListeners is a handler of MaxListenersExceeded that logs a warning by the log package,
+// and adds the listener.
+func WarnMaxListeners(err *MaxListenersError) error {
+	log.Printf("event: warning: %s", err)
+	return nil
+}
+
 func NewBus(opts ...Option) Bus {
-	return &bus{
-		listenerMap:     sync.Map{},
-		onceListenerMap: sync.Map{},
-		wg:              sync.WaitGroup{},
-		inShutdown:      atomic.Bool{},
-		options:         newOption(opts...),
+	b := &bus{
+		listenerMap: sync.Map{},
+		tracker:     lifecycle.NewTracker(),
+		options:     newOption(opts...),
+	}
+	b.SetMaxListeners(b.options.MaxListeners)
+	return b
+}
+
+type listenerOption struct {
+	Priority int
+	Group    string
+}
+
+func newListenerOption(opts ...ListenerOption) *listenerOption {
+	o := &listenerOption{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// ListenerOption is the option of a listener added to Bus.
+type ListenerOption func(*listenerOption)
+
+// Priority sets the priority of listener, the listeners of higher priority are called first, default is 0.
+func Priority(priority int) ListenerOption {
+	return func(o *listenerOption) {
+		o.Priority = priority
+	}
+}
+
+// Group sets the group of listener, it is usually the name of the module that adds the listener.
+// The listeners of a group with the same priority are kept together, in the position of the first one added,
+// and they can be removed by Bus.OffGroup.
+func Group(name string) ListenerOption {
+	return func(o *listenerOption) {
+		o.Group = name
 	}
 }
@@ -0,0 +1,88 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// Result is the result of a listener called by AsyncEmit.
+type Result struct {
+	// Listener is the called listener, the one-time listeners are unwrapped.
+	Listener Listener
+	// Err is the error returned by the listener, or the reason why it was not called,
+	// e.g. the context of emit was done or the pool rejected it.
+	Err error
+	// Duration is the time the listener took, zero if it was not called.
+	Duration time.Duration
+}
+
+// Report reports the results of the listeners called by AsyncEmit.
+type Report struct {
+	err       error // the error of emit, no listener is called if it is not nil
+	results   []Result
+	remaining atomic.Int64
+	done      chan struct{}
+}
+
+func newReport(listeners []Listener) *Report {
+	r := &Report{results: make([]Result, len(listeners)), done: make(chan struct{})}
+	for i, listener := range listeners {
+		r.results[i].Listener = listener
+	}
+	r.remaining.Store(int64(len(listeners)))
+	if len(listeners) == 0 {
+		close(r.done)
+	}
+	return r
+}
+
+// failedReport returns a Report of an emit that failed before calling any listener.
+func failedReport(err error) *Report {
+	r := newReport(nil)
+	r.err = err
+	return r
+}
+
+// complete records the result of the i-th listener.
+func (r *Report) complete(i int, err error, duration time.Duration) {
+	r.results[i].Err = err
+	r.results[i].Duration = duration
+	if r.remaining.Add(-1) == 0 {
+		close(r.done)
+	}
+}
+
+// Done returns a channel that's closed when all listeners returned.
+func (r *Report) Done() <-chan struct{} {
+	return r.done
+}
+
+// Wait waits for all listeners, and returns their results in the order of listeners.
+// err is the error of emit, e.g. ErrBusClosed or ErrListener, or the error of ctx if ctx is done first.
+// The errors of listeners are reported by the results only.
+func (r *Report) Wait(ctx context.Context) ([]Result, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	select {
+	case <-r.done:
+		return r.results, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Err waits for all listeners, and returns the error of emit and the errors of listeners joined.
+func (r *Report) Err() error {
+	results, err := r.Wait(context.Background())
+	if err != nil {
+		return err
+	}
+	errs := make([]error, 0, len(results))
+	for _, result := range results {
+		errs = append(errs, result.Err)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,119 @@
+package event
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"strings"
+)
+
+// Route matches the events that a listener added by Bus.Route listens to, besides the exact type of body.
+type Route interface {
+	// Match reports whether the listener listens to the events of the body type and topic,
+	// the topic is empty if the body is not Topical.
+	// The result must not change, as it is cached by the type and topic.
+	Match(eventType reflect.Type, topic string) bool
+}
+
+// Topical is implemented by the bodies of events that have a topic, e.g. "order.created", see Topic.
+type Topical interface {
+	Topic() string
+}
+
+// topicOf returns the topic of event, it is empty if the body is not Topical.
+func topicOf(e Event) string {
+	if topical, ok := e.Body().(Topical); ok {
+		return topical.Topic()
+	}
+	return ""
+}
+
+// All returns a Route that matches all events.
+func All() Route {
+	return allRoute{}
+}
+
+type allRoute struct{}
+
+func (allRoute) Match(reflect.Type, string) bool {
+	return true
+}
+
+func (allRoute) String() string {
+	return "*"
+}
+
+// Assignable returns a Route that matches the events whose body is assignable to T,
+// e.g. the bodies that implement an interface like ddd.DomainEvent.
+func Assignable[T any]() Route {
+	return assignableRoute{target: reflect.TypeOf((*T)(nil)).Elem()}
+}
+
+type assignableRoute struct {
+	target reflect.Type
+}
+
+func (r assignableRoute) Match(eventType reflect.Type, _ string) bool {
+	return eventType.AssignableTo(r.target)
+}
+
+func (r assignableRoute) String() string {
+	return "assignable to " + r.target.String()
+}
+
+// Topic returns a Route that matches the events whose body is Topical and whose topic matches the pattern.
+// The topic is hierarchical, the levels are separated by ".", e.g. "order.created".
+// A level of pattern is matched as path.Match, e.g. "order.*" and "order.create?",
+// and "**" matches zero or more levels, e.g. "order.**" matches "order" and "order.item.added".
+func Topic(pattern string) Route {
+	r := topicRoute{pattern: pattern, levels: strings.Split(pattern, ".")}
+	for _, level := range r.levels {
+		if _, err := path.Match(level, ""); err != nil {
+			r.err = fmt.Errorf("%w: topic pattern %q: %w", ErrRouteInvalid, pattern, err)
+			break
+		}
+	}
+	return r
+}
+
+type topicRoute struct {
+	pattern string
+	levels  []string
+	err     error
+}
+
+func (r topicRoute) Match(_ reflect.Type, topic string) bool {
+	if r.err != nil || topic == "" {
+		return false
+	}
+	return matchLevels(r.levels, strings.Split(topic, "."))
+}
+
+func (r topicRoute) String() string {
+	return "topic " + r.pattern
+}
+
+func (r topicRoute) validate() error {
+	return r.err
+}
+
+func matchLevels(pattern []string, topic []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(topic); i++ {
+				if matchLevels(pattern[1:], topic[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(topic) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], topic[0]); !ok {
+			return false
+		}
+		pattern, topic = pattern[1:], topic[1:]
+	}
+	return len(topic) == 0
+}
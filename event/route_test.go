@@ -0,0 +1,118 @@
+package event
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type domainEvent interface {
+	AggregateID() string
+}
+
+type orderCreated struct {
+	ID string
+}
+
+func (e orderCreated) AggregateID() string {
+	return e.ID
+}
+
+func (e orderCreated) Topic() string {
+	return "order.created"
+}
+
+type orderItemAdded struct {
+	ID string
+}
+
+func (e *orderItemAdded) AggregateID() string {
+	return e.ID
+}
+
+func (e *orderItemAdded) Topic() string {
+	return "order.item.added"
+}
+
+func TestTopic(t *testing.T) {
+	tests := []struct {
+		pattern string
+		topic   string
+		want    bool
+	}{
+		{pattern: "order.created", topic: "order.created", want: true},
+		{pattern: "order.*", topic: "order.created", want: true},
+		{pattern: "order.*", topic: "order.item.added", want: false},
+		{pattern: "order.**", topic: "order.item.added", want: true},
+		{pattern: "order.**", topic: "order", want: true},
+		{pattern: "**.added", topic: "order.item.added", want: true},
+		{pattern: "order.create?", topic: "order.created", want: true},
+		{pattern: "*", topic: "order.created", want: false},
+		{pattern: "**", topic: "", want: false},
+		{pattern: "order.[", topic: "order.[", want: false},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.want, Topic(test.pattern).Match(nil, test.topic), "%s %s", test.pattern, test.topic)
+	}
+}
+
+func TestBus_Route(t *testing.T) {
+	bus := NewBus()
+	var calls []string
+	all := &recordListener{name: "all", calls: &calls}
+	domain := &recordListener{name: "domain", calls: &calls}
+	orders := &recordListener{name: "orders", calls: &calls}
+	created := &recordListener{name: "created", calls: &calls}
+	assert.NoError(t, bus.Route(All(), all, Priority(-1)))
+	assert.NoError(t, bus.Route(Assignable[domainEvent](), domain))
+	assert.NoError(t, bus.Route(Topic("order.**"), orders, Priority(1)))
+	assert.NoError(t, bus.On(NewEvent(orderCreated{}, nil), created))
+	assert.ErrorIs(t, bus.Route(Topic("order.["), all), ErrRouteInvalid)
+	assert.ErrorIs(t, bus.Route(nil, all), ErrRouteInvalid)
+
+	assert.NoError(t, bus.Emit(NewEvent(orderCreated{ID: "1"}, nil)))
+	assert.Equal(t, []string{"orders", "domain", "created", "all"}, calls)
+
+	calls = nil
+	assert.NoError(t, bus.Emit(NewEvent(&orderItemAdded{ID: "1"}, nil)))
+	assert.Equal(t, []string{"orders", "domain", "all"}, calls)
+
+	calls = nil
+	assert.NoError(t, bus.AsyncEmit(NewEvent(1, nil)).Err())
+	assert.Equal(t, []string{"all"}, calls)
+	assert.Equal(t, []Listener{orders, domain, created, all}, bus.Listeners(NewEvent(orderCreated{}, nil)))
+	assert.Equal(t, 1, bus.ListenerCount(NewEvent("s", nil)))
+
+	// the index is invalidated by the changes of listeners
+	calls = nil
+	assert.NoError(t, bus.OffRoute(domain))
+	assert.NoError(t, bus.Off(NewEvent(orderCreated{}, nil), created))
+	assert.NoError(t, bus.Emit(NewEvent(orderCreated{ID: "1"}, nil)))
+	assert.Equal(t, []string{"orders", "all"}, calls)
+	assert.Len(t, bus.Events(), 0)
+
+	calls = nil
+	assert.NoError(t, bus.Close(context.Background()))
+	assert.ErrorIs(t, bus.Route(All(), all), ErrBusClosed)
+	assert.ErrorIs(t, bus.Emit(NewEvent(orderCreated{ID: "1"}, nil)), ErrBusClosed)
+	assert.Empty(t, calls)
+}
+
+func TestSubscribe_Interface(t *testing.T) {
+	bus := NewBus()
+	var ids []string
+	unsubscribe, err := Subscribe[domainEvent](bus, func(ctx context.Context, body domainEvent, meta Meta) error {
+		ids = append(ids, body.AggregateID())
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, bus.Emit(NewEvent(orderCreated{ID: "1"}, nil)))
+	assert.NoError(t, bus.Emit(NewEvent(&orderItemAdded{ID: "2"}, nil)))
+	assert.NoError(t, bus.Emit(NewEvent(1, nil)))
+	assert.Equal(t, []string{"1", "2"}, ids)
+
+	assert.NoError(t, unsubscribe())
+	assert.NoError(t, bus.Emit(NewEvent(orderCreated{ID: "3"}, nil)))
+	assert.Equal(t, []string{"1", "2"}, ids)
+}
@@ -1,13 +1,200 @@
 package event
 
-//import (
-//	"context"
-//)
-//
-//type Store interface {
-//	AllStoredEventsBetween(ctx context.Context, lowStoredEventId int64, highStoredEventId int64) ([]Event, error)
-//	AllStoredEventsSince(ctx context.Context, storedEventId int64) ([]Event, error)
-//	//Append(Event Event) Event
-//	Close(ctx context.Context) error
-//	CountStoredEvents(ctx context.Context) int64
-//}
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+const (
+	// AnyVersion is the expected version of Store.Append that skips the version check.
+	AnyVersion int64 = -1
+
+	// NoStream is the expected version of Store.Append that expects the stream doesn't exist yet.
+	NoStream int64 = 0
+)
+
+// StoredEvent is an Event stored in a Store.
+type StoredEvent struct {
+	// Sequence is the global sequence of the event in the store, it starts from 1 and has no gaps.
+	Sequence int64
+	// StreamID is the id of the stream that the event belongs to, e.g. the id of an aggregate.
+	StreamID string
+	// Version is the version of the stream after the event, it starts from 1 and has no gaps.
+	Version int64
+	// Event is the stored event, its context is not stored.
+	Event Event
+}
+
+// Store is an append-only store of events. The events are ordered by a global sequence,
+// and grouped by streams, each stream is ordered by its version.
+type Store interface {
+	// Append appends the events to the end of stream atomically, and returns them as stored.
+	// The ids of events must be strings, so that they are kept by durable stores, see CheckStoredEvent.
+	// expectedVersion is the version of stream that the caller read, NoStream if the stream is new,
+	// if the actual version differs a VersionConflictError is returned. AnyVersion skips the check.
+	Append(ctx context.Context, streamID string, expectedVersion int64, events ...Event) ([]StoredEvent, error)
+
+	// AllStoredEventsBetween returns the events whose sequence is in [lowStoredEventId, highStoredEventId], in order.
+	AllStoredEventsBetween(ctx context.Context, lowStoredEventId int64, highStoredEventId int64) ([]StoredEvent, error)
+
+	// AllStoredEventsSince returns the events whose sequence is greater than storedEventId, in order.
+	AllStoredEventsSince(ctx context.Context, storedEventId int64) ([]StoredEvent, error)
+
+	// StreamEvents returns the events of stream whose version is in [fromVersion, toVersion], in order.
+	// toVersion <= 0 means the end of stream. A stream that doesn't exist has no events.
+	StreamEvents(ctx context.Context, streamID string, fromVersion int64, toVersion int64) ([]StoredEvent, error)
+
+	// StreamVersion returns the current version of stream, NoStream if the stream doesn't exist.
+	StreamVersion(ctx context.Context, streamID string) (int64, error)
+
+	// CountStoredEvents returns the number of events in the store, that is the last sequence.
+	CountStoredEvents(ctx context.Context) (int64, error)
+
+	// Close closes the store, the methods return ErrStoreClosed after it.
+	Close(ctx context.Context) error
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// MemoryStore is an in-memory Store, it is for tests and the processes that needn't durable events.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	events  []StoredEvent
+	streams map[string][]int // stream -> indexes of events
+	closed  bool
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{streams: make(map[string][]int)}
+}
+
+func (s *MemoryStore) Append(_ context.Context, streamID string, expectedVersion int64, events ...Event) ([]StoredEvent, error) {
+	for _, e := range events {
+		if err := CheckStoredEvent(e); err != nil {
+			return nil, err
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, ErrStoreClosed
+	}
+	version := int64(len(s.streams[streamID]))
+	if err := CheckVersion(streamID, expectedVersion, version); err != nil {
+		return nil, err
+	}
+	stored := make([]StoredEvent, 0, len(events))
+	for _, e := range events {
+		version++
+		storedEvent := StoredEvent{
+			Sequence: int64(len(s.events)) + 1,
+			StreamID: streamID,
+			Version:  version,
+			Event:    NewEventAt(e.Body(), e.ID(), e.When()),
+		}
+		s.streams[streamID] = append(s.streams[streamID], len(s.events))
+		s.events = append(s.events, storedEvent)
+		stored = append(stored, storedEvent)
+	}
+	return stored, nil
+}
+
+func (s *MemoryStore) AllStoredEventsBetween(_ context.Context, lowStoredEventId int64, highStoredEventId int64) ([]StoredEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return nil, ErrStoreClosed
+	}
+	low, high := SequenceRange(lowStoredEventId, highStoredEventId, int64(len(s.events)))
+	if low > high {
+		return nil, nil
+	}
+	events := make([]StoredEvent, high-low+1)
+	copy(events, s.events[low-1:high])
+	return events, nil
+}
+
+func (s *MemoryStore) AllStoredEventsSince(ctx context.Context, storedEventId int64) ([]StoredEvent, error) {
+	return s.AllStoredEventsBetween(ctx, storedEventId+1, 0)
+}
+
+func (s *MemoryStore) StreamEvents(_ context.Context, streamID string, fromVersion int64, toVersion int64) ([]StoredEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return nil, ErrStoreClosed
+	}
+	indexes := s.streams[streamID]
+	from, to := SequenceRange(fromVersion, toVersion, int64(len(indexes)))
+	if from > to {
+		return nil, nil
+	}
+	events := make([]StoredEvent, 0, to-from+1)
+	for _, i := range indexes[from-1 : to] {
+		events = append(events, s.events[i])
+	}
+	return events, nil
+}
+
+func (s *MemoryStore) StreamVersion(_ context.Context, streamID string) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return 0, ErrStoreClosed
+	}
+	return int64(len(s.streams[streamID])), nil
+}
+
+func (s *MemoryStore) CountStoredEvents(_ context.Context) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return 0, ErrStoreClosed
+	}
+	return int64(len(s.events)), nil
+}
+
+func (s *MemoryStore) Close(_ context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrStoreClosed
+	}
+	s.closed = true
+	return nil
+}
+
+// CheckVersion returns a VersionConflictError if the actual version of stream is not the expected version,
+// it is for the implementations of Store.Append.
+func CheckVersion(streamID string, expected int64, actual int64) error {
+	if expected == AnyVersion || expected == actual {
+		return nil
+	}
+	return &VersionConflictError{StreamID: streamID, Expected: expected, Actual: actual}
+}
+
+// SequenceRange clamps the inclusive range [low, high] of sequences or versions to [1, last],
+// high <= 0 means last. The range is empty if low > high. It is for the implementations of Store.
+func SequenceRange(low int64, high int64, last int64) (int64, int64) {
+	if high <= 0 || high > last {
+		high = last
+	}
+	return max(low, 1), high
+}
+
+// CheckStoredEvent returns an error if the event can't be stored, that is nil, or has a nil body,
+// or has an id that is not a string. It is for the implementations of Store.Append.
+func CheckStoredEvent(e Event) error {
+	if e == nil {
+		return ErrEventNil
+	}
+	if e.Body() == nil {
+		return ErrEventTypeInvalid
+	}
+	if _, ok := e.ID().(string); !ok {
+		return fmt.Errorf("%w: %T", ErrEventIDInvalid, e.ID())
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+package event_test
+
+import (
+	"testing"
+
+	"github.com/go-leo/design-pattern/event"
+	"github.com/go-leo/design-pattern/event/storetest"
+)
+
+func TestMemoryStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) event.Store {
+		return event.NewMemoryStore()
+	})
+}
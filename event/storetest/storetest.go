@@ -0,0 +1,285 @@
+// Package storetest provides a conformance test suite for the implementations of event.Store.
+package storetest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-leo/design-pattern/event"
+	"github.com/stretchr/testify/assert"
+)
+
+// Created is a body of the events appended by the suite.
+type Created struct {
+	Name string
+}
+
+// Renamed is a body of the events appended by the suite, it is appended as a pointer.
+type Renamed struct {
+	From string
+	To   string
+}
+
+// Codec returns an event.Codec that can decode the bodies appended by the suite.
+func Codec() event.Codec {
+	return event.NewJSONCodec(Created{}, &Renamed{})
+}
+
+// NewStore returns an empty event.Store for a test, the suite closes it.
+type NewStore func(t *testing.T) event.Store
+
+// Run runs the conformance test suite against the stores returned by newStore.
+func Run(t *testing.T, newStore NewStore) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, store event.Store)
+	}{
+		{name: "Append", test: testAppend},
+		{name: "ExpectedVersion", test: testExpectedVersion},
+		{name: "AllStoredEvents", test: testAllStoredEvents},
+		{name: "StreamEvents", test: testStreamEvents},
+		{name: "ConcurrentAppend", test: testConcurrentAppend},
+		{name: "Close", test: testClose},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := newStore(t)
+			defer store.Close(context.Background())
+			test.test(t, store)
+		})
+	}
+}
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// newEvent returns an event with a string id and a fixed time, that survive the encoding of durable stores,
+// the ids of stored events must be strings.
+func newEvent(body any, n int) event.Event {
+	return event.NewEventAt(body, fmt.Sprintf("event-%d", n), epoch.Add(time.Duration(n)*time.Second))
+}
+
+// AssertEqual asserts that the stored events are equal, the time is compared by time.Time.Equal.
+func AssertEqual(t *testing.T, expected []event.StoredEvent, actual []event.StoredEvent) bool {
+	t.Helper()
+	if !assert.Len(t, actual, len(expected)) {
+		return false
+	}
+	ok := true
+	for i := range expected {
+		e, a := expected[i], actual[i]
+		ok = assert.Equal(t, e.Sequence, a.Sequence, "sequence of %d", i) && ok
+		ok = assert.Equal(t, e.StreamID, a.StreamID, "stream of %d", i) && ok
+		ok = assert.Equal(t, e.Version, a.Version, "version of %d", i) && ok
+		ok = assert.Equal(t, e.Event.ID(), a.Event.ID(), "id of %d", i) && ok
+		ok = assert.Equal(t, e.Event.Body(), a.Event.Body(), "body of %d", i) && ok
+		ok = assert.True(t, e.Event.When().Equal(a.Event.When()), "time of %d", i) && ok
+	}
+	return ok
+}
+
+func testAppend(t *testing.T, store event.Store) {
+	ctx := context.Background()
+	stored, err := store.Append(ctx, "user-1", event.NoStream, newEvent(Created{Name: "jax"}, 1), newEvent(&Renamed{From: "jax", To: "leo"}, 2))
+	if !assert.NoError(t, err) {
+		return
+	}
+	expected := []event.StoredEvent{
+		{Sequence: 1, StreamID: "user-1", Version: 1, Event: newEvent(Created{Name: "jax"}, 1)},
+		{Sequence: 2, StreamID: "user-1", Version: 2, Event: newEvent(&Renamed{From: "jax", To: "leo"}, 2)},
+	}
+	AssertEqual(t, expected, stored)
+
+	all, err := store.AllStoredEventsSince(ctx, 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+	AssertEqual(t, expected, all)
+
+	version, err := store.StreamVersion(ctx, "user-1")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), version)
+	version, err = store.StreamVersion(ctx, "user-2")
+	assert.NoError(t, err)
+	assert.Equal(t, event.NoStream, version)
+
+	count, err := store.CountStoredEvents(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	stored, err = store.Append(ctx, "user-1", 2)
+	assert.NoError(t, err)
+	assert.Empty(t, stored)
+	_, err = store.Append(ctx, "user-1", 2, nil)
+	assert.ErrorIs(t, err, event.ErrEventNil)
+
+	// the ids must be strings, the stores don't have to keep the types of other ids
+	_, err = store.Append(ctx, "user-1", 2, event.NewEventAt(Created{Name: "max"}, 3, epoch))
+	assert.ErrorIs(t, err, event.ErrEventIDInvalid)
+	_, err = store.Append(ctx, "user-1", 2, newEvent(Created{Name: "max"}, 3), event.NewEventAt(Created{Name: "max"}, nil, epoch))
+	assert.ErrorIs(t, err, event.ErrEventIDInvalid)
+	count, err = store.CountStoredEvents(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+}
+
+func testExpectedVersion(t *testing.T, store event.Store) {
+	ctx := context.Background()
+	_, err := store.Append(ctx, "user-1", event.NoStream, newEvent(Created{Name: "jax"}, 1))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = store.Append(ctx, "user-1", event.NoStream, newEvent(Created{Name: "leo"}, 2))
+	assert.ErrorIs(t, err, event.ErrVersionConflict)
+	var conflict *event.VersionConflictError
+	if assert.ErrorAs(t, err, &conflict) {
+		assert.Equal(t, &event.VersionConflictError{StreamID: "user-1", Expected: event.NoStream, Actual: 1}, conflict)
+	}
+	_, err = store.Append(ctx, "user-2", 1, newEvent(Created{Name: "leo"}, 2))
+	assert.ErrorIs(t, err, event.ErrVersionConflict)
+
+	// the failed appends append nothing
+	count, err := store.CountStoredEvents(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	stored, err := store.Append(ctx, "user-1", 1, newEvent(&Renamed{From: "jax", To: "leo"}, 3))
+	assert.NoError(t, err)
+	AssertEqual(t, []event.StoredEvent{{Sequence: 2, StreamID: "user-1", Version: 2, Event: newEvent(&Renamed{From: "jax", To: "leo"}, 3)}}, stored)
+
+	stored, err = store.Append(ctx, "user-1", event.AnyVersion, newEvent(&Renamed{From: "leo", To: "max"}, 4))
+	assert.NoError(t, err)
+	AssertEqual(t, []event.StoredEvent{{Sequence: 3, StreamID: "user-1", Version: 3, Event: newEvent(&Renamed{From: "leo", To: "max"}, 4)}}, stored)
+}
+
+// appendStreams appends 3 events to each of "a" and "b" alternately, the sequence n is of the event n.
+func appendStreams(t *testing.T, store event.Store) []event.StoredEvent {
+	var all []event.StoredEvent
+	for n := 1; n <= 6; n++ {
+		streamID := "a"
+		if n%2 == 0 {
+			streamID = "b"
+		}
+		stored, err := store.Append(context.Background(), streamID, event.AnyVersion, newEvent(Created{Name: fmt.Sprint(n)}, n))
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		all = append(all, stored...)
+	}
+	return all
+}
+
+func testAllStoredEvents(t *testing.T, store event.Store) {
+	ctx := context.Background()
+	all := appendStreams(t, store)
+
+	tests := []struct {
+		low, high int64
+		expected  []event.StoredEvent
+	}{
+		{low: 1, high: 6, expected: all},
+		{low: 2, high: 4, expected: all[1:4]},
+		{low: 0, high: 0, expected: all},
+		{low: 5, high: 100, expected: all[4:]},
+		{low: 4, high: 3, expected: nil},
+		{low: 7, high: 8, expected: nil},
+	}
+	for _, test := range tests {
+		events, err := store.AllStoredEventsBetween(ctx, test.low, test.high)
+		assert.NoError(t, err)
+		AssertEqual(t, test.expected, events)
+	}
+
+	events, err := store.AllStoredEventsSince(ctx, 4)
+	assert.NoError(t, err)
+	AssertEqual(t, all[4:], events)
+	events, err = store.AllStoredEventsSince(ctx, 6)
+	assert.NoError(t, err)
+	assert.Empty(t, events)
+
+	count, err := store.CountStoredEvents(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(6), count)
+}
+
+func testStreamEvents(t *testing.T, store event.Store) {
+	ctx := context.Background()
+	all := appendStreams(t, store)
+	a := []event.StoredEvent{all[0], all[2], all[4]}
+	b := []event.StoredEvent{all[1], all[3], all[5]}
+	for i := range a {
+		assert.Equal(t, int64(i+1), a[i].Version)
+		assert.Equal(t, int64(i+1), b[i].Version)
+	}
+
+	tests := []struct {
+		streamID string
+		from, to int64
+		expected []event.StoredEvent
+	}{
+		{streamID: "a", from: 1, to: 0, expected: a},
+		{streamID: "b", from: 2, to: 0, expected: b[1:]},
+		{streamID: "b", from: 1, to: 2, expected: b[:2]},
+		{streamID: "a", from: 3, to: 3, expected: a[2:]},
+		{streamID: "a", from: 4, to: 0, expected: nil},
+		{streamID: "c", from: 1, to: 0, expected: nil},
+	}
+	for _, test := range tests {
+		events, err := store.StreamEvents(ctx, test.streamID, test.from, test.to)
+		assert.NoError(t, err)
+		AssertEqual(t, test.expected, events)
+	}
+}
+
+func testConcurrentAppend(t *testing.T, store event.Store) {
+	ctx := context.Background()
+	const writers = 8
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	conflicts := 0
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// all writers read version 0, only one of them wins
+			_, err := store.Append(ctx, "contended", event.NoStream, newEvent(Created{Name: fmt.Sprint(i)}, i))
+			if err != nil {
+				assert.ErrorIs(t, err, event.ErrVersionConflict)
+				mu.Lock()
+				conflicts++
+				mu.Unlock()
+			}
+			_, err = store.Append(ctx, fmt.Sprintf("own-%d", i), event.NoStream, newEvent(Created{Name: fmt.Sprint(i)}, i))
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, writers-1, conflicts)
+
+	all, err := store.AllStoredEventsSince(ctx, 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Len(t, all, writers+1)
+	for i, e := range all {
+		assert.Equal(t, int64(i+1), e.Sequence)
+	}
+}
+
+func testClose(t *testing.T, store event.Store) {
+	ctx := context.Background()
+	assert.NoError(t, store.Close(ctx))
+	_, err := store.Append(ctx, "user-1", event.AnyVersion, newEvent(Created{Name: "jax"}, 1))
+	assert.ErrorIs(t, err, event.ErrStoreClosed)
+	_, err = store.AllStoredEventsSince(ctx, 0)
+	assert.ErrorIs(t, err, event.ErrStoreClosed)
+	_, err = store.StreamEvents(ctx, "user-1", 1, 0)
+	assert.ErrorIs(t, err, event.ErrStoreClosed)
+	_, err = store.StreamVersion(ctx, "user-1")
+	assert.ErrorIs(t, err, event.ErrStoreClosed)
+	_, err = store.CountStoredEvents(ctx)
+	assert.ErrorIs(t, err, event.ErrStoreClosed)
+}
@@ -0,0 +1,91 @@
+package eventsource
+
+import (
+	"slices"
+
+	"github.com/go-leo/design-pattern/ddd"
+	"github.com/go-leo/design-pattern/event"
+	"github.com/google/uuid"
+)
+
+// Aggregate is an event sourced aggregate root, its state is changed only by applying events.
+// An implementation embeds Root and implements Apply, and its commands change the state by Raise, e.g.
+//
+//	type Order struct {
+//		eventsource.Root
+//		status string
+//	}
+//
+//	func (o *Order) Ship() error {
+//		if o.status != "paid" {
+//			return errors.New("order is not paid")
+//		}
+//		return eventsource.Raise(o, OrderShipped{})
+//	}
+//
+//	func (o *Order) Apply(e event.Event) error {
+//		switch e.Body().(type) {
+//		case OrderShipped:
+//			o.status = "shipped"
+//		}
+//		return nil
+//	}
+type Aggregate interface {
+	ddd.AggregateRoot
+
+	// Apply changes the state by the event. It is called by Raise for a new event,
+	// and by Repository.Load to replay the stored events, so it must not have other side effects.
+	Apply(e event.Event) error
+
+	// Uncommitted return the events raised since the aggregate was loaded or saved.
+	Uncommitted() []event.Event
+
+	root() *Root
+}
+
+var _ ddd.AggregateRoot = (*Root)(nil)
+
+// Root keeps the identity, the version and the uncommitted events of an Aggregate, it is embedded in the aggregate.
+type Root struct {
+	id          string
+	version     int64 // the version of the committed events
+	uncommitted []event.Event
+}
+
+// NewRoot returns a Root of the aggregate identified by id.
+func NewRoot(id string) Root {
+	return Root{id: id}
+}
+
+// AggregateID return the identity of the aggregate.
+func (r *Root) AggregateID() string {
+	return r.id
+}
+
+// Version return the version of the aggregate, that is the number of events applied, including the uncommitted.
+func (r *Root) Version() int64 {
+	return r.version + int64(len(r.uncommitted))
+}
+
+// Uncommitted return the events raised since the aggregate was loaded or saved.
+func (r *Root) Uncommitted() []event.Event {
+	return slices.Clone(r.uncommitted)
+}
+
+func (r *Root) root() *Root {
+	return r
+}
+
+// Raise applies a new event of body to the aggregate, and records it as uncommitted if Apply succeeds.
+func Raise(a Aggregate, body any) error {
+	if body == nil {
+		return event.ErrEventNil
+	}
+	e := event.NewEvent(body, uuid.NewString())
+	if err := a.Apply(e); err != nil {
+		return err
+	}
+	r := a.root()
+	r.uncommitted = append(r.uncommitted, e)
+	return nil
+}
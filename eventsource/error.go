@@ -0,0 +1,38 @@
+package eventsource
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrAggregateNotFound aggregate has no events
+	ErrAggregateNotFound = errors.New("aggregate not found")
+
+	// ErrConflict aggregate was changed concurrently since it was loaded
+	ErrConflict = errors.New("aggregate conflict")
+)
+
+// ConflictError is returned by Repository.Save when the aggregate was changed concurrently since it was loaded,
+// the aggregate should be loaded again and the command retried.
+// It matches ErrConflict, and event.ErrVersionConflict of the store, by errors.Is.
+type ConflictError struct {
+	AggregateID string
+	// Expected is the version of the aggregate when it was loaded.
+	Expected int64
+	// Actual is the version of the stream in the store.
+	Actual int64
+	Err    error
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("aggregate %q was changed concurrently, loaded version %d, actual version %d", e.AggregateID, e.Expected, e.Actual)
+}
+
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+func (e *ConflictError) Unwrap() error {
+	return e.Err
+}
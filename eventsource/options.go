@@ -0,0 +1,23 @@
+package eventsource
+
+type option struct {
+	StreamPrefix string
+}
+
+func newOption(opts ...Option) *option {
+	o := &option{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+type Option func(*option)
+
+// StreamPrefix sets the prefix of the stream ids of aggregates, e.g. "order-",
+// so that the aggregates of different types can be saved in one store. Default is no prefix.
+func StreamPrefix(prefix string) Option {
+	return func(o *option) {
+		o.StreamPrefix = prefix
+	}
+}
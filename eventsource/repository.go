@@ -0,0 +1,69 @@
+package eventsource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-leo/design-pattern/event"
+)
+
+// Repository loads and saves the aggregates of type A in an event.Store, an aggregate is saved as a stream of events.
+type Repository[A Aggregate] struct {
+	store        event.Store
+	newAggregate func(id string) A
+	options      *option
+}
+
+// NewRepository returns a Repository of store, newAggregate returns an empty aggregate of id to replay the events,
+// e.g. func(id string) *Order { return &Order{Root: eventsource.NewRoot(id)} }.
+func NewRepository[A Aggregate](store event.Store, newAggregate func(id string) A, opts ...Option) *Repository[A] {
+	return &Repository[A]{store: store, newAggregate: newAggregate, options: newOption(opts...)}
+}
+
+// StreamID returns the id of stream that the aggregate of id is saved as.
+func (r *Repository[A]) StreamID(id string) string {
+	return r.options.StreamPrefix + id
+}
+
+// Load rebuilds the aggregate of id by replaying its stream, it returns ErrAggregateNotFound if the stream is empty.
+func (r *Repository[A]) Load(ctx context.Context, id string) (A, error) {
+	var zero A
+	events, err := r.store.StreamEvents(ctx, r.StreamID(id), 1, 0)
+	if err != nil {
+		return zero, err
+	}
+	if len(events) == 0 {
+		return zero, fmt.Errorf("%w: %s", ErrAggregateNotFound, id)
+	}
+	aggregate := r.newAggregate(id)
+	root := aggregate.root()
+	for _, e := range events {
+		if err := aggregate.Apply(e.Event); err != nil {
+			return zero, fmt.Errorf("replay event %d of aggregate %s: %w", e.Version, id, err)
+		}
+		root.version = e.Version
+	}
+	return aggregate, nil
+}
+
+// Save appends the uncommitted events of aggregate to its stream, if the stream is still of the version that the
+// aggregate was loaded, otherwise it returns a ConflictError and the aggregate is not changed.
+// The events are committed if it succeeds.
+func (r *Repository[A]) Save(ctx context.Context, aggregate A) error {
+	root := aggregate.root()
+	if len(root.uncommitted) == 0 {
+		return nil
+	}
+	stored, err := r.store.Append(ctx, r.StreamID(root.id), root.version, root.uncommitted...)
+	var conflict *event.VersionConflictError
+	if errors.As(err, &conflict) {
+		return &ConflictError{AggregateID: root.id, Expected: conflict.Expected, Actual: conflict.Actual, Err: err}
+	}
+	if err != nil {
+		return err
+	}
+	root.version = stored[len(stored)-1].Version
+	root.uncommitted = nil
+	return nil
+}
@@ -0,0 +1,169 @@
+package eventsource
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-leo/design-pattern/event"
+	"github.com/stretchr/testify/assert"
+)
+
+type AccountOpened struct {
+	Owner string
+}
+
+type MoneyDeposited struct {
+	Amount int
+}
+
+type MoneyWithdrawn struct {
+	Amount int
+}
+
+type Account struct {
+	Root
+	owner   string
+	balance int
+}
+
+func NewAccount(id string, owner string) (*Account, error) {
+	account := &Account{Root: NewRoot(id)}
+	return account, Raise(account, AccountOpened{Owner: owner})
+}
+
+func (a *Account) Deposit(amount int) error {
+	return Raise(a, MoneyDeposited{Amount: amount})
+}
+
+func (a *Account) Withdraw(amount int) error {
+	return Raise(a, MoneyWithdrawn{Amount: amount})
+}
+
+func (a *Account) Apply(e event.Event) error {
+	switch body := e.Body().(type) {
+	case AccountOpened:
+		a.owner = body.Owner
+	case MoneyDeposited:
+		a.balance += body.Amount
+	case MoneyWithdrawn:
+		if body.Amount > a.balance {
+			return errors.New("insufficient balance")
+		}
+		a.balance -= body.Amount
+	default:
+		return errors.New("unknown event")
+	}
+	return nil
+}
+
+func newAccountRepository(store event.Store) *Repository[*Account] {
+	return NewRepository(store, func(id string) *Account { return &Account{Root: NewRoot(id)} }, StreamPrefix("account-"))
+}
+
+func TestRaise(t *testing.T) {
+	account, err := NewAccount("1", "jax")
+	assert.NoError(t, err)
+	assert.NoError(t, account.Deposit(100))
+	assert.Error(t, account.Withdraw(200))
+	assert.ErrorIs(t, Raise(account, nil), event.ErrEventNil)
+
+	// the events failed to apply are not recorded
+	assert.Equal(t, int64(2), account.Version())
+	assert.Equal(t, 100, account.balance)
+	uncommitted := account.Uncommitted()
+	if assert.Len(t, uncommitted, 2) {
+		assert.Equal(t, AccountOpened{Owner: "jax"}, uncommitted[0].Body())
+		assert.Equal(t, MoneyDeposited{Amount: 100}, uncommitted[1].Body())
+		assert.NotEqual(t, uncommitted[0].ID(), uncommitted[1].ID())
+	}
+}
+
+func TestRepository(t *testing.T) {
+	ctx := context.Background()
+	store := event.NewMemoryStore()
+	repo := newAccountRepository(store)
+
+	_, err := repo.Load(ctx, "1")
+	assert.ErrorIs(t, err, ErrAggregateNotFound)
+
+	account, _ := NewAccount("1", "jax")
+	assert.NoError(t, account.Deposit(100))
+	assert.NoError(t, repo.Save(ctx, account))
+	assert.Empty(t, account.Uncommitted())
+	assert.Equal(t, int64(2), account.Version())
+	version, err := store.StreamVersion(ctx, "account-1")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), version)
+
+	// nothing to save
+	assert.NoError(t, repo.Save(ctx, account))
+
+	loaded, err := repo.Load(ctx, "1")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "1", loaded.AggregateID())
+	assert.Equal(t, int64(2), loaded.Version())
+	assert.Equal(t, "jax", loaded.owner)
+	assert.Equal(t, 100, loaded.balance)
+	assert.Empty(t, loaded.Uncommitted())
+
+	assert.NoError(t, loaded.Withdraw(30))
+	assert.NoError(t, repo.Save(ctx, loaded))
+	loaded, err = repo.Load(ctx, "1")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), loaded.Version())
+	assert.Equal(t, 70, loaded.balance)
+}
+
+func TestRepository_Conflict(t *testing.T) {
+	ctx := context.Background()
+	repo := newAccountRepository(event.NewMemoryStore())
+	account, _ := NewAccount("1", "jax")
+	assert.NoError(t, repo.Save(ctx, account))
+
+	first, err := repo.Load(ctx, "1")
+	assert.NoError(t, err)
+	second, err := repo.Load(ctx, "1")
+	assert.NoError(t, err)
+
+	assert.NoError(t, first.Deposit(100))
+	assert.NoError(t, repo.Save(ctx, first))
+
+	assert.NoError(t, second.Deposit(50))
+	err = repo.Save(ctx, second)
+	assert.ErrorIs(t, err, ErrConflict)
+	assert.ErrorIs(t, err, event.ErrVersionConflict)
+	var conflict *ConflictError
+	if assert.ErrorAs(t, err, &conflict) {
+		assert.Equal(t, "1", conflict.AggregateID)
+		assert.Equal(t, int64(1), conflict.Expected)
+		assert.Equal(t, int64(2), conflict.Actual)
+	}
+	// the aggregate is not changed
+	assert.Len(t, second.Uncommitted(), 1)
+	assert.Equal(t, int64(2), second.Version())
+
+	// a new aggregate conflicts with the existing stream
+	duplicate, _ := NewAccount("1", "leo")
+	assert.ErrorIs(t, repo.Save(ctx, duplicate), ErrConflict)
+
+	// retry on the reloaded aggregate
+	second, err = repo.Load(ctx, "1")
+	assert.NoError(t, err)
+	assert.NoError(t, second.Deposit(50))
+	assert.NoError(t, repo.Save(ctx, second))
+	loaded, err := repo.Load(ctx, "1")
+	assert.NoError(t, err)
+	assert.Equal(t, 150, loaded.balance)
+}
+
+func TestRepository_ReplayError(t *testing.T) {
+	ctx := context.Background()
+	store := event.NewMemoryStore()
+	_, err := store.Append(ctx, "account-1", event.NoStream, event.NewEvent(AccountOpened{Owner: "jax"}, "1"), event.NewEvent("unknown", "2"))
+	assert.NoError(t, err)
+	_, err = newAccountRepository(store).Load(ctx, "1")
+	assert.ErrorContains(t, err, "replay event 2 of aggregate 1")
+}
@@ -0,0 +1,74 @@
+// Package instrument defines the Hook that observes the commands, queries and listeners of the cqrs and event buses.
+// It is dependency-free, see the otelhook package for the OpenTelemetry adapter.
+package instrument
+
+import (
+	"context"
+)
+
+// Kind is the kind of Operation.
+type Kind string
+
+const (
+	// Command is the execution of a command by cqrs.Bus.
+	Command Kind = "command"
+	// Query is the execution of a query by cqrs.Bus.
+	Query Kind = "query"
+	// StreamQuery is the execution of a streaming query by cqrs.Bus, it ends when the handler returns.
+	StreamQuery Kind = "stream query"
+	// Listener is the handling of an event by a listener of event.Bus.
+	Listener Kind = "listener"
+)
+
+// Operation describes an observed operation.
+type Operation struct {
+	// Kind is the kind of operation.
+	Kind Kind
+	// Message is the name of the type of command, query or event, e.g. "*main.createUserCmd".
+	Message string
+	// Handler is the name of the type of listener, it is empty for commands and queries.
+	Handler string
+}
+
+// Hook observes the operations of buses.
+type Hook interface {
+	// Start is called when the operation starts, the returned context is passed to the handler,
+	// end is called with the error of operation after the operation ends.
+	// The listeners of event.Bus.AsyncEmit start when they are submitted, so the time waiting in pool is observed.
+	Start(ctx context.Context, op Operation) (_ context.Context, end func(err error))
+
+	// Propagate returns a copy of parent that carries the trace of ctx,
+	// it is used to pass the trace into a context that is not derived from ctx.
+	Propagate(ctx context.Context, parent context.Context) context.Context
+}
+
+// Hooks returns a Hook that calls hooks in order, and ends them in reverse order.
+func Hooks(hooks ...Hook) Hook {
+	if len(hooks) == 1 {
+		return hooks[0]
+	}
+	return multiHook(hooks)
+}
+
+type multiHook []Hook
+
+func (hooks multiHook) Start(ctx context.Context, op Operation) (context.Context, func(err error)) {
+	ends := make([]func(err error), 0, len(hooks))
+	for _, hook := range hooks {
+		var end func(err error)
+		ctx, end = hook.Start(ctx, op)
+		ends = append(ends, end)
+	}
+	return ctx, func(err error) {
+		for i := len(ends) - 1; i >= 0; i-- {
+			ends[i](err)
+		}
+	}
+}
+
+func (hooks multiHook) Propagate(ctx context.Context, parent context.Context) context.Context {
+	for _, hook := range hooks {
+		parent = hook.Propagate(ctx, parent)
+	}
+	return parent
+}
@@ -0,0 +1,47 @@
+package instrument
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type ctxKey struct{}
+
+type recordHook struct {
+	name    string
+	records *[]string
+}
+
+func (h recordHook) Start(ctx context.Context, op Operation) (context.Context, func(err error)) {
+	*h.records = append(*h.records, h.name+" start "+string(op.Kind)+" "+op.Message)
+	ctx = context.WithValue(ctx, ctxKey{}, h.name)
+	return ctx, func(err error) {
+		*h.records = append(*h.records, h.name+" end "+err.Error())
+	}
+}
+
+func (h recordHook) Propagate(ctx context.Context, parent context.Context) context.Context {
+	*h.records = append(*h.records, h.name+" propagate "+ctx.Value(ctxKey{}).(string))
+	return context.WithValue(parent, ctxKey{}, h.name)
+}
+
+func TestHooks(t *testing.T) {
+	var records []string
+	hook := Hooks(recordHook{name: "a", records: &records}, recordHook{name: "b", records: &records})
+	ctx, end := hook.Start(context.Background(), Operation{Kind: Command, Message: "createUserCmd"})
+	assert.Equal(t, "b", ctx.Value(ctxKey{}))
+	parent := hook.Propagate(ctx, context.Background())
+	assert.Equal(t, "b", parent.Value(ctxKey{}))
+	end(errors.New("failed"))
+	assert.Equal(t, []string{
+		"a start command createUserCmd",
+		"b start command createUserCmd",
+		"a propagate b",
+		"b propagate b",
+		"b end failed",
+		"a end failed",
+	}, records)
+}
@@ -0,0 +1,131 @@
+// Package otelhook adapts OpenTelemetry to instrument.Hook.
+//
+// Every operation is traced by a span named by its kind and message, e.g. "command *main.createUserCmd",
+// and recorded by the metrics:
+//   - bus.operations: the counter of the ended operations.
+//   - bus.operation.duration: the histogram of the durations of operations, in seconds.
+//   - bus.operations.in_flight: the number of the started but not ended operations,
+//     it includes the listeners waiting in the pool of event.Bus.
+//
+// The metrics have the attributes bus.kind, bus.message and bus.handler of the operation,
+// the ended ones also have bus.outcome, that is "ok" or "error".
+package otelhook
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-leo/design-pattern/instrument"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/go-leo/design-pattern/instrument/otelhook"
+
+var _ instrument.Hook = (*Hook)(nil)
+
+// Hook is an instrument.Hook that traces and measures the operations by OpenTelemetry.
+type Hook struct {
+	tracer     trace.Tracer
+	operations metric.Int64Counter
+	duration   metric.Float64Histogram
+	inFlight   metric.Int64UpDownCounter
+}
+
+type option struct {
+	TracerProvider trace.TracerProvider
+	MeterProvider  metric.MeterProvider
+}
+
+type Option func(*option)
+
+// TracerProvider sets the trace.TracerProvider, default is the global one.
+func TracerProvider(provider trace.TracerProvider) Option {
+	return func(o *option) {
+		o.TracerProvider = provider
+	}
+}
+
+// MeterProvider sets the metric.MeterProvider, default is the global one.
+func MeterProvider(provider metric.MeterProvider) Option {
+	return func(o *option) {
+		o.MeterProvider = provider
+	}
+}
+
+// New returns a Hook, it returns an error if the instruments can not be created.
+func New(opts ...Option) (*Hook, error) {
+	o := &option{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.TracerProvider == nil {
+		o.TracerProvider = otel.GetTracerProvider()
+	}
+	if o.MeterProvider == nil {
+		o.MeterProvider = otel.GetMeterProvider()
+	}
+	meter := o.MeterProvider.Meter(instrumentationName)
+	operations, err := meter.Int64Counter("bus.operations",
+		metric.WithDescription("The number of the ended operations of buses."))
+	if err != nil {
+		return nil, err
+	}
+	duration, err := meter.Float64Histogram("bus.operation.duration",
+		metric.WithDescription("The duration of the operations of buses."),
+		metric.WithUnit("s"))
+	if err != nil {
+		return nil, err
+	}
+	inFlight, err := meter.Int64UpDownCounter("bus.operations.in_flight",
+		metric.WithDescription("The number of the in-flight operations of buses."))
+	if err != nil {
+		return nil, err
+	}
+	return &Hook{
+		tracer:     o.TracerProvider.Tracer(instrumentationName),
+		operations: operations,
+		duration:   duration,
+		inFlight:   inFlight,
+	}, nil
+}
+
+func (h *Hook) Start(ctx context.Context, op instrument.Operation) (context.Context, func(err error)) {
+	attrs := make([]attribute.KeyValue, 0, 4)
+	attrs = append(attrs, attribute.String("bus.kind", string(op.Kind)), attribute.String("bus.message", op.Message))
+	if op.Handler != "" {
+		attrs = append(attrs, attribute.String("bus.handler", op.Handler))
+	}
+	ctx, span := h.tracer.Start(ctx, string(op.Kind)+" "+op.Message, trace.WithAttributes(attrs...))
+	inFlightAttrs := metric.WithAttributes(attrs...)
+	h.inFlight.Add(ctx, 1, inFlightAttrs)
+	start := time.Now()
+	return ctx, func(err error) {
+		h.inFlight.Add(ctx, -1, inFlightAttrs)
+		outcome := "ok"
+		if err != nil {
+			outcome = "error"
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		endAttrs := metric.WithAttributes(append(attrs, attribute.String("bus.outcome", outcome))...)
+		h.operations.Add(ctx, 1, endAttrs)
+		h.duration.Record(ctx, time.Since(start).Seconds(), endAttrs)
+		span.End()
+	}
+}
+
+// Propagate carries the span context and the baggage of ctx into parent.
+func (h *Hook) Propagate(ctx context.Context, parent context.Context) context.Context {
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		parent = trace.ContextWithSpanContext(parent, spanContext)
+	}
+	if bag := baggage.FromContext(ctx); bag.Len() > 0 {
+		parent = baggage.ContextWithBaggage(parent, bag)
+	}
+	return parent
+}
@@ -0,0 +1,152 @@
+package otelhook
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-leo/design-pattern/cqrs"
+	"github.com/go-leo/design-pattern/event"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type createUserCmd struct {
+	Name string
+}
+
+type userCreated struct {
+	Name string
+}
+
+type welcomeListener struct {
+	spanContext trace.SpanContext
+}
+
+func (l *welcomeListener) Handle(e event.Event) error {
+	l.spanContext = trace.SpanContextFromContext(e.Context())
+	return nil
+}
+
+func TestHook(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	reader := sdkmetric.NewManualReader()
+	meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	hook, err := New(TracerProvider(tracerProvider), MeterProvider(meterProvider))
+	assert.NoError(t, err)
+
+	eventBus := event.NewBus(event.Hook(hook))
+	listener := &welcomeListener{}
+	assert.NoError(t, eventBus.On(event.NewEvent(userCreated{}, nil), listener))
+	bus := cqrs.NewBus(cqrs.Hook(hook), cqrs.EventBus(eventBus))
+	assert.NoError(t, cqrs.RegisterResult[createUserCmd, string](bus, cqrs.CommandResultHandlerFunc[createUserCmd, string](func(ctx context.Context, cmd createUserCmd) (string, []event.Event, error) {
+		if cmd.Name == "" {
+			return "", nil, cqrs.Validation("name is empty")
+		}
+		return cmd.Name, []event.Event{event.NewEvent(userCreated{Name: cmd.Name}, nil)}, nil
+	})))
+
+	ctx := context.Background()
+	_, err = cqrs.ExecResult[createUserCmd, string](ctx, bus, createUserCmd{Name: "jax"})
+	assert.NoError(t, err)
+	_, err = cqrs.ExecResult[createUserCmd, string](ctx, bus, createUserCmd{})
+	assert.ErrorIs(t, err, cqrs.ErrValidation)
+
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 3)
+	listenerSpan, commandSpan, failedSpan := spans[0], spans[1], spans[2]
+	assert.Equal(t, "listener otelhook.userCreated", listenerSpan.Name)
+	assert.Contains(t, listenerSpan.Attributes, attribute.String("bus.handler", "*otelhook.welcomeListener"))
+	assert.Equal(t, "command otelhook.createUserCmd", commandSpan.Name)
+	assert.Equal(t, commandSpan.SpanContext.SpanID(), listenerSpan.Parent.SpanID())
+	assert.Equal(t, commandSpan.SpanContext.TraceID(), listener.spanContext.TraceID())
+	assert.Equal(t, listenerSpan.SpanContext.SpanID(), listener.spanContext.SpanID())
+	assert.Equal(t, codes.Error, failedSpan.Status.Code)
+	assert.Equal(t, codes.Unset, commandSpan.Status.Code)
+
+	var rm metricdata.ResourceMetrics
+	assert.NoError(t, reader.Collect(ctx, &rm))
+	metrics := make(map[string]metricdata.Metrics)
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		metrics[m.Name] = m
+	}
+	counts := make(map[string]int64)
+	for _, dp := range metrics["bus.operations"].Data.(metricdata.Sum[int64]).DataPoints {
+		kind, _ := dp.Attributes.Value("bus.kind")
+		outcome, _ := dp.Attributes.Value("bus.outcome")
+		counts[kind.AsString()+" "+outcome.AsString()] += dp.Value
+	}
+	assert.Equal(t, map[string]int64{"command ok": 1, "command error": 1, "listener ok": 1}, counts)
+	var histogramCount uint64
+	for _, dp := range metrics["bus.operation.duration"].Data.(metricdata.Histogram[float64]).DataPoints {
+		histogramCount += dp.Count
+	}
+	assert.Equal(t, uint64(3), histogramCount)
+	for _, dp := range metrics["bus.operations.in_flight"].Data.(metricdata.Sum[int64]).DataPoints {
+		assert.Equal(t, int64(0), dp.Value)
+	}
+}
+
+func TestHook_AsyncEmit(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	hook, err := New(TracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))))
+	assert.NoError(t, err)
+	failed := errors.New("failed")
+	eventBus := event.NewBus(event.Hook(hook))
+	assert.NoError(t, eventBus.On(event.NewEvent(userCreated{}, nil), failingListener{err: failed}))
+
+	assert.ErrorIs(t, eventBus.AsyncEmit(event.NewEvent(userCreated{Name: "jax"}, nil)).Err(), failed)
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "listener otelhook.userCreated", spans[0].Name)
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+}
+
+func TestHook_ListenerPanic(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	reader := sdkmetric.NewManualReader()
+	hook, err := New(TracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))),
+		MeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))))
+	assert.NoError(t, err)
+	eventBus := event.NewBus(event.Hook(hook))
+	assert.NoError(t, eventBus.On(event.NewEvent(userCreated{}, nil), panickingListener{}))
+
+	assert.PanicsWithValue(t, "boom", func() {
+		_ = eventBus.Emit(event.NewEvent(userCreated{Name: "jax"}, nil))
+	})
+	// the span is ended, and the in-flight operation is done
+	spans := exporter.GetSpans()
+	if assert.Len(t, spans, 1) {
+		assert.Equal(t, codes.Error, spans[0].Status.Code)
+	}
+	var rm metricdata.ResourceMetrics
+	assert.NoError(t, reader.Collect(context.Background(), &rm))
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		if m.Name == "bus.operations.in_flight" {
+			for _, dp := range m.Data.(metricdata.Sum[int64]).DataPoints {
+				assert.Equal(t, int64(0), dp.Value)
+			}
+		}
+	}
+}
+
+type panickingListener struct{}
+
+func (panickingListener) Handle(event.Event) error {
+	panic("boom")
+}
+
+type failingListener struct {
+	err error
+}
+
+func (l failingListener) Handle(event.Event) error {
+	return l.err
+}
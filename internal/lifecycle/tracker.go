@@ -0,0 +1,160 @@
+// Package lifecycle tracks the in-flight operations of a bus, to close it gracefully.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrClosed tracker was closed
+	ErrClosed = errors.New("tracker was closed")
+
+	// ErrDrainTimeout in-flight operations were not drained in time
+	ErrDrainTimeout = errors.New("drain timeout")
+)
+
+// AbandonedError is returned by Close when some in-flight operations were not drained,
+// their contexts were canceled and they were abandoned.
+type AbandonedError struct {
+	// Abandoned is the number of abandoned operations.
+	Abandoned int
+	// Err is the reason why the draining stopped, the error of context or ErrDrainTimeout.
+	Err error
+}
+
+func (e *AbandonedError) Error() string {
+	return fmt.Sprintf("%d in-flight operations abandoned: %v", e.Abandoned, e.Err)
+}
+
+func (e *AbandonedError) Unwrap() error {
+	return e.Err
+}
+
+// Tracker tracks the in-flight operations. After Close, new operations are rejected atomically.
+type Tracker struct {
+	mu      sync.Mutex
+	closed  bool
+	aborted bool
+	ops     map[*operation]struct{}
+	drained chan struct{} // closed when the tracker was closed and no operation is in flight.
+}
+
+// operation is an in-flight operation.
+type operation struct {
+	t      *Tracker
+	cancel context.CancelFunc
+}
+
+// end ends the operation, it can be called more than once.
+func (op *operation) end() {
+	t := op.t
+	t.mu.Lock()
+	if _, ok := t.ops[op]; !ok {
+		t.mu.Unlock()
+		return
+	}
+	delete(t.ops, op)
+	if t.closed && len(t.ops) == 0 {
+		close(t.drained)
+	}
+	t.mu.Unlock()
+	op.cancel()
+}
+
+// trackerKey is the context key of the operations of a Tracker.
+type trackerKey struct {
+	t *Tracker
+}
+
+// Enter starts an operation, it returns a context derived from ctx that is canceled when Close abandons the operation,
+// and a function to end the operation. If the tracker was closed, ErrClosed is returned,
+// unless ctx is the context of an in-flight operation and the tracker is still draining,
+// a nested operation is part of the draining work.
+func (t *Tracker) Enter(ctx context.Context) (context.Context, func(), error) {
+	nested := ctx.Value(trackerKey{t: t}) != nil
+	ctx, cancel := context.WithCancel(context.WithValue(ctx, trackerKey{t: t}, struct{}{}))
+	op := &operation{t: t, cancel: cancel}
+
+	t.mu.Lock()
+	if t.closed && (len(t.ops) == 0 || !nested) {
+		t.mu.Unlock()
+		cancel()
+		return ctx, nil, ErrClosed
+	}
+	t.ops[op] = struct{}{}
+	if t.aborted {
+		cancel()
+	}
+	t.mu.Unlock()
+	return ctx, op.end, nil
+}
+
+// Closed reports whether the tracker was closed.
+func (t *Tracker) Closed() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.closed
+}
+
+// InFlight returns the number of in-flight operations.
+func (t *Tracker) InFlight() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.ops)
+}
+
+// Close rejects new operations, and waits for the in-flight operations to end.
+// If ctx is done or timeout elapses first, the contexts of the in-flight operations are canceled,
+// and an AbandonedError is returned. A timeout <= 0 means no timeout.
+// If the tracker was already closed, ErrClosed is returned.
+func (t *Tracker) Close(ctx context.Context, timeout time.Duration) error {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return ErrClosed
+	}
+	t.closed = true
+	if len(t.ops) == 0 {
+		close(t.drained)
+	}
+	t.mu.Unlock()
+
+	var timeoutC <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		timeoutC = timer.C
+	}
+	var err error
+	select {
+	case <-t.drained:
+		return nil
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-timeoutC:
+		err = ErrDrainTimeout
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	abandoned := len(t.ops)
+	if abandoned == 0 {
+		return nil
+	}
+	t.aborted = true
+	for op := range t.ops {
+		op.cancel()
+	}
+	return &AbandonedError{Abandoned: abandoned, Err: err}
+}
+
+// NewTracker returns a Tracker.
+func NewTracker() *Tracker {
+	return &Tracker{
+		ops:     make(map[*operation]struct{}),
+		drained: make(chan struct{}),
+	}
+}
@@ -0,0 +1,67 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracker_Drain(t *testing.T) {
+	tracker := NewTracker()
+	ctx, done, err := tracker.Enter(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, tracker.InFlight())
+
+	closed := make(chan error)
+	go func() { closed <- tracker.Close(context.Background(), 0) }()
+	time.Sleep(10 * time.Millisecond)
+
+	// new operations are rejected, nested ones join the draining
+	_, _, err = tracker.Enter(context.Background())
+	assert.ErrorIs(t, err, ErrClosed)
+	_, nestedDone, err := tracker.Enter(ctx)
+	assert.NoError(t, err)
+	nestedDone()
+
+	done()
+	done()
+	assert.NoError(t, <-closed)
+	assert.Equal(t, 0, tracker.InFlight())
+	assert.ErrorIs(t, tracker.Close(context.Background(), 0), ErrClosed)
+
+	// the tracker was drained, no operation can join it
+	_, _, err = tracker.Enter(ctx)
+	assert.ErrorIs(t, err, ErrClosed)
+}
+
+func TestTracker_Abandon(t *testing.T) {
+	tracker := NewTracker()
+	ctx1, done1, err := tracker.Enter(context.Background())
+	assert.NoError(t, err)
+	ctx2, done2, err := tracker.Enter(context.Background())
+	assert.NoError(t, err)
+	defer done2()
+	done1()
+	assert.ErrorIs(t, ctx1.Err(), context.Canceled)
+
+	err = tracker.Close(context.Background(), 20*time.Millisecond)
+	var abandonedErr *AbandonedError
+	assert.True(t, errors.As(err, &abandonedErr))
+	assert.Equal(t, 1, abandonedErr.Abandoned)
+	assert.ErrorIs(t, err, ErrDrainTimeout)
+	assert.ErrorIs(t, ctx2.Err(), context.Canceled)
+
+	tracker = NewTracker()
+	ctx3, done3, err := tracker.Enter(context.Background())
+	assert.NoError(t, err)
+	defer done3()
+	closeCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = tracker.Close(closeCtx, 0)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorAs(t, err, &abandonedErr)
+	assert.ErrorIs(t, ctx3.Err(), context.Canceled)
+}
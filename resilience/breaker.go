@@ -0,0 +1,198 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-leo/design-pattern/cqrs"
+)
+
+// State is the state of CircuitBreaker.
+type State int
+
+const (
+	// StateClosed the calls are allowed, and the failures are counted.
+	StateClosed State = iota
+	// StateOpen the calls are rejected with ErrCircuitOpen until the open timeout elapses.
+	StateOpen
+	// StateHalfOpen a limited number of probe calls are allowed to check whether the callee recovered.
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// BreakerOption configures the CircuitBreaker.
+type BreakerOption func(*CircuitBreaker)
+
+// FailureThreshold sets the number of consecutive failures that opens the circuit, default is 5.
+func FailureThreshold(n int) BreakerOption {
+	return func(cb *CircuitBreaker) {
+		cb.failureThreshold = n
+	}
+}
+
+// OpenTimeout sets the duration that the circuit stays open before it becomes half-open, default is 30s.
+func OpenTimeout(d time.Duration) BreakerOption {
+	return func(cb *CircuitBreaker) {
+		cb.openTimeout = d
+	}
+}
+
+// HalfOpenProbes sets the number of probe calls allowed when the circuit is half-open, default is 1.
+// The circuit closes after all probes succeed, and opens again if any probe fails.
+func HalfOpenProbes(n int) BreakerOption {
+	return func(cb *CircuitBreaker) {
+		cb.halfOpenProbes = n
+	}
+}
+
+// IsFailure sets the predicate that reports whether an error is a failure of the callee.
+// By default, the canceled context and the classified errors except cqrs.ErrTransient, e.g. cqrs.ErrNotFound,
+// are caused by the caller, they are not failures.
+func IsFailure(isFailure func(err error) bool) BreakerOption {
+	return func(cb *CircuitBreaker) {
+		cb.isFailure = isFailure
+	}
+}
+
+// CircuitBreaker is a Policy that stops calling a failing callee for a while.
+// It opens after consecutive failures, rejects the calls while open,
+// and allows probe calls after the open timeout to decide whether to close again.
+type CircuitBreaker struct {
+	failureThreshold int
+	openTimeout      time.Duration
+	halfOpenProbes   int
+	isFailure        func(err error) bool
+
+	mu         sync.Mutex
+	state      State
+	generation uint64 // increased on every state change, results of previous generations are ignored.
+	failures   int
+	probes     int // probes in flight when half-open
+	successes  int // succeeded probes when half-open
+	openedAt   time.Time
+}
+
+// NewCircuitBreaker returns a CircuitBreaker in the closed state.
+func NewCircuitBreaker(opts ...BreakerOption) *CircuitBreaker {
+	cb := &CircuitBreaker{
+		failureThreshold: 5,
+		openTimeout:      30 * time.Second,
+		halfOpenProbes:   1,
+		isFailure:        isFailure,
+	}
+	for _, opt := range opts {
+		opt(cb)
+	}
+	cb.failureThreshold = max(cb.failureThreshold, 1)
+	cb.halfOpenProbes = max(cb.halfOpenProbes, 1)
+	return cb
+}
+
+// State returns the current state.
+func (cb *CircuitBreaker) State() State {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.refresh(time.Now())
+	return cb.state
+}
+
+func (cb *CircuitBreaker) Execute(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	generation, err := cb.allow()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			cb.record(generation, true)
+			panic(p)
+		}
+		cb.record(generation, err != nil && cb.isFailure(err))
+	}()
+	return fn(ctx)
+}
+
+func (cb *CircuitBreaker) allow() (uint64, error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.refresh(time.Now())
+	switch cb.state {
+	case StateOpen:
+		return 0, ErrCircuitOpen
+	case StateHalfOpen:
+		if cb.probes+cb.successes >= cb.halfOpenProbes {
+			return 0, ErrCircuitOpen
+		}
+		cb.probes++
+	}
+	return cb.generation, nil
+}
+
+func (cb *CircuitBreaker) record(generation uint64, failed bool) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if generation != cb.generation {
+		return
+	}
+	switch cb.state {
+	case StateClosed:
+		if !failed {
+			cb.failures = 0
+			return
+		}
+		cb.failures++
+		if cb.failures >= cb.failureThreshold {
+			cb.setState(StateOpen, time.Now())
+		}
+	case StateHalfOpen:
+		cb.probes--
+		if failed {
+			cb.setState(StateOpen, time.Now())
+			return
+		}
+		cb.successes++
+		if cb.successes >= cb.halfOpenProbes {
+			cb.setState(StateClosed, time.Now())
+		}
+	}
+}
+
+// refresh turns the open circuit to half-open after the open timeout.
+func (cb *CircuitBreaker) refresh(now time.Time) {
+	if cb.state == StateOpen && now.Sub(cb.openedAt) >= cb.openTimeout {
+		cb.setState(StateHalfOpen, now)
+	}
+}
+
+func (cb *CircuitBreaker) setState(state State, now time.Time) {
+	cb.state = state
+	cb.generation++
+	cb.failures = 0
+	cb.probes = 0
+	cb.successes = 0
+	if state == StateOpen {
+		cb.openedAt = now
+	}
+}
+
+// isFailure is the default failure predicate of CircuitBreaker.
+func isFailure(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	code := cqrs.CodeOf(err)
+	return code == cqrs.CodeUnknown || code == cqrs.CodeTransient
+}
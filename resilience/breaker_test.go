@@ -0,0 +1,56 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-leo/design-pattern/cqrs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	cb := NewCircuitBreaker(FailureThreshold(2), OpenTimeout(20*time.Millisecond), HalfOpenProbes(2))
+	errDown := errors.New("down")
+	fail := func(ctx context.Context) error { return errDown }
+	succeed := func(ctx context.Context) error { return nil }
+
+	// errors caused by the caller are not failures
+	assert.ErrorIs(t, cb.Execute(context.Background(), func(ctx context.Context) error { return cqrs.NotFound("user") }), cqrs.ErrNotFound)
+	assert.ErrorIs(t, cb.Execute(context.Background(), fail), errDown)
+	assert.NoError(t, cb.Execute(context.Background(), succeed))
+	assert.ErrorIs(t, cb.Execute(context.Background(), fail), errDown)
+	assert.Equal(t, StateClosed, cb.State())
+	assert.ErrorIs(t, cb.Execute(context.Background(), fail), errDown)
+	assert.Equal(t, StateOpen, cb.State())
+
+	assert.ErrorIs(t, cb.Execute(context.Background(), succeed), ErrCircuitOpen)
+
+	// a failed probe opens the circuit again
+	time.Sleep(25 * time.Millisecond)
+	assert.Equal(t, StateHalfOpen, cb.State())
+	assert.ErrorIs(t, cb.Execute(context.Background(), fail), errDown)
+	assert.Equal(t, StateOpen, cb.State())
+
+	// probes are limited, and the circuit closes after all probes succeed
+	time.Sleep(25 * time.Millisecond)
+	release := make(chan struct{})
+	probed := make(chan error)
+	for i := 0; i < 2; i++ {
+		go func() {
+			probed <- cb.Execute(context.Background(), func(ctx context.Context) error {
+				<-release
+				return nil
+			})
+		}()
+	}
+	assert.Eventually(t, func() bool {
+		return errors.Is(cb.Execute(context.Background(), succeed), ErrCircuitOpen)
+	}, time.Second, time.Millisecond)
+	close(release)
+	assert.NoError(t, <-probed)
+	assert.NoError(t, <-probed)
+	assert.Equal(t, StateClosed, cb.State())
+	assert.NoError(t, cb.Execute(context.Background(), succeed))
+}
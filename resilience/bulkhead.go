@@ -0,0 +1,47 @@
+package resilience
+
+import (
+	"context"
+	"time"
+)
+
+type bulkhead struct {
+	sem     chan struct{}
+	maxWait time.Duration
+}
+
+// Bulkhead returns a Policy that limits the concurrent calls to limit.
+// A call waits at most maxWait for a free slot, then it fails with ErrBulkheadFull.
+// If maxWait is 0, the call fails immediately when the bulkhead is full.
+func Bulkhead(limit int, maxWait time.Duration) Policy {
+	return &bulkhead{sem: make(chan struct{}, max(limit, 1)), maxWait: maxWait}
+}
+
+func (b *bulkhead) Execute(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := b.acquire(ctx); err != nil {
+		return err
+	}
+	defer func() { <-b.sem }()
+	return fn(ctx)
+}
+
+func (b *bulkhead) acquire(ctx context.Context) error {
+	select {
+	case b.sem <- struct{}{}:
+		return nil
+	default:
+	}
+	if b.maxWait <= 0 {
+		return ErrBulkheadFull
+	}
+	timer := time.NewTimer(b.maxWait)
+	defer timer.Stop()
+	select {
+	case b.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return ErrBulkheadFull
+	}
+}
@@ -0,0 +1,34 @@
+package resilience
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBulkhead(t *testing.T) {
+	policy := Bulkhead(1, 0)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- policy.Execute(context.Background(), func(ctx context.Context) error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+	assert.ErrorIs(t, policy.Execute(context.Background(), func(ctx context.Context) error { return nil }), ErrBulkheadFull)
+
+	waiting := Bulkhead(1, time.Second).(*bulkhead)
+	waiting.sem = policy.(*bulkhead).sem
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+	}()
+	assert.NoError(t, waiting.Execute(context.Background(), func(ctx context.Context) error { return nil }))
+	assert.NoError(t, <-done)
+}
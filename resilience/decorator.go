@@ -0,0 +1,68 @@
+package resilience
+
+import (
+	"context"
+
+	"github.com/go-leo/design-pattern/cqrs"
+	"github.com/go-leo/design-pattern/decorator"
+	"github.com/go-leo/design-pattern/endpoint"
+	"github.com/go-leo/design-pattern/event"
+)
+
+// Command returns a Decorator that executes the CommandHandler with the policy.
+func Command[Args any](policy Policy) decorator.Decorator[cqrs.CommandHandler[Args]] {
+	return decorator.Func[cqrs.CommandHandler[Args]](func(handler cqrs.CommandHandler[Args]) cqrs.CommandHandler[Args] {
+		return cqrs.CommandHandlerFunc[Args](func(ctx context.Context, args Args) error {
+			return policy.Execute(ctx, func(ctx context.Context) error {
+				return handler.Handle(ctx, args)
+			})
+		})
+	})
+}
+
+// CommandResult returns a Decorator that executes the CommandResultHandler with the policy,
+// the result and events of the last attempt are returned.
+func CommandResult[Args any, Result any](policy Policy) decorator.Decorator[cqrs.CommandResultHandler[Args, Result]] {
+	return decorator.Func[cqrs.CommandResultHandler[Args, Result]](func(handler cqrs.CommandResultHandler[Args, Result]) cqrs.CommandResultHandler[Args, Result] {
+		return cqrs.CommandResultHandlerFunc[Args, Result](func(ctx context.Context, args Args) (Result, []event.Event, error) {
+			var res Result
+			var events []event.Event
+			err := policy.Execute(ctx, func(ctx context.Context) error {
+				var err error
+				res, events, err = handler.Handle(ctx, args)
+				return err
+			})
+			return res, events, err
+		})
+	})
+}
+
+// Query returns a Decorator that executes the QueryHandler with the policy.
+func Query[Args any, Result any](policy Policy) decorator.Decorator[cqrs.QueryHandler[Args, Result]] {
+	return decorator.Func[cqrs.QueryHandler[Args, Result]](func(handler cqrs.QueryHandler[Args, Result]) cqrs.QueryHandler[Args, Result] {
+		return cqrs.QueryHandlerFunc[Args, Result](func(ctx context.Context, args Args) (Result, error) {
+			var res Result
+			err := policy.Execute(ctx, func(ctx context.Context) error {
+				var err error
+				res, err = handler.Handle(ctx, args)
+				return err
+			})
+			return res, err
+		})
+	})
+}
+
+// Endpoint returns a Decorator that invokes the Endpoint with the policy.
+func Endpoint[Req any, Resp any](policy Policy) endpoint.Decorator[Req, Resp] {
+	return decorator.Func[endpoint.Endpoint[Req, Resp]](func(ep endpoint.Endpoint[Req, Resp]) endpoint.Endpoint[Req, Resp] {
+		return endpoint.Func[Req, Resp](func(ctx context.Context, req Req) (Resp, error) {
+			var resp Resp
+			err := policy.Execute(ctx, func(ctx context.Context) error {
+				var err error
+				resp, err = ep.Invoke(ctx, req)
+				return err
+			})
+			return resp, err
+		})
+	})
+}
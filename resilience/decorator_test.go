@@ -0,0 +1,116 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-leo/design-pattern/cqrs"
+	"github.com/go-leo/design-pattern/decorator"
+	"github.com/go-leo/design-pattern/endpoint"
+	"github.com/go-leo/design-pattern/event"
+	"github.com/stretchr/testify/assert"
+)
+
+type chargeCmd struct {
+	Amount int
+}
+
+type balanceQuery struct{}
+
+// flaky fails with a transient error until it was called n times.
+type flaky struct {
+	n     int
+	calls int
+}
+
+func (f *flaky) call() error {
+	f.calls++
+	if f.calls < f.n {
+		return cqrs.Transient(errors.New("unavailable"))
+	}
+	return nil
+}
+
+func TestDecorators(t *testing.T) {
+	policy := Chain(Retry(Backoff(time.Millisecond, time.Millisecond)), Timeout(time.Second))
+	ctx := context.Background()
+
+	cmd := &flaky{n: 2}
+	commandHandler := decorator.Chain[cqrs.CommandHandler[chargeCmd]](
+		cqrs.CommandHandlerFunc[chargeCmd](func(ctx context.Context, args chargeCmd) error { return cmd.call() }),
+		Command[chargeCmd](policy),
+	)
+	assert.NoError(t, commandHandler.Handle(ctx, chargeCmd{Amount: 1}))
+	assert.Equal(t, 2, cmd.calls)
+
+	result := &flaky{n: 3}
+	resultHandler := CommandResult[chargeCmd, int](policy).Decorate(
+		cqrs.CommandResultHandlerFunc[chargeCmd, int](func(ctx context.Context, args chargeCmd) (int, []event.Event, error) {
+			if err := result.call(); err != nil {
+				return 0, nil, err
+			}
+			return args.Amount, []event.Event{event.NewEvent(args, nil)}, nil
+		}),
+	)
+	res, events, err := resultHandler.Handle(ctx, chargeCmd{Amount: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, res)
+	assert.Len(t, events, 1)
+	assert.Equal(t, 3, result.calls)
+
+	query := &flaky{n: 2}
+	queryHandler := Query[balanceQuery, int](policy).Decorate(
+		cqrs.QueryHandlerFunc[balanceQuery, int](func(ctx context.Context, args balanceQuery) (int, error) {
+			if err := query.call(); err != nil {
+				return 0, err
+			}
+			return 10, nil
+		}),
+	)
+	balance, err := queryHandler.Handle(ctx, balanceQuery{})
+	assert.NoError(t, err)
+	assert.Equal(t, 10, balance)
+
+	invoke := &flaky{n: 4}
+	ep := endpoint.Chain[string, string](
+		endpoint.Func[string, string](func(ctx context.Context, req string) (string, error) {
+			if err := invoke.call(); err != nil {
+				return "", err
+			}
+			return "hello " + req, nil
+		}),
+		Endpoint[string, string](policy),
+	)
+	_, err = ep.Invoke(ctx, "leo")
+	assert.ErrorIs(t, err, cqrs.ErrTransient)
+	assert.Equal(t, 3, invoke.calls)
+	resp, err := ep.Invoke(ctx, "leo")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello leo", resp)
+}
+
+func TestTimeout(t *testing.T) {
+	var attempts int
+	policy := Chain(Retry(MaxAttempts(2), Backoff(time.Millisecond, time.Millisecond)), Timeout(10*time.Millisecond))
+	ep := Endpoint[string, string](policy).Decorate(endpoint.Func[string, string](func(ctx context.Context, req string) (string, error) {
+		attempts++
+		<-ctx.Done()
+		return "", ctx.Err()
+	}))
+	_, err := ep.Invoke(context.Background(), "leo")
+	assert.ErrorIs(t, err, ErrTimeout)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 2, attempts)
+
+	// the deadline of caller is not a timeout of the policy
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	err = Timeout(time.Second).Execute(ctx, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	assert.NotErrorIs(t, err, ErrTimeout)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,60 @@
+// Package resilience provides policies that make calls resilient: retry, timeout, bulkhead and circuit breaker.
+// A Policy can be applied to cqrs.CommandHandler, cqrs.CommandResultHandler, cqrs.QueryHandler
+// and endpoint.Endpoint by the decorators of this package.
+package resilience
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-leo/design-pattern/cqrs"
+)
+
+var (
+	// ErrTimeout the call did not finish before the deadline of Timeout
+	ErrTimeout = errors.New("resilience: timeout")
+
+	// ErrBulkheadFull the concurrent calls reached the limit of Bulkhead
+	ErrBulkheadFull = errors.New("resilience: bulkhead is full")
+
+	// ErrCircuitOpen the CircuitBreaker is open, or it is half-open and all probes are in flight
+	ErrCircuitOpen = errors.New("resilience: circuit breaker is open")
+)
+
+// Policy executes a call with a resilience strategy.
+type Policy interface {
+	// Execute calls fn, the ctx passed to fn is derived from ctx.
+	Execute(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// The PolicyFunc type is an adapter to allow the use of ordinary functions as Policy.
+type PolicyFunc func(ctx context.Context, fn func(ctx context.Context) error) error
+
+// Execute calls f(ctx, fn).
+func (f PolicyFunc) Execute(ctx context.Context, fn func(ctx context.Context) error) error {
+	return f(ctx, fn)
+}
+
+// Chain returns a Policy that applies the policies in order, the first one is the outermost.
+// For example, Chain(Retry(), Timeout(d)) bounds each attempt with the timeout.
+func Chain(policies ...Policy) Policy {
+	return PolicyFunc(func(ctx context.Context, fn func(ctx context.Context) error) error {
+		return chain(policies, fn)(ctx)
+	})
+}
+
+func chain(policies []Policy, fn func(ctx context.Context) error) func(ctx context.Context) error {
+	for i := len(policies) - 1; i >= 0; i-- {
+		policy, next := policies[i], fn
+		fn = func(ctx context.Context) error {
+			return policy.Execute(ctx, next)
+		}
+	}
+	return fn
+}
+
+// IsTransient reports whether err is temporary, it is the default retryable predicate of Retry.
+// The transient errors are cqrs.ErrTransient, ErrTimeout and ErrBulkheadFull.
+func IsTransient(err error) bool {
+	return errors.Is(err, cqrs.ErrTransient) || errors.Is(err, ErrTimeout) || errors.Is(err, ErrBulkheadFull)
+}
@@ -0,0 +1,112 @@
+package resilience
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+type retry struct {
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	jitter      float64
+	retryable   func(err error) bool
+}
+
+// RetryOption configures the Retry policy.
+type RetryOption func(*retry)
+
+// MaxAttempts sets the max number of attempts, including the first one, default is 3.
+func MaxAttempts(n int) RetryOption {
+	return func(r *retry) {
+		r.maxAttempts = n
+	}
+}
+
+// Backoff sets the delay before the first retry and the max delay, default is 100ms and 5s.
+// The delay doubles after each retry.
+func Backoff(base, max time.Duration) RetryOption {
+	return func(r *retry) {
+		r.baseDelay = base
+		r.maxDelay = max
+	}
+}
+
+// Jitter sets the fraction of the delay that is randomized, in [0, 1], default is 0.5.
+// For example, with jitter 0.5, a delay of 100ms becomes a random delay in [50ms, 100ms].
+func Jitter(fraction float64) RetryOption {
+	return func(r *retry) {
+		r.jitter = fraction
+	}
+}
+
+// Retryable sets the predicate that reports whether an error is retried, default is IsTransient.
+func Retryable(retryable func(err error) bool) RetryOption {
+	return func(r *retry) {
+		r.retryable = retryable
+	}
+}
+
+// Retry returns a Policy that retries the failed calls with jittered exponential backoff.
+// The call is not retried if the error is not retryable or ctx is done, the last error is returned.
+func Retry(opts ...RetryOption) Policy {
+	r := &retry{
+		maxAttempts: 3,
+		baseDelay:   100 * time.Millisecond,
+		maxDelay:    5 * time.Second,
+		jitter:      0.5,
+		retryable:   IsTransient,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.maxAttempts < 1 {
+		r.maxAttempts = 1
+	}
+	r.jitter = min(max(r.jitter, 0), 1)
+	return r
+}
+
+func (r *retry) Execute(ctx context.Context, fn func(ctx context.Context) error) error {
+	var err error
+	for attempt := 0; attempt < r.maxAttempts; attempt++ {
+		if attempt > 0 {
+			if err := sleep(ctx, r.delay(attempt)); err != nil {
+				return err
+			}
+		}
+		if err = fn(ctx); err == nil || !r.retryable(err) || ctx.Err() != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// delay returns the jittered delay before the retry.
+func (r *retry) delay(retry int) time.Duration {
+	delay := r.baseDelay
+	for i := 1; i < retry && delay < r.maxDelay; i++ {
+		delay <<= 1
+	}
+	delay = min(delay, r.maxDelay)
+	if spread := int64(float64(delay) * r.jitter); spread > 0 {
+		delay -= time.Duration(rand.Int63n(spread + 1))
+	}
+	return delay
+}
+
+// sleep waits for d, it returns the error of ctx if ctx is done first.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,71 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-leo/design-pattern/cqrs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetry(t *testing.T) {
+	policy := Retry(MaxAttempts(3), Backoff(time.Millisecond, 4*time.Millisecond))
+
+	var calls int
+	err := policy.Execute(context.Background(), func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return cqrs.Transient(errors.New("unavailable"))
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+
+	// exhausted
+	calls = 0
+	err = policy.Execute(context.Background(), func(ctx context.Context) error {
+		calls++
+		return cqrs.Transient(errors.New("unavailable"))
+	})
+	assert.ErrorIs(t, err, cqrs.ErrTransient)
+	assert.Equal(t, 3, calls)
+
+	// not retryable
+	calls = 0
+	err = policy.Execute(context.Background(), func(ctx context.Context) error {
+		calls++
+		return cqrs.NotFound("user")
+	})
+	assert.ErrorIs(t, err, cqrs.ErrNotFound)
+	assert.Equal(t, 1, calls)
+
+	// canceled while waiting
+	ctx, cancel := context.WithCancel(context.Background())
+	calls = 0
+	err = Retry(Backoff(time.Hour, time.Hour)).Execute(ctx, func(ctx context.Context) error {
+		calls++
+		time.AfterFunc(time.Millisecond, cancel)
+		return ErrBulkheadFull
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, calls)
+}
+
+func TestRetry_Delay(t *testing.T) {
+	r := Retry(Backoff(10*time.Millisecond, 50*time.Millisecond), Jitter(0)).(*retry)
+	assert.Equal(t, 10*time.Millisecond, r.delay(1))
+	assert.Equal(t, 20*time.Millisecond, r.delay(2))
+	assert.Equal(t, 40*time.Millisecond, r.delay(3))
+	assert.Equal(t, 50*time.Millisecond, r.delay(4))
+	assert.Equal(t, 50*time.Millisecond, r.delay(100))
+
+	r = Retry(Backoff(10*time.Millisecond, 50*time.Millisecond), Jitter(0.5)).(*retry)
+	for i := 0; i < 100; i++ {
+		delay := r.delay(2)
+		assert.GreaterOrEqual(t, delay, 10*time.Millisecond)
+		assert.LessOrEqual(t, delay, 20*time.Millisecond)
+	}
+}
@@ -0,0 +1,23 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Timeout returns a Policy that bounds the call with a deadline of d.
+// The called function must respect the ctx, if it fails after the deadline exceeded,
+// the error is wrapped by ErrTimeout, unless the parent ctx is done too.
+func Timeout(d time.Duration) Policy {
+	return PolicyFunc(func(ctx context.Context, fn func(ctx context.Context) error) error {
+		timeoutCtx, cancel := context.WithTimeout(ctx, d)
+		defer cancel()
+		err := fn(timeoutCtx)
+		if err != nil && ctx.Err() == nil && errors.Is(timeoutCtx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("%w: %w", ErrTimeout, err)
+		}
+		return err
+	})
+}
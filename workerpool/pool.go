@@ -0,0 +1,317 @@
+// Package workerpool provides Pool, a bounded gopher.Gopher with a fixed number of workers and a bounded queue,
+// so that a traffic spike can't start unbounded goroutines.
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync"
+
+	"github.com/go-leo/gox/syncx/gopher"
+)
+
+var (
+	// ErrClosed pool is closed
+	ErrClosed = errors.New("workerpool: pool is closed")
+
+	// ErrRejected matches every RejectedError by errors.Is
+	ErrRejected = errors.New("workerpool: function was rejected")
+
+	// ErrDropped function was dropped from the queue by the DropOldest policy
+	ErrDropped = errors.New("workerpool: function was dropped")
+)
+
+// RejectedError is returned by the Reject policy when the queue is full.
+type RejectedError struct {
+	// Workers is the number of workers.
+	Workers int
+	// QueueSize is the size of queue.
+	QueueSize int
+}
+
+func (e *RejectedError) Error() string {
+	return fmt.Sprintf("workerpool: queue is full, %d workers, queue size %d", e.Workers, e.QueueSize)
+}
+
+// Is reports whether target is ErrRejected.
+func (e *RejectedError) Is(target error) bool {
+	return target == ErrRejected
+}
+
+// Droppable is a gopher.Gopher that may drop the functions it accepted.
+type Droppable interface {
+	gopher.Gopher
+
+	// GoDroppable runs f asynchronously like Go, if f is dropped before it runs, drop is called with the reason instead.
+	GoDroppable(f func(), drop func(err error)) error
+}
+
+// Submit runs f in pool, if pool is Droppable and f is dropped, drop is called instead.
+// The callers that wait for f, e.g. a Future, must use Submit, otherwise they wait forever for a dropped f.
+func Submit(pool gopher.Gopher, f func(), drop func(err error)) error {
+	if droppable, ok := pool.(Droppable); ok {
+		return droppable.GoDroppable(f, drop)
+	}
+	return pool.Go(f)
+}
+
+// Policy is the policy when a function is submitted to a full Pool.
+type Policy int
+
+const (
+	// Block blocks the caller until the queue has room.
+	// A function running in the pool must not submit to the same pool, or the pool may deadlock.
+	Block Policy = iota
+	// Reject rejects the function with a RejectedError.
+	Reject
+	// DropOldest drops the oldest function in the queue to make room, the dropped function fails with ErrDropped.
+	// If the queue size is zero, the function is rejected with a RejectedError.
+	DropOldest
+	// CallerRuns runs the function in the caller goroutine.
+	CallerRuns
+)
+
+func (p Policy) String() string {
+	switch p {
+	case Block:
+		return "block"
+	case Reject:
+		return "reject"
+	case DropOldest:
+		return "drop oldest"
+	case CallerRuns:
+		return "caller runs"
+	default:
+		return "unknown"
+	}
+}
+
+// Stats is a snapshot of the metrics of Pool.
+type Stats struct {
+	// Workers is the number of workers.
+	Workers int
+	// Running is the number of functions being run by workers.
+	Running int
+	// Queued is the number of functions waiting in the queue, that is the queue depth.
+	Queued int
+	// Completed is the number of functions that were run by workers.
+	Completed int64
+	// Rejected is the number of functions rejected by the Reject or DropOldest policy.
+	Rejected int64
+	// Dropped is the number of functions dropped by the DropOldest policy.
+	Dropped int64
+	// CallerRuns is the number of functions run by the callers by the CallerRuns policy.
+	CallerRuns int64
+}
+
+type option struct {
+	Policy  Policy
+	Recover func(p any)
+}
+
+type Option func(*option)
+
+// Overflow sets the Policy when the queue is full, default is Block.
+func Overflow(policy Policy) Option {
+	return func(o *option) {
+		o.Policy = policy
+	}
+}
+
+// Recover sets the function that is called with the value of panic when a function panics,
+// default logs the panic and the stack trace. The worker keeps running after a panic.
+func Recover(recover func(p any)) Option {
+	return func(o *option) {
+		o.Recover = recover
+	}
+}
+
+var _ Droppable = (*Pool)(nil)
+
+// Pool runs the functions by a fixed number of workers, the functions wait in a bounded queue if all workers are busy.
+type Pool struct {
+	mu        sync.Mutex
+	notEmpty  *sync.Cond
+	notFull   *sync.Cond
+	queue     []task
+	queueSize int
+	closed    bool
+	workers   int
+	wg        sync.WaitGroup
+	options   *option
+	stats     Stats
+	closeOnce sync.Once
+}
+
+type task struct {
+	f    func()
+	drop func(err error)
+}
+
+// New returns a Pool with workers workers and a queue of queueSize functions, and starts the workers.
+// With a zero queueSize, a function is accepted only if a worker is not running a function.
+func New(workers int, queueSize int, opts ...Option) *Pool {
+	o := &option{
+		Recover: func(p any) {
+			log.Printf("workerpool: panic: %v\n%s", p, debug.Stack())
+		},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	p := &Pool{
+		queueSize: max(queueSize, 0),
+		workers:   max(workers, 1),
+		options:   o,
+	}
+	p.notEmpty = sync.NewCond(&p.mu)
+	p.notFull = sync.NewCond(&p.mu)
+	p.wg.Add(p.workers)
+	for i := 0; i < p.workers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+// Go runs f asynchronously by a worker, see Policy for the behavior when the queue is full.
+func (p *Pool) Go(f func()) error {
+	return p.submit(task{f: f})
+}
+
+func (p *Pool) GoDroppable(f func(), drop func(err error)) error {
+	return p.submit(task{f: f, drop: drop})
+}
+
+// Stats returns the current metrics.
+func (p *Pool) Stats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := p.stats
+	stats.Workers = p.workers
+	stats.Queued = len(p.queue)
+	return stats
+}
+
+// Close stops accepting functions, and waits for the queued and running functions to finish,
+// it returns the error of ctx if ctx is done first.
+func (p *Pool) Close(ctx context.Context) error {
+	p.closeOnce.Do(func() {
+		p.mu.Lock()
+		p.closed = true
+		p.notEmpty.Broadcast()
+		p.notFull.Broadcast()
+		p.mu.Unlock()
+	})
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *Pool) submit(t task) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrClosed
+	}
+	if p.full() {
+		switch p.options.Policy {
+		case Reject:
+			p.stats.Rejected++
+			p.mu.Unlock()
+			return &RejectedError{Workers: p.workers, QueueSize: p.queueSize}
+		case DropOldest:
+			if len(p.queue) == 0 {
+				p.stats.Rejected++
+				p.mu.Unlock()
+				return &RejectedError{Workers: p.workers, QueueSize: p.queueSize}
+			}
+			oldest := p.pop()
+			p.stats.Dropped++
+			p.push(t)
+			p.mu.Unlock()
+			if oldest.drop != nil {
+				oldest.drop(ErrDropped)
+			}
+			return nil
+		case CallerRuns:
+			p.stats.CallerRuns++
+			p.mu.Unlock()
+			p.run(t.f)
+			return nil
+		default:
+			for p.full() && !p.closed {
+				p.notFull.Wait()
+			}
+			if p.closed {
+				p.mu.Unlock()
+				return ErrClosed
+			}
+		}
+	}
+	p.push(t)
+	p.mu.Unlock()
+	return nil
+}
+
+// full reports whether a function can't be accepted, the workers that are not running take functions without queueing.
+func (p *Pool) full() bool {
+	return len(p.queue) >= p.queueSize+p.workers-p.stats.Running
+}
+
+// push appends t to the queue and wakes a worker, the caller must hold mu.
+func (p *Pool) push(t task) {
+	p.queue = append(p.queue, t)
+	p.notEmpty.Signal()
+}
+
+// pop removes the oldest function from the queue, the caller must hold mu.
+func (p *Pool) pop() task {
+	t := p.queue[0]
+	p.queue[0] = task{}
+	p.queue = p.queue[1:]
+	return t
+}
+
+func (p *Pool) work() {
+	defer p.wg.Done()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for {
+		for len(p.queue) == 0 && !p.closed {
+			p.notEmpty.Wait()
+		}
+		if len(p.queue) == 0 {
+			// closed and drained
+			return
+		}
+		t := p.pop()
+		p.stats.Running++
+		p.mu.Unlock()
+		p.run(t.f)
+		p.mu.Lock()
+		p.stats.Running--
+		p.stats.Completed++
+		// a finished worker makes room for a blocked caller.
+		p.notFull.Signal()
+	}
+}
+
+func (p *Pool) run(f func()) {
+	defer func() {
+		if v := recover(); v != nil {
+			p.options.Recover(v)
+		}
+	}()
+	f()
+}
@@ -0,0 +1,171 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// occupy submits a function that blocks the only worker of pool until release is closed.
+func occupy(t *testing.T, pool *Pool) chan struct{} {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	assert.NoError(t, pool.Go(func() {
+		close(started)
+		<-release
+	}))
+	<-started
+	return release
+}
+
+func TestPool_Go(t *testing.T) {
+	pool := New(4, 16)
+	var n atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		assert.NoError(t, pool.Go(func() {
+			defer wg.Done()
+			n.Add(1)
+		}))
+	}
+	wg.Wait()
+	assert.Equal(t, int64(100), n.Load())
+	assert.NoError(t, pool.Close(context.Background()))
+	stats := pool.Stats()
+	assert.Equal(t, 4, stats.Workers)
+	assert.Equal(t, int64(100), stats.Completed)
+	assert.Equal(t, 0, stats.Queued)
+	assert.Equal(t, 0, stats.Running)
+
+	assert.ErrorIs(t, pool.Go(func() {}), ErrClosed)
+}
+
+func TestPool_Block(t *testing.T) {
+	pool := New(1, 1)
+	defer pool.Close(context.Background())
+	release := occupy(t, pool)
+	assert.NoError(t, pool.Go(func() {}))
+	assert.Equal(t, 1, pool.Stats().Queued)
+	assert.Equal(t, 1, pool.Stats().Running)
+
+	submitted := make(chan error)
+	go func() {
+		submitted <- pool.Go(func() {})
+	}()
+	select {
+	case <-submitted:
+		t.Fatal("Go is not blocked by the full queue")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	select {
+	case err := <-submitted:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Go is still blocked")
+	}
+}
+
+func TestPool_Reject(t *testing.T) {
+	pool := New(1, 1, Overflow(Reject))
+	defer pool.Close(context.Background())
+	release := occupy(t, pool)
+	defer close(release)
+	assert.NoError(t, pool.Go(func() {}))
+
+	err := pool.Go(func() {})
+	assert.ErrorIs(t, err, ErrRejected)
+	var rejected *RejectedError
+	assert.True(t, errors.As(err, &rejected))
+	assert.Equal(t, &RejectedError{Workers: 1, QueueSize: 1}, rejected)
+	assert.Equal(t, int64(1), pool.Stats().Rejected)
+}
+
+func TestPool_DropOldest(t *testing.T) {
+	pool := New(1, 2, Overflow(DropOldest))
+	release := occupy(t, pool)
+	var dropped []int
+	var ran []int
+	var mu sync.Mutex
+	for i := 1; i <= 4; i++ {
+		i := i
+		assert.NoError(t, pool.GoDroppable(func() {
+			mu.Lock()
+			defer mu.Unlock()
+			ran = append(ran, i)
+		}, func(err error) {
+			assert.ErrorIs(t, err, ErrDropped)
+			dropped = append(dropped, i)
+		}))
+	}
+	assert.Equal(t, []int{1, 2}, dropped)
+	assert.Equal(t, int64(2), pool.Stats().Dropped)
+	close(release)
+	assert.NoError(t, pool.Close(context.Background()))
+	assert.Equal(t, []int{3, 4}, ran)
+}
+
+func TestPool_DropOldest_NoQueue(t *testing.T) {
+	pool := New(1, 0, Overflow(DropOldest))
+	defer pool.Close(context.Background())
+	release := occupy(t, pool)
+	defer close(release)
+	assert.ErrorIs(t, pool.Go(func() {}), ErrRejected)
+}
+
+func TestPool_CallerRuns(t *testing.T) {
+	pool := New(1, 0, Overflow(CallerRuns))
+	defer pool.Close(context.Background())
+	release := occupy(t, pool)
+	defer close(release)
+	ran := false
+	assert.NoError(t, pool.Go(func() { ran = true }))
+	assert.True(t, ran)
+	assert.Equal(t, int64(1), pool.Stats().CallerRuns)
+}
+
+func TestPool_Recover(t *testing.T) {
+	recovered := make(chan any, 1)
+	pool := New(1, 1, Recover(func(p any) { recovered <- p }))
+	assert.NoError(t, pool.Go(func() { panic("boom") }))
+	assert.Equal(t, "boom", <-recovered)
+	done := make(chan struct{})
+	assert.NoError(t, pool.Go(func() { close(done) }))
+	<-done
+	assert.NoError(t, pool.Close(context.Background()))
+}
+
+func TestPool_Close(t *testing.T) {
+	pool := New(1, 1)
+	release := occupy(t, pool)
+	ran := false
+	assert.NoError(t, pool.Go(func() { ran = true }))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, pool.Close(ctx), context.DeadlineExceeded)
+	assert.ErrorIs(t, pool.Go(func() {}), ErrClosed)
+
+	close(release)
+	assert.NoError(t, pool.Close(context.Background()))
+	assert.True(t, ran, "the queued function is drained")
+}
+
+func TestSubmit(t *testing.T) {
+	ran := make(chan struct{})
+	assert.NoError(t, Submit(goGopher{}, func() { close(ran) }, nil))
+	<-ran
+}
+
+type goGopher struct{}
+
+func (goGopher) Go(f func()) error {
+	go f()
+	return nil
+}